@@ -3,10 +3,19 @@ package fsql
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // BatchSize is the default size for batched operations
@@ -25,9 +34,17 @@ type BatchInsertExecutor struct {
 	sb *strings.Builder
 
 	// Object mode fields
-	objectMode bool
-	objectType reflect.Type
-	tagCache   *ModelTagCache
+	objectMode   bool
+	objectType   reflect.Type
+	tagCache     *ModelTagCache
+	objectFields []ModelField // the inserted fields, in b.fields order
+
+	// Upsert fields (see BatchUpsertExecutor)
+	conflictFields []string
+	updateFields   []string
+
+	// returned collects the RETURNING values of flushes until FlushReturning hands them out
+	returned []interface{}
 }
 
 // NewBatchInsert creates a new batch insert executor
@@ -96,13 +113,14 @@ func (b *BatchInsertExecutor) addMap(values map[string]interface{}) error {
 	b.valuesBatch = append(b.valuesBatch, rowValues)
 
 	if len(b.valuesBatch) >= b.batchSize {
-		return b.Flush()
+		return b.flushContext(context.Background(), true)
 	}
 
 	return nil
 }
 
-// addObject adds a struct record to the batch
+// addObject adds a struct record to the batch. Like StructToInsertMap, a zero field with a
+// dbInsertValue gets that default (SQL keywords and "sql:..." expressions are inlined).
 func (b *BatchInsertExecutor) addObject(obj interface{}) error {
 	if b.tagCache == nil {
 		objType := reflect.TypeOf(obj)
@@ -120,8 +138,9 @@ func (b *BatchInsertExecutor) addObject(obj interface{}) error {
 
 		var insertFields []string
 		for _, field := range b.tagCache.Fields {
-			if strings.Contains(field.Mode, "i") {
+			if field.HasMode("i") && field.writable() {
 				insertFields = append(insertFields, field.DbName)
+				b.objectFields = append(b.objectFields, field)
 			}
 		}
 
@@ -131,6 +150,11 @@ func (b *BatchInsertExecutor) addObject(obj interface{}) error {
 		for _, field := range insertFields {
 			b.fieldMap[field] = true
 		}
+		if err := b.checkUpdateFields(); err != nil {
+			b.tagCache = nil
+			b.objectFields = nil
+			return err
+		}
 	}
 
 	val := reflect.ValueOf(obj)
@@ -142,30 +166,35 @@ func (b *BatchInsertExecutor) addObject(obj interface{}) error {
 		return fmt.Errorf("object type mismatch: expected %s, got %s", b.objectType.Name(), val.Type().Name())
 	}
 
-	rowValues := make([]interface{}, 0, len(b.fields))
+	rowValues := make([]interface{}, 0, len(b.objectFields))
 
-	for i, tagField := range b.tagCache.Fields {
-		if strings.Contains(tagField.Mode, "i") {
-			fieldVal := val.Field(i).Interface()
-
-			if tagField.InsertValue != "" {
-				fieldVal = tagField.InsertValue
-			}
-
-			rowValues = append(rowValues, fieldVal)
+	for _, field := range b.objectFields {
+		// By name: tag cache positions skip untagged fields, so they aren't struct field indexes
+		fieldVal := val.FieldByName(field.Name)
+		if field.InsertValue == "" || !fieldVal.IsZero() {
+			rowValues = append(rowValues, fieldVal.Interface())
+		} else if expr, ok := insertDefaultSQL(field.InsertValue); ok {
+			rowValues = append(rowValues, insertExpr(expr))
+		} else {
+			rowValues = append(rowValues, field.InsertValue)
 		}
 	}
 
 	b.valuesBatch = append(b.valuesBatch, rowValues)
 
 	if len(b.valuesBatch) >= b.batchSize {
-		return b.Flush()
+		return b.flushContext(context.Background(), true)
 	}
 
 	return nil
 }
 
-// SetReturning sets the returning field
+// insertExpr is a dbInsertValue default that buildQuery writes into VALUES instead of binding
+type insertExpr string
+
+// SetReturning sets the returning field. Batches flushed by Add or FlushReturning then collect
+// the field's value per inserted row, handed out by FlushReturning; an explicit Flush discards
+// whatever was collected, so executors that never call FlushReturning don't accumulate values.
 func (b *BatchInsertExecutor) SetReturning(field string) *BatchInsertExecutor {
 	b.returning = field
 	return b
@@ -178,10 +207,84 @@ func (b *BatchInsertExecutor) Flush() error {
 
 // FlushContext executes the current batch with context
 func (b *BatchInsertExecutor) FlushContext(ctx context.Context) error {
+	return b.flushContext(ctx, false)
+}
+
+// flushContext executes the current batch, collecting its RETURNING values when collect is set
+// (Add's auto-flush and FlushReturning) and discarding collected values otherwise
+func (b *BatchInsertExecutor) flushContext(ctx context.Context, collect bool) error {
+	if !collect {
+		b.returned = nil
+	}
 	if len(b.valuesBatch) == 0 {
 		return nil
 	}
 
+	query, flattenedValues := b.buildQuery()
+	if collect && b.returning != "" {
+		rows, err := DB.Query(ctx, query, flattenedValues...)
+		if err := b.collectReturning(rows, err); err != nil {
+			return err
+		}
+	} else if _, err := DB.Exec(ctx, query, flattenedValues...); err != nil {
+		return err
+	}
+
+	b.valuesBatch = b.valuesBatch[:0]
+
+	return nil
+}
+
+// FlushReturning flushes the current batch and returns the SetReturning value of every row
+// inserted since the last FlushReturning or Flush, auto-flushed batches included, in insertion
+// order (uuid values as strings). An upsert with DO NOTHING returns nothing for the rows it
+// skipped, so the values then no longer line up with the added rows. Without a returning field
+// it returns an error.
+func (b *BatchInsertExecutor) FlushReturning(ctx context.Context) ([]interface{}, error) {
+	if b.returning == "" {
+		return nil, errors.New("no returning field set, call SetReturning first")
+	}
+	if err := b.flushContext(ctx, true); err != nil {
+		return nil, err
+	}
+	returned := b.returned
+	b.returned = nil
+	return returned, nil
+}
+
+// FlushReturningWithTx is FlushReturning within a transaction
+func (b *BatchInsertExecutor) FlushReturningWithTx(ctx context.Context, tx *Tx) ([]interface{}, error) {
+	if b.returning == "" {
+		return nil, errors.New("no returning field set, call SetReturning first")
+	}
+	if err := b.flushWithTxContext(ctx, tx, true); err != nil {
+		return nil, err
+	}
+	returned := b.returned
+	b.returned = nil
+	return returned, nil
+}
+
+// collectReturning appends the first column of each returned row to b.returned
+func (b *BatchInsertExecutor) collectReturning(rows pgx.Rows, err error) error {
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns := getColumns(rows)
+	for rows.Next() {
+		row, err := scanMap(rows, columns)
+		if err != nil {
+			return err
+		}
+		b.returned = append(b.returned, row[columns[0]])
+	}
+	return rows.Err()
+}
+
+// buildQuery builds the multi-row INSERT (or upsert) for the current batch
+func (b *BatchInsertExecutor) buildQuery() (string, []interface{}) {
 	b.sb.Reset()
 	b.sb.WriteString(`INSERT INTO "`)
 	b.sb.WriteString(b.tableName)
@@ -196,41 +299,80 @@ func (b *BatchInsertExecutor) FlushContext(ctx context.Context) error {
 
 	b.sb.WriteString(") VALUES ")
 
-	flattenedValues := make([]interface{}, 0, len(b.valuesBatch)*len(b.fields))
-	paramCounter := 1
+	flattenedValues := b.writeValues()
+
+	if len(b.conflictFields) > 0 {
+		b.writeOnConflict()
+	}
+
+	if b.returning != "" {
+		b.sb.WriteString(" RETURNING ")
+		b.sb.WriteString(b.returning)
+	}
+
+	return b.sb.String(), flattenedValues
+}
 
+// writeValues writes the batch's ($1,$2),($3,NOW()) list like BuildValuesClause, inlining
+// insertExpr defaults, and returns the bound args
+func (b *BatchInsertExecutor) writeValues() []interface{} {
+	flatArgs := make([]interface{}, 0, len(b.valuesBatch)*len(b.fields))
 	for i, row := range b.valuesBatch {
 		if i > 0 {
-			b.sb.WriteString(", ")
+			b.sb.WriteByte(',')
 		}
-
-		b.sb.WriteString("(")
-		for j := range b.fields {
+		b.sb.WriteByte('(')
+		for j, val := range row {
 			if j > 0 {
-				b.sb.WriteString(", ")
+				b.sb.WriteByte(',')
 			}
-			b.sb.WriteString(fmt.Sprintf("$%d", paramCounter))
-			paramCounter++
-
-			flattenedValues = append(flattenedValues, row[j])
+			if expr, ok := val.(insertExpr); ok {
+				b.sb.WriteString(string(expr))
+				continue
+			}
+			flatArgs = append(flatArgs, val)
+			b.sb.WriteByte('$')
+			b.sb.WriteString(strconv.Itoa(len(flatArgs)))
 		}
-		b.sb.WriteString(")")
+		b.sb.WriteByte(')')
 	}
+	return flatArgs
+}
 
-	if b.returning != "" {
-		b.sb.WriteString(" RETURNING ")
-		b.sb.WriteString(b.returning)
+// writeOnConflict writes ON CONFLICT (keys) DO UPDATE SET col = EXCLUDED.col for the update
+// fields (in object mode without explicit update fields: the inserted dbMode "u" ones), or DO NOTHING
+func (b *BatchInsertExecutor) writeOnConflict() {
+	updateFields := b.updateFields
+	if updateFields == nil {
+		// Only inserted columns: EXCLUDED holds the column default for the others
+		for _, field := range b.objectFields {
+			if field.HasMode("u") && !slices.Contains(b.conflictFields, field.DbName) {
+				updateFields = append(updateFields, field.DbName)
+			}
+		}
 	}
 
-	query := b.sb.String()
-	_, err := DB.Exec(ctx, query, flattenedValues...)
-	if err != nil {
-		return err
+	b.sb.WriteString(" ON CONFLICT (")
+	for i, field := range b.conflictFields {
+		if i > 0 {
+			b.sb.WriteString(", ")
+		}
+		b.sb.WriteString(`"` + quotesReplacer.Replace(field) + `"`)
+	}
+	if len(updateFields) == 0 {
+		b.sb.WriteString(") DO NOTHING")
+		return
+	}
+	b.sb.WriteString(") DO UPDATE SET ")
+	for i, field := range updateFields {
+		if i > 0 {
+			b.sb.WriteString(", ")
+		}
+		quoted := `"` + quotesReplacer.Replace(field) + `"`
+		b.sb.WriteString(quoted)
+		b.sb.WriteString(" = EXCLUDED.")
+		b.sb.WriteString(quoted)
 	}
-
-	b.valuesBatch = b.valuesBatch[:0]
-
-	return nil
 }
 
 // FlushWithTx executes the current batch within a transaction
@@ -240,58 +382,79 @@ func (b *BatchInsertExecutor) FlushWithTx(tx *Tx) error {
 
 // FlushWithTxContext executes the current batch within a transaction with context
 func (b *BatchInsertExecutor) FlushWithTxContext(ctx context.Context, tx *Tx) error {
+	return b.flushWithTxContext(ctx, tx, false)
+}
+
+// flushWithTxContext is flushContext within a transaction
+func (b *BatchInsertExecutor) flushWithTxContext(ctx context.Context, tx *Tx, collect bool) error {
+	if !collect {
+		b.returned = nil
+	}
 	if len(b.valuesBatch) == 0 {
 		return nil
 	}
 
-	b.sb.Reset()
-	b.sb.WriteString(`INSERT INTO "`)
-	b.sb.WriteString(b.tableName)
-	b.sb.WriteString(`" (`)
-
-	for i, field := range b.fields {
-		if i > 0 {
-			b.sb.WriteString(", ")
+	query, flattenedValues := b.buildQuery()
+	if collect && b.returning != "" {
+		rows, err := tx.QueryContext(ctx, query, flattenedValues...)
+		if err := b.collectReturning(rows, err); err != nil {
+			return err
 		}
-		b.sb.WriteString(field)
+	} else if _, err := tx.ExecContext(ctx, query, flattenedValues...); err != nil {
+		return err
 	}
 
-	b.sb.WriteString(") VALUES ")
-
-	flattenedValues := make([]interface{}, 0, len(b.valuesBatch)*len(b.fields))
-	paramCounter := 1
+	b.valuesBatch = b.valuesBatch[:0]
 
-	for i, row := range b.valuesBatch {
-		if i > 0 {
-			b.sb.WriteString(", ")
-		}
+	return nil
+}
 
-		b.sb.WriteString("(")
-		for j := range b.fields {
-			if j > 0 {
-				b.sb.WriteString(", ")
-			}
-			b.sb.WriteString(fmt.Sprintf("$%d", paramCounter))
-			paramCounter++
+// BatchUpsertExecutor handles batched upserts: each flush is a single multi-row
+// INSERT ... ON CONFLICT (conflict fields) DO UPDATE SET col = EXCLUDED.col.
+// A batch must not hold the same key twice: Postgres refuses to update a row twice in one statement.
+type BatchUpsertExecutor struct {
+	*BatchInsertExecutor
+}
 
-			flattenedValues = append(flattenedValues, row[j])
-		}
-		b.sb.WriteString(")")
-	}
+// NewBatchUpsert creates a new batch upsert executor for maps holding every field.
+// Rows conflicting on conflictFields get updateFields overwritten (DO NOTHING if empty);
+// conflictFields must match a unique index and can't be empty, and updateFields must be
+// among fields.
+func NewBatchUpsert(tableName string, fields []string, conflictFields []string, updateFields []string, batchSize int) (*BatchUpsertExecutor, error) {
+	if len(conflictFields) == 0 {
+		return nil, errors.New("batch upsert requires conflict fields")
+	}
+	b := NewBatchInsert(tableName, fields, batchSize)
+	b.conflictFields = conflictFields
+	b.updateFields = updateFields
+	if err := b.checkUpdateFields(); err != nil {
+		return nil, err
+	}
+	return &BatchUpsertExecutor{b}, nil
+}
 
-	if b.returning != "" {
-		b.sb.WriteString(" RETURNING ")
-		b.sb.WriteString(b.returning)
-	}
+// NewBatchUpsertExecutor creates a new batch upsert executor for structured objects: the dbMode
+// "i" fields are inserted, and conflicting rows get updateFields overwritten (nil means the
+// inserted "u" fields other than the conflict fields). Add returns an error for update fields
+// that aren't inserted.
+func NewBatchUpsertExecutor(tableName string, conflictFields []string, updateFields []string, batchSize int) (*BatchUpsertExecutor, error) {
+	if len(conflictFields) == 0 {
+		return nil, errors.New("batch upsert requires conflict fields")
+	}
+	b := NewBatchInsertExecutor(tableName, batchSize)
+	b.conflictFields = conflictFields
+	b.updateFields = updateFields
+	return &BatchUpsertExecutor{b}, nil
+}
 
-	query := b.sb.String()
-	_, err := tx.ExecContext(ctx, query, flattenedValues...)
-	if err != nil {
-		return err
+// checkUpdateFields rejects update fields missing from the inserted fields, whose EXCLUDED
+// value would be the column default rather than the row's
+func (b *BatchInsertExecutor) checkUpdateFields() error {
+	for _, field := range b.updateFields {
+		if !b.fieldMap[field] {
+			return fmt.Errorf("update field %s is not an inserted field", field)
+		}
 	}
-
-	b.valuesBatch = b.valuesBatch[:0]
-
 	return nil
 }
 
@@ -473,6 +636,236 @@ func (b *BatchUpdateExecutor) FlushWithTxContext(ctx context.Context, tx *Tx) er
 	return err
 }
 
+// BatchUpdateUnnest updates many rows in one statement by joining the table against unnest()ed
+// typed arrays, one per column:
+//
+//	UPDATE "t" SET "a" = v."a" FROM unnest($1::bigint[], $2::uuid[]) AS v("a", "key") WHERE "t"."key" = v."key"
+//
+// Each row map must hold every updateField and keyField. The array types come from the registered
+// model's Go field types (int -> bigint, string -> text, time.Time -> timestamptz, ...); tag a field
+// with dbType (e.g. dbType:"uuid") when the Go type is ambiguous. Unlike the CASE-based
+// BatchUpdateExecutor the query size doesn't grow with the number of rows.
+// Returns the number of rows updated.
+func BatchUpdateUnnest(ctx context.Context, tableName string, updateFields []string, keyField string, rows []map[string]interface{}) (int64, error) {
+	query, args, err := buildBatchUpdateUnnestQuery(tableName, updateFields, keyField, rows)
+	if err != nil {
+		return 0, err
+	}
+	if query == "" {
+		return 0, nil
+	}
+
+	result, err := DB.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("batch update failed: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+// buildBatchUpdateUnnestQuery builds the UPDATE ... FROM unnest(...) for BatchUpdateUnnest
+func buildBatchUpdateUnnestQuery(tableName string, updateFields []string, keyField string, rows []map[string]interface{}) (string, []interface{}, error) {
+	modelInfo, ok := getModelInfo(tableName)
+	if !ok {
+		return "", nil, fmt.Errorf("table name not initialized: %s", tableName)
+	}
+	if len(updateFields) == 0 {
+		return "", nil, fmt.Errorf("no fields to update")
+	}
+	if len(rows) == 0 {
+		return "", nil, nil
+	}
+
+	columns := append(append(make([]string, 0, len(updateFields)+1), updateFields...), keyField)
+	quoted := make([]string, len(columns))
+	arrays := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+
+	for i, col := range columns {
+		pgType, err := columnPgType(modelInfo, col)
+		if err != nil {
+			return "", nil, err
+		}
+
+		values := make([]interface{}, len(rows))
+		for j, row := range rows {
+			value, ok := row[col]
+			if !ok {
+				return "", nil, fmt.Errorf("missing field %s in row %d", col, j)
+			}
+			values[j] = value
+		}
+
+		literal, err := pgArrayLiteral(values, pgType)
+		if err != nil {
+			return "", nil, fmt.Errorf("column %s: %w", col, err)
+		}
+
+		quoted[i] = `"` + quotesReplacer.Replace(col) + `"`
+		arrays[i] = fmt.Sprintf("$%d::%s[]", i+1, pgType)
+		args[i] = literal
+	}
+
+	var sb strings.Builder
+	sb.WriteString("UPDATE ")
+	sb.WriteString(modelInfo.quotedTableName)
+	sb.WriteString(" SET ")
+	for i := range updateFields {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quoted[i])
+		sb.WriteString(" = v.")
+		sb.WriteString(quoted[i])
+	}
+	sb.WriteString(" FROM unnest(")
+	sb.WriteString(strings.Join(arrays, ", "))
+	sb.WriteString(") AS v(")
+	sb.WriteString(strings.Join(quoted, ", "))
+	sb.WriteString(") WHERE ")
+	sb.WriteString(modelInfo.quotedTableName)
+	sb.WriteByte('.')
+	sb.WriteString(quoted[len(quoted)-1])
+	sb.WriteString(" = v.")
+	sb.WriteString(quoted[len(quoted)-1])
+
+	return sb.String(), args, nil
+}
+
+// columnPgType returns the Postgres type of a registered column: its dbType tag,
+// or the type derived from the Go field type
+func columnPgType(modelInfo *modelInfo, column string) (string, error) {
+	if pgType, ok := modelInfo.dbTypes[column]; ok {
+		return pgType, nil
+	}
+	fieldType, ok := modelInfo.fieldTypes[column]
+	if !ok {
+		return "", fmt.Errorf("unknown column: %s", column)
+	}
+	if pgType, ok := pgTypeForGoType(fieldType); ok {
+		return pgType, nil
+	}
+	return "", fmt.Errorf("cannot derive a postgres type for column %s (%s), add a dbType tag", column, fieldType)
+}
+
+// pgTypeForGoType maps a Go field type to the Postgres type used for its arrays
+func pgTypeForGoType(t reflect.Type) (string, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType, reflect.TypeOf(sql.NullTime{}):
+		return "timestamptz", true
+	case reflect.TypeOf(sql.NullString{}):
+		return "text", true
+	case reflect.TypeOf(sql.NullInt64{}):
+		return "bigint", true
+	case reflect.TypeOf(sql.NullInt32{}):
+		return "integer", true
+	case reflect.TypeOf(sql.NullInt16{}):
+		return "smallint", true
+	case reflect.TypeOf(sql.NullFloat64{}):
+		return "double precision", true
+	case reflect.TypeOf(sql.NullBool{}):
+		return "boolean", true
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "text", true
+	case reflect.Bool:
+		return "boolean", true
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32:
+		return "bigint", true
+	case reflect.Uint64:
+		return "numeric", true
+	case reflect.Int32, reflect.Uint16:
+		return "integer", true
+	case reflect.Int16, reflect.Int8, reflect.Uint8:
+		return "smallint", true
+	case reflect.Float64:
+		return "double precision", true
+	case reflect.Float32:
+		return "real", true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytea", true
+		}
+	}
+	return "", false
+}
+
+// pgArrayLiteral renders values as a Postgres array literal ('{"a",NULL,"c"}') for a pgType[] cast.
+// Every element is quoted, which Postgres accepts for any element type.
+func pgArrayLiteral(values []interface{}, pgType string) (string, error) {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		text, isNull, err := pgArrayElement(value, pgType)
+		if err != nil {
+			return "", err
+		}
+		if isNull {
+			sb.WriteString("NULL")
+			continue
+		}
+		sb.WriteByte('"')
+		for _, r := range text {
+			if r == '"' || r == '\\' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(r)
+		}
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String(), nil
+}
+
+// pgArrayElement returns the text form of one array element, or isNull
+func pgArrayElement(value interface{}, pgType string) (string, bool, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr && v.IsNil() {
+			return "", true, nil
+		}
+		driverVal, err := valuer.Value()
+		if err != nil {
+			return "", false, err
+		}
+		value = driverVal
+	}
+
+	v := reflect.ValueOf(value)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", true, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", true, nil
+	}
+
+	switch val := v.Interface().(type) {
+	case string:
+		return val, false, nil
+	case []byte:
+		// JSON from a Valuer stays text; other bytes use the bytea hex format
+		if pgType == "json" || pgType == "jsonb" {
+			return string(val), false, nil
+		}
+		return `\x` + hex.EncodeToString(val), false, nil
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05.999999999Z07:00"), false, nil
+	case bool:
+		return strconv.FormatBool(val), false, nil
+	}
+	return fmt.Sprint(v.Interface()), false, nil
+}
+
 // Pool for BatchInsertExecutors
 var batchInsertPool = sync.Pool{
 	New: func() interface{} {
@@ -492,6 +885,9 @@ func GetBatchInsert(tableName string, fields []string, batchSize int) *BatchInse
 	b.valuesBatch = b.valuesBatch[:0]
 	b.batchSize = batchSize
 	b.returning = ""
+	b.conflictFields = nil
+	b.updateFields = nil
+	b.returned = nil
 
 	b.fieldMap = make(map[string]bool, len(fields))
 	for _, field := range fields {
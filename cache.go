@@ -4,6 +4,7 @@ package fsql
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 
@@ -41,6 +42,11 @@ type modelInfo struct {
 	dbFieldsInsertMap map[string]struct{}
 	dbFieldsUpdateMap map[string]struct{}
 	linkedFields      map[string]string // FieldName -> TableAlias
+	fieldTypes        map[string]reflect.Type // db column -> Go field type
+	dbTypes           map[string]string       // db column -> Postgres type from the dbType tag
+	primaryKey        string                  // Default key/returning column (dbMode "pk" or RegisterPrimaryKey)
+	computedColumns   []computedColumn        // Virtual columns appended to the unaliased select list
+	modelType         reflect.Type            // Struct type registered for the table
 	
 	// Pre-generated quoted strings for faster access
 	quotedTableName string
@@ -134,6 +140,9 @@ func InitModelTagCache(model interface{}, tableName string) {
 	dbFieldsInsertMap := make(map[string]struct{}, numFields)
 	dbFieldsUpdateMap := make(map[string]struct{}, numFields)
 	linkedFields := make(map[string]string, numFields/4) // Assuming ~25% are linked
+	fieldTypes := make(map[string]reflect.Type, numFields)
+	dbTypes := make(map[string]string)
+	primaryKey := ""
 
 	// Pre-compute the quoted table name for reuse
 	quotedTableName := `"` + quotesReplacer.Replace(tableName) + `"`
@@ -199,7 +208,7 @@ func InitModelTagCache(model interface{}, tableName string) {
 		if modeParser["u"] {
 			flags |= modeUpdate
 		}
-		if modeParser["s"] {
+		if modeParser["s"] || modeParser["agg"] {
 			flags |= modeSkip
 		}
 		if modeParser["l"] || modeParser["link"] {
@@ -213,6 +222,13 @@ func InitModelTagCache(model interface{}, tableName string) {
 		}
 
 		dbTagMap[field.Name] = dbTagValue
+		fieldTypes[dbTagValue] = field.Type
+		if dbType := field.Tag.Get("dbType"); dbType != "" {
+			dbTypes[dbTagValue] = dbType
+		}
+		if modeParser["pk"] {
+			primaryKey = dbTagValue
+		}
 
 		if (flags & modeSkip) != 0 {
 			continue
@@ -255,6 +271,10 @@ func InitModelTagCache(model interface{}, tableName string) {
 		dbFieldsInsertMap: dbFieldsInsertMap,
 		dbFieldsUpdateMap: dbFieldsUpdateMap,
 		linkedFields:      linkedFields,
+		fieldTypes:        fieldTypes,
+		dbTypes:           dbTypes,
+		primaryKey:        primaryKey,
+		modelType:         modelType,
 		quotedTableName:   quotedTableName,
 		quotedFields:      quotedFields,
 		
@@ -270,6 +290,134 @@ func InitModelTagCache(model interface{}, tableName string) {
 	modelFieldsCache.Set(tableName, modelInfo)
 }
 
+// RegisterPrimaryKey sets the default key/returning column for a registered table,
+// overriding any dbMode "pk" tag. Call it at startup next to InitModelTagCache.
+func RegisterPrimaryKey(tableName, column string) error {
+	info, ok := getModelInfo(tableName)
+	if !ok {
+		return fmt.Errorf("table name not initialized: %s", tableName)
+	}
+	if _, ok := info.fieldTypes[column]; !ok {
+		return fmt.Errorf("unknown column %s for table %s", column, tableName)
+	}
+
+	// Copy-on-write so concurrent readers never see a partially updated modelInfo
+	updated := *info
+	updated.primaryKey = column
+	modelFieldsCache.Set(tableName, &updated)
+	return nil
+}
+
+// computedColumn is a select-list expression exposed under a column name
+type computedColumn struct {
+	name       string
+	expression string
+}
+
+// RegisterComputedColumn adds `expression AS "name"` to the select list of a registered table,
+// so SelectBase/Build and GetSelectFields return it and it scans into a field tagged db:"name"
+// (tag that field dbMode:"s" so it isn't selected as a real column). Only the unaliased select
+// list gets computed columns since the expression references the table by name.
+// Registering the same name again replaces its expression.
+func RegisterComputedColumn(tableName, name, expression string) error {
+	info, ok := getModelInfo(tableName)
+	if !ok {
+		return fmt.Errorf("table name not initialized: %s", tableName)
+	}
+	if expression == "" {
+		return fmt.Errorf("empty expression for computed column %s", name)
+	}
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("computed column name must not contain '.': %s", name)
+	}
+	if _, err := QuoteIdentifier(name); err != nil {
+		return err
+	}
+
+	computed := make([]computedColumn, 0, len(info.computedColumns)+1)
+	for _, c := range info.computedColumns {
+		if c.name != name {
+			computed = append(computed, c)
+		}
+	}
+	computed = append(computed, computedColumn{name: name, expression: expression})
+
+	// Copy-on-write so concurrent readers keep a consistent select list
+	updated := *info
+	updated.computedColumns = computed
+	updated.selectFieldsCache = make(map[string][]string, len(info.selectFieldsCache))
+	updated.selectFieldNamesCache = make(map[string][]string, len(info.selectFieldNamesCache))
+	for alias, fields := range info.selectFieldsCache {
+		updated.selectFieldsCache[alias] = fields
+		updated.selectFieldNamesCache[alias] = info.selectFieldNamesCache[alias]
+	}
+
+	fields, fieldNames := computeFieldsByModeInternal(info.dbFieldsSelect, info.quotedTableName, info.quotedFields, "")
+	for _, c := range computed {
+		quoted, _ := QuoteIdentifier(c.name) // validated when registered
+		fields = append(fields, c.expression+" AS "+quoted)
+		fieldNames = append(fieldNames, c.name)
+	}
+	updated.selectFieldsCache[""] = fields
+	updated.selectFieldNamesCache[""] = fieldNames
+
+	modelFieldsCache.Set(tableName, &updated)
+	return nil
+}
+
+// PrimaryKey returns the default key column registered for a table
+func PrimaryKey(tableName string) (string, bool) {
+	info, ok := getModelInfo(tableName)
+	if !ok || info.primaryKey == "" {
+		return "", false
+	}
+	return info.primaryKey, true
+}
+
+// ClearModelCache forgets the metadata registered for a table so InitModelTagCache can
+// register it again (possibly with a different struct). Cached struct tags and scan
+// traversals of the table's struct type are dropped too.
+func ClearModelCache(tableName string) {
+	info, ok := getModelInfo(tableName)
+	if !ok {
+		return
+	}
+	modelFieldsCache.Delete(tableName)
+	clearTypeCaches(info.modelType)
+}
+
+// ClearAllModelCaches forgets every registered table and all derived struct caches
+func ClearAllModelCaches() {
+	modelFieldsCache.Clear()
+
+	modelTagCacheMutex.Lock()
+	modelTagCache = make(map[reflect.Type]*ModelTagCache)
+	modelTagCacheMutex.Unlock()
+
+	traversalCacheLock.Lock()
+	traversalCacheMap = make(map[traversalKey]*traversalCache)
+	traversalCacheLock.Unlock()
+}
+
+// clearTypeCaches drops the type-keyed tag cache and scan traversals of a struct type
+func clearTypeCaches(modelType reflect.Type) {
+	if modelType == nil {
+		return
+	}
+
+	modelTagCacheMutex.Lock()
+	delete(modelTagCache, modelType)
+	modelTagCacheMutex.Unlock()
+
+	traversalCacheLock.Lock()
+	for key := range traversalCacheMap {
+		if key.typ == modelType {
+			delete(traversalCacheMap, key)
+		}
+	}
+	traversalCacheLock.Unlock()
+}
+
 // getModelInfo retrieves model info from cache
 func getModelInfo(tableName string) (*modelInfo, bool) {
 	return modelFieldsCache.Get(tableName)
@@ -403,3 +551,82 @@ func GetInsertValues(tableName string) map[string]string {
 	}
 	return modelInfo.dbInsertValueMap
 }
+
+// ModelInfoPublic is a read-only snapshot of the metadata registered for a table
+type ModelInfoPublic struct {
+	Table          string
+	FieldColumns   map[string]string // Go field name -> column name
+	SelectColumns  []string
+	InsertColumns  []string
+	UpdateColumns  []string
+	LinkedFields   map[string]string // Go field name -> table alias
+	InsertDefaults map[string]string // Column name -> dbInsertValue
+	PrimaryKey     string            // Default key column, empty if none registered
+}
+
+// ModelMetadata returns a copy of the metadata stored by InitModelTagCache for table
+func ModelMetadata(table string) (*ModelInfoPublic, bool) {
+	modelInfo, ok := getModelInfo(table)
+	if !ok {
+		return nil, false
+	}
+
+	return &ModelInfoPublic{
+		Table:          table,
+		FieldColumns:   copyStringMap(modelInfo.dbTagMap),
+		SelectColumns:  append([]string(nil), modelInfo.dbFieldsSelect...),
+		InsertColumns:  append([]string(nil), modelInfo.dbFieldsInsert...),
+		UpdateColumns:  append([]string(nil), modelInfo.dbFieldsUpdate...),
+		LinkedFields:   copyStringMap(modelInfo.linkedFields),
+		InsertDefaults: copyStringMap(modelInfo.dbInsertValueMap),
+		PrimaryKey:     modelInfo.primaryKey,
+	}, true
+}
+
+// ModelSummary describes one registered table for startup diagnostics
+type ModelSummary struct {
+	Table          string
+	ModelType      string // Registered Go struct type, e.g. "main.User"
+	SelectCount    int
+	InsertCount    int
+	UpdateCount    int
+	LinkedFields   map[string]string // Go field name -> table alias
+	InsertDefaults map[string]string // Column name -> dbInsertValue
+	PrimaryKey     string
+}
+
+// DumpRegisteredModels summarizes every table registered with InitModelTagCache, sorted by table.
+// Use it at startup to check expected models are registered and their dbMode tags parsed as intended.
+func DumpRegisteredModels() []ModelSummary {
+	var summaries []ModelSummary
+	modelFieldsCache.Range(func(table string, info *modelInfo) bool {
+		summary := ModelSummary{
+			Table:          table,
+			SelectCount:    len(info.dbFieldsSelect),
+			InsertCount:    len(info.dbFieldsInsert),
+			UpdateCount:    len(info.dbFieldsUpdate),
+			LinkedFields:   copyStringMap(info.linkedFields),
+			InsertDefaults: copyStringMap(info.dbInsertValueMap),
+			PrimaryKey:     info.primaryKey,
+		}
+		if info.modelType != nil {
+			summary.ModelType = info.modelType.String()
+		}
+		summaries = append(summaries, summary)
+		return true
+	})
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Table < summaries[j].Table
+	})
+	return summaries
+}
+
+// copyStringMap returns a shallow copy of a string map
+func copyStringMap(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -6,15 +6,18 @@ package fsql
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"log"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog"
 )
 
@@ -26,37 +29,68 @@ import (
 // NOTE: This is set by InitDB/InitDBPool, users should use DB directly
 var Db = &dbCompat{}
 
-// dbCompat wraps pgxpool.Pool to provide sqlx-like interface
-type dbCompat struct{}
+// dbCompat wraps pgxpool.Pool to provide sqlx-like interface.
+// The zero value uses the primary DB; GetReplika returns handles bound to a replica.
+type dbCompat struct {
+	replica *DBConnection
+}
 
-// Close closes the database connection pool
+// pool returns the pool this handle queries
+func (d *dbCompat) pool() *pgxpool.Pool {
+	if d.replica != nil {
+		return d.replica.pool
+	}
+	return DB
+}
+
+// track records a replica query's outcome for health tracking (no-op on the primary)
+func (d *dbCompat) track(ctx context.Context, err error) {
+	if d.replica != nil {
+		d.replica.recordResult(ctx, err)
+	}
+}
+
+// Close closes the database connection pool (replica pools are closed by CloseReplicas)
 func (d *dbCompat) Close() {
+	if d.replica != nil {
+		return
+	}
 	CloseDB()
 }
 
 // Exec executes a query without returning any rows (delegates to SafeExec)
 func (d *dbCompat) Exec(query string, args ...interface{}) (pgconn.CommandTag, error) {
+	if d.replica != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultDBTimeout)
+		defer cancel()
+		return d.ExecContext(ctx, query, args...)
+	}
 	return SafeExec(query, args...)
 }
 
 // Query executes a query that returns rows (delegates to SafeQuery)
 func (d *dbCompat) Query(query string, args ...interface{}) (pgx.Rows, error) {
-	return SafeQuery(query, args...)
+	return d.QueryContext(context.Background(), query, args...)
 }
 
 // QueryRow executes a query that returns at most one row
 func (d *dbCompat) QueryRow(query string, args ...interface{}) pgx.Row {
-	return SafeQueryRow(query, args...)
+	return d.QueryRowContext(context.Background(), query, args...)
 }
 
 // Get retrieves a single row into dest (struct scanning)
 func (d *dbCompat) Get(dest interface{}, query string, args ...interface{}) error {
+	if d.replica != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultDBTimeout)
+		defer cancel()
+		return d.GetContext(ctx, dest, query, args...)
+	}
 	return SafeGet(dest, query, args...)
 }
 
 // GetContext retrieves a single row into dest with context
 func (d *dbCompat) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	rows, err := DB.Query(ctx, query, args...)
+	rows, err := d.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -66,12 +100,17 @@ func (d *dbCompat) GetContext(ctx context.Context, dest interface{}, query strin
 
 // Select retrieves multiple rows into dest (slice of structs)
 func (d *dbCompat) Select(dest interface{}, query string, args ...interface{}) error {
+	if d.replica != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultDBTimeout)
+		defer cancel()
+		return d.SelectContext(ctx, dest, query, args...)
+	}
 	return SafeSelect(dest, query, args...)
 }
 
 // SelectContext retrieves multiple rows with context
 func (d *dbCompat) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	rows, err := DB.Query(ctx, query, args...)
+	rows, err := d.QueryContext(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -80,22 +119,34 @@ func (d *dbCompat) SelectContext(ctx context.Context, dest interface{}, query st
 
 // NamedExec executes a named query
 func (d *dbCompat) NamedExec(query string, arg interface{}) (pgconn.CommandTag, error) {
+	if d.replica != nil {
+		positionalQuery, args, err := namedToPositional(query, arg)
+		if err != nil {
+			return pgconn.CommandTag{}, err
+		}
+		return d.Exec(positionalQuery, args...)
+	}
 	return SafeNamedExec(query, arg)
 }
 
 // QueryRowContext executes a query that returns at most one row with context
+// (errors surface at Scan, so they aren't counted against a replica's health)
 func (d *dbCompat) QueryRowContext(ctx context.Context, query string, args ...interface{}) pgx.Row {
-	return DB.QueryRow(ctx, query, args...)
+	return d.pool().QueryRow(ctx, query, args...)
 }
 
 // QueryContext executes a query that returns rows with context
 func (d *dbCompat) QueryContext(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
-	return DB.Query(ctx, query, args...)
+	rows, err := d.pool().Query(ctx, query, args...)
+	d.track(ctx, err)
+	return rows, err
 }
 
 // ExecContext executes a query without returning rows with context
 func (d *dbCompat) ExecContext(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
-	return DB.Exec(ctx, query, args...)
+	tag, err := d.pool().Exec(ctx, query, args...)
+	d.track(ctx, err)
+	return tag, err
 }
 
 // DefaultDBTimeout is the default timeout for database operations
@@ -104,7 +155,7 @@ var DefaultDBTimeout = 30 * time.Second
 // logger for fsql operations (optional)
 var logger *zerolog.Logger
 
-// SetLogger configures the global logger for fsql operations
+// SetLogger configures the global logger for fsql operations (used by SetSlowQueryThreshold)
 func SetLogger(l *zerolog.Logger) {
 	logger = l
 }
@@ -118,6 +169,7 @@ type DBConfig struct {
 	MaxConnections           int
 	MinConnections           int
 	IdleInTransactionTimeout time.Duration // If set, kills connections idle in transaction for this long
+	PreparedStatements       bool          // Use prepared statements (see InitDBWithPoolPrepared); not behind transaction-pooling PgBouncer
 }
 
 // DefaultConfig provides reasonable production defaults
@@ -138,7 +190,7 @@ func InitDB(database string, config ...DBConfig) {
 	// Set idle_in_transaction timeout before pool creation
 	idleInTxTimeout = cfg.IdleInTransactionTimeout
 
-	_, err := InitDBWithPool(database, cfg.MaxConnections, cfg.MinConnections)
+	_, err := initDBWithPool(database, cfg.MaxConnections, cfg.MinConnections, cfg.PreparedStatements)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -152,7 +204,7 @@ func InitDBPool(database string, config ...DBConfig) {
 		DefaultConfig = cfg
 	}
 
-	_, err := InitDBWithPool(database, cfg.MaxConnections, cfg.MinConnections)
+	_, err := initDBWithPool(database, cfg.MaxConnections, cfg.MinConnections, cfg.PreparedStatements)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -167,39 +219,12 @@ func InitDBCompat(database string, config ...DBConfig) {
 		DefaultConfig = cfg
 	}
 
-	_, err := InitDBWithPool(database, cfg.MaxConnections, cfg.MinConnections)
+	_, err := initDBWithPool(database, cfg.MaxConnections, cfg.MinConnections, cfg.PreparedStatements)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 }
 
-// =============================================================================
-// REPLICA SUPPORT (NO-OP STUBS)
-// =============================================================================
-
-// DBConnection represents a database connection with health tracking
-type DBConnection struct {
-	URI          string
-	FailureCount int32
-	State        int32
-}
-
-// InitDbReplicas initializes read replica connections (no-op in lite version)
-func InitDbReplicas(databases []string, config ...DBConfig) {
-	// No-op: fsql-lite doesn't support replicas
-	// All reads go to the main pool
-}
-
-// GetReplika returns a read replica connection (returns main DB in lite version)
-func GetReplika() *dbCompat {
-	return Db
-}
-
-// CloseReplicas closes all replica connections (no-op in lite version)
-func CloseReplicas() {
-	// No-op: fsql-lite doesn't support replicas
-}
-
 // IsConnectionHealthy checks if the database connection is healthy
 func IsConnectionHealthy(db interface{}) bool {
 	if DB == nil {
@@ -223,17 +248,141 @@ func SafeExec(query string, args ...interface{}) (pgconn.CommandTag, error) {
 func SafeExecTimeout(timeout time.Duration, query string, args ...interface{}) (pgconn.CommandTag, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	return DB.Exec(ctx, query, args...)
+	return safeExec(ctx, query, args...)
+}
+
+// SafeExecCtx is SafeExec bound to the caller's ctx, so cancelling it aborts the query.
+// DefaultDBTimeout applies only when ctx has no deadline of its own.
+func SafeExecCtx(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	return safeExec(ctx, query, args...)
+}
+
+// withDefaultTimeout bounds ctx by DefaultDBTimeout unless it already has a deadline
+func withDefaultTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DefaultDBTimeout)
+}
+
+// safeExec runs the Exec behind the SafeExec wrappers
+func safeExec(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	defer logSlowQuery(time.Now(), query, args)
+	return poolExec(ctx, DB, query, args...)
+}
+
+// ExecResult wraps a pgx CommandTag with statement classification helpers
+type ExecResult struct {
+	Tag pgconn.CommandTag
+}
+
+// RowsAffected returns the number of rows affected by the statement
+func (r ExecResult) RowsAffected() int64 {
+	return r.Tag.RowsAffected()
+}
+
+// IsInsert reports whether the statement was an INSERT
+func (r ExecResult) IsInsert() bool {
+	return r.Tag.Insert()
+}
+
+// IsUpdate reports whether the statement was an UPDATE
+func (r ExecResult) IsUpdate() bool {
+	return r.Tag.Update()
+}
+
+// IsDelete reports whether the statement was a DELETE
+func (r ExecResult) IsDelete() bool {
+	return r.Tag.Delete()
+}
+
+// IsSelect reports whether the statement was a SELECT
+func (r ExecResult) IsSelect() bool {
+	return r.Tag.Select()
+}
+
+// InsertOID returns the OID from an "INSERT oid rows" tag (always 0 on tables without OIDs)
+func (r ExecResult) InsertOID() uint32 {
+	if !r.Tag.Insert() {
+		return 0
+	}
+	fields := strings.Fields(r.Tag.String())
+	if len(fields) != 3 {
+		return 0
+	}
+	oid, err := strconv.ParseUint(fields[1], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(oid)
 }
 
-// SafeQuery wraps DB.Query (no timeout - iterator consumed after return)
+// String returns the raw command tag (e.g. "UPDATE 3")
+func (r ExecResult) String() string {
+	return r.Tag.String()
+}
+
+// SafeExecResult is SafeExec returning a classified ExecResult
+func SafeExecResult(query string, args ...interface{}) (ExecResult, error) {
+	tag, err := SafeExecTimeout(DefaultDBTimeout, query, args...)
+	return ExecResult{Tag: tag}, err
+}
+
+// SafeQuery wraps DB.Query with automatic timeout
+// The timeout covers the iteration too: it's released when the rows are closed
+// The slow-query log times the query until its first response, not the iteration
 func SafeQuery(query string, args ...interface{}) (pgx.Rows, error) {
-	return DB.Query(context.Background(), query, args...)
+	return SafeQueryTimeout(DefaultDBTimeout, query, args...)
 }
 
-// SafeQueryTimeout wraps DB.Query (no timeout - iterator consumed after return)
+// SafeQueryTimeout wraps DB.Query with custom timeout.
+// The context stays live while the caller iterates and is cancelled by rows.Close()
+// (or once Next reports no more rows), so an abandoned or hung iterator can't hold its
+// connection past the timeout.
 func SafeQueryTimeout(timeout time.Duration, query string, args ...interface{}) (pgx.Rows, error) {
-	return DB.Query(context.Background(), query, args...)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	return safeQuery(ctx, cancel, query, args...)
+}
+
+// SafeQueryCtx is SafeQuery bound to the caller's ctx (DefaultDBTimeout applies only when
+// ctx has no deadline); the derived context is released when the rows are closed
+func SafeQueryCtx(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	ctx, cancel := withDefaultTimeout(ctx)
+	return safeQuery(ctx, cancel, query, args...)
+}
+
+// safeQuery runs the Query behind the SafeQuery wrappers; cancel fires when the rows are done
+func safeQuery(ctx context.Context, cancel context.CancelFunc, query string, args ...interface{}) (pgx.Rows, error) {
+	defer logSlowQuery(time.Now(), query, args)
+	rows, err := poolQuery(ctx, DB, query, args...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &cancelOnCloseRows{Rows: rows, cancel: cancel}, nil
+}
+
+// cancelOnCloseRows is pgx.Rows whose query context is cancelled when the rows are done
+type cancelOnCloseRows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+}
+
+// Next advances the rows, releasing the context after the last row
+func (r *cancelOnCloseRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.cancel()
+	return false
+}
+
+// Close closes the rows, then cancels the query context
+func (r *cancelOnCloseRows) Close() {
+	r.Rows.Close()
+	r.cancel()
 }
 
 // SafeGet wraps Get with automatic timeout
@@ -241,11 +390,25 @@ func SafeGet(dest interface{}, query string, args ...interface{}) error {
 	return SafeGetTimeout(DefaultDBTimeout, dest, query, args...)
 }
 
-// SafeGetTimeout wraps Get with custom timeout
+// SafeGetTimeout wraps Get with custom timeout (routed to a replica when SetReplicaReads is on)
 func SafeGetTimeout(timeout time.Duration, dest interface{}, query string, args ...interface{}) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	rows, err := DB.Query(ctx, query, args...)
+	return safeGet(ctx, dest, query, args...)
+}
+
+// SafeGetCtx is SafeGet bound to the caller's ctx (DefaultDBTimeout applies only when ctx
+// has no deadline); it returns sql.ErrNoRows when no row matches
+func SafeGetCtx(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	return safeGet(ctx, dest, query, args...)
+}
+
+// safeGet runs the single-row read behind the SafeGet wrappers
+func safeGet(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	defer logSlowQuery(time.Now(), query, args)
+	rows, err := routedQuery(ctx, query, args...)
 	if err != nil {
 		return err
 	}
@@ -254,29 +417,91 @@ func SafeGetTimeout(timeout time.Duration, dest interface{}, query string, args
 }
 
 // SafeSelect wraps Select with automatic timeout
+// Rows are appended to dest: a non-empty slice keeps its existing elements (see SafeSelectReset)
 func SafeSelect(dest interface{}, query string, args ...interface{}) error {
 	return SafeSelectTimeout(DefaultDBTimeout, dest, query, args...)
 }
 
-// SafeSelectTimeout wraps Select with custom timeout
+// SafeSelectTimeout wraps Select with custom timeout (routed to a replica when SetReplicaReads is on)
 func SafeSelectTimeout(timeout time.Duration, dest interface{}, query string, args ...interface{}) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	rows, err := DB.Query(ctx, query, args...)
+	return safeSelect(ctx, dest, query, args...)
+}
+
+// SafeSelectCtx is SafeSelect bound to the caller's ctx (DefaultDBTimeout applies only when
+// ctx has no deadline); rows are appended to dest
+func SafeSelectCtx(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := withDefaultTimeout(ctx)
+	defer cancel()
+	return safeSelect(ctx, dest, query, args...)
+}
+
+// safeSelect runs the multi-row read behind the SafeSelect wrappers
+func safeSelect(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	defer logSlowQuery(time.Now(), query, args)
+	rows, err := routedQuery(ctx, query, args...)
 	if err != nil {
 		return err
 	}
 	return StructsScan(rows, dest)
 }
 
-// SafeQueryRow wraps DB.QueryRow (no timeout - Scan happens after return)
+// SafeSelectReset is SafeSelect that empties dest first, so it holds exactly the query's rows
+func SafeSelectReset(dest interface{}, query string, args ...interface{}) error {
+	return SafeSelectResetTimeout(DefaultDBTimeout, dest, query, args...)
+}
+
+// SafeSelectResetTimeout is SafeSelectTimeout that empties dest first
+func SafeSelectResetTimeout(timeout time.Duration, dest interface{}, query string, args ...interface{}) error {
+	if err := resetSlice(dest); err != nil {
+		return err
+	}
+	return SafeSelectTimeout(timeout, dest, query, args...)
+}
+
+// SelectT is SafeSelect returning the rows as a typed slice (empty, not nil, when no rows match)
+//
+//	models, err := fsql.SelectT[AIModel](`SELECT * FROM ai_model WHERE type = $1`, "chat")
+func SelectT[T any](query string, args ...interface{}) ([]T, error) {
+	out := make([]T, 0)
+	if err := SafeSelect(&out, query, args...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetT is SafeGet returning the row as a typed value; it returns sql.ErrNoRows when no row matches
+func GetT[T any](query string, args ...interface{}) (T, error) {
+	var out T
+	if err := SafeGet(&out, query, args...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return out, nil
+}
+
+// resetSlice truncates the slice dest points to (keeping its capacity)
+func resetSlice(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("dest must be a pointer to a slice, got %T", dest)
+	}
+	v.Elem().SetLen(0)
+	return nil
+}
+
+// SafeQueryRow wraps DB.QueryRow with automatic timeout
 func SafeQueryRow(query string, args ...interface{}) pgx.Row {
-	return DB.QueryRow(context.Background(), query, args...)
+	return SafeQueryRowTimeout(DefaultDBTimeout, query, args...)
 }
 
-// SafeQueryRowTimeout wraps DB.QueryRow (no timeout - Scan happens after return)
+// SafeQueryRowTimeout wraps DB.QueryRow with custom timeout; the deadline covers the
+// Scan that follows, and the context is released once Scan returns
 func SafeQueryRowTimeout(timeout time.Duration, query string, args ...interface{}) pgx.Row {
-	return DB.QueryRow(context.Background(), query, args...)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	rows, err := safeQuery(ctx, cancel, query, args...)
+	return &rowsRow{rows: rows, err: err}
 }
 
 // SafeNamedExec is not directly supported by pgx, provided for compatibility
@@ -342,42 +567,148 @@ func namedToPositional(query string, arg interface{}) (string, []interface{}, er
 }
 
 func namedMapToPositional(query string, m map[string]interface{}) (string, []interface{}, error) {
-	var args []interface{}
-	paramIdx := 1
-
-	for key, val := range m {
-		placeholder := ":" + key
-		if strings.Contains(query, placeholder) {
-			query = strings.Replace(query, placeholder, fmt.Sprintf("$%d", paramIdx), -1)
-			args = append(args, val)
-			paramIdx++
+	return bindNamed(query, func(name string) (interface{}, bool) {
+		val, ok := m[name]
+		return val, ok
+	})
+}
+
+func namedStructToPositional(query string, v reflect.Value) (string, []interface{}, error) {
+	t := v.Type()
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		dbTag := t.Field(i).Tag.Get("db")
+		if dbTag == "" || dbTag == "-" {
+			continue
 		}
+		fields[dbTag] = i
 	}
 
-	return query, args, nil
+	return bindNamed(query, func(name string) (interface{}, bool) {
+		idx, ok := fields[name]
+		if !ok {
+			return nil, false
+		}
+		return v.Field(idx).Interface(), true
+	})
 }
 
-func namedStructToPositional(query string, v reflect.Value) (string, []interface{}, error) {
+// bindNamed replaces each :name parameter with $N, matching whole identifiers only.
+// Parameters inside literals, quoted identifiers and comments are left alone, as are
+// :: casts. A name used several times binds to the same placeholder, and a :name with no
+// matching key or field is left as is.
+// A slice bound directly inside IN (...) expands to one placeholder per element, like sqlx.In:
+// "type IN (:types)" becomes "type IN ($1, $2, $3)". Elsewhere (e.g. = ANY(:ids)) a slice
+// stays a single array parameter.
+func bindNamed(query string, lookup func(name string) (interface{}, bool)) (string, []interface{}, error) {
+	var sb strings.Builder
+	sb.Grow(len(query))
 	var args []interface{}
-	paramIdx := 1
-	t := v.Type()
+	placeholders := make(map[string]string)
+	n := len(query)
 
-	for i := 0; i < v.NumField(); i++ {
-		field := t.Field(i)
-		dbTag := field.Tag.Get("db")
-		if dbTag == "" || dbTag == "-" {
+	for i := 0; i < n; {
+		if next, ok := skipSQLNonCode(query, i); ok {
+			sb.WriteString(query[i:next])
+			i = next
+			continue
+		}
+
+		c := query[i]
+		if c != ':' {
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+
+		// :: cast
+		if i+1 < n && query[i+1] == ':' {
+			sb.WriteString("::")
+			i += 2
+			continue
+		}
+
+		end := i + 1
+		for end < n && isWordByte(query[end]) {
+			end++
+		}
+		if end == i+1 || (query[i+1] >= '0' && query[i+1] <= '9') {
+			sb.WriteByte(c)
+			i++
 			continue
 		}
 
-		placeholder := ":" + dbTag
-		if strings.Contains(query, placeholder) {
-			query = strings.Replace(query, placeholder, fmt.Sprintf("$%d", paramIdx), -1)
-			args = append(args, v.Field(i).Interface())
-			paramIdx++
+		name := query[i+1 : end]
+		inList := endsWithInParen(sb.String())
+		key := name
+		if inList {
+			key = name + "()"
+		}
+
+		placeholder, seen := placeholders[key]
+		if !seen {
+			val, ok := lookup(name)
+			if !ok {
+				// Unknown names pass through untouched, as they always have
+				sb.WriteString(query[i:end])
+				i = end
+				continue
+			}
+
+			if elems, ok := expandableSlice(val); ok && inList {
+				if len(elems) == 0 {
+					return "", nil, fmt.Errorf("empty slice for named parameter: %s", name)
+				}
+				placeholder = PlaceholdersString(len(args)+1, len(elems))
+				args = append(args, elems...)
+			} else {
+				args = append(args, val)
+				placeholder = "$" + strconv.Itoa(len(args))
+			}
+			placeholders[key] = placeholder
 		}
+		sb.WriteString(placeholder)
+		i = end
 	}
 
-	return query, args, nil
+	return sb.String(), args, nil
+}
+
+// endsWithInParen reports whether s ends with "IN (" (any case and spacing)
+func endsWithInParen(s string) bool {
+	s = strings.TrimRight(s, " \t\r\n")
+	if !strings.HasSuffix(s, "(") {
+		return false
+	}
+	s = strings.TrimRight(s[:len(s)-1], " \t\r\n")
+	if len(s) < 2 || !strings.EqualFold(s[len(s)-2:], "IN") {
+		return false
+	}
+	return len(s) == 2 || !isWordByte(s[len(s)-3])
+}
+
+// expandableSlice returns the elements of a slice or array value that binds as a list.
+// []byte and driver.Valuer types (JSON, arrays with their own encoding) are single values.
+func expandableSlice(val interface{}) ([]interface{}, bool) {
+	if val == nil {
+		return nil, false
+	}
+	if _, ok := val.(driver.Valuer); ok {
+		return nil, false
+	}
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, false
+	}
+	if rv.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+
+	elems := make([]interface{}, rv.Len())
+	for i := range elems {
+		elems[i] = rv.Index(i).Interface()
+	}
+	return elems, true
 }
 
 // =============================================================================
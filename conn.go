@@ -0,0 +1,210 @@
+// conn.go - Dedicated pool connections for session-scoped work
+package fsql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Conn is a connection checked out from the pool for session-scoped work
+// (LISTEN, advisory locks, COPY, SET ...). Everything run through a Conn uses
+// the same backend session until Release is called.
+//
+// WARNING: a Conn that is never released is leaked from the pool for good.
+// Always `defer conn.Release()` right after AcquireConn. Session state (GUCs,
+// advisory locks, temp tables) survives Release, so reset it before releasing.
+// Conn is NOT safe for concurrent use by multiple goroutines.
+type Conn struct {
+	conn *pgxpool.Conn
+}
+
+// ErrConnReleased is returned when using a Conn after Release
+var ErrConnReleased = errors.New("connection has already been released")
+
+// AcquireConn checks out a dedicated connection from the pool
+func AcquireConn(ctx context.Context) (*Conn, error) {
+	if DB == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	conn, err := DB.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	return &Conn{conn: conn}, nil
+}
+
+// ConnFn is a function run on a dedicated connection by WithConn
+type ConnFn func(ctx context.Context, conn *Conn) error
+
+// WithConn checks out a dedicated connection, runs fn on it and releases it afterwards,
+// even if fn panics. Session state set by fn is still not reset on release.
+func WithConn(ctx context.Context, fn ConnFn) error {
+	conn, err := AcquireConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	return fn(ctx, conn)
+}
+
+// Release returns the connection to the pool (safe to call more than once)
+func (c *Conn) Release() {
+	if c.conn == nil {
+		return
+	}
+	c.conn.Release()
+	c.conn = nil
+}
+
+// Raw returns the underlying pgx connection (nil after Release)
+func (c *Conn) Raw() *pgx.Conn {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Conn()
+}
+
+// ExecContext executes a query on the connection with context
+func (c *Conn) ExecContext(ctx context.Context, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	if c.conn == nil {
+		return pgconn.CommandTag{}, ErrConnReleased
+	}
+	return c.conn.Exec(ctx, query, args...)
+}
+
+// QueryContext executes a query that returns rows on the connection with context
+func (c *Conn) QueryContext(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	if c.conn == nil {
+		return nil, ErrConnReleased
+	}
+	return c.conn.Query(ctx, query, args...)
+}
+
+// QueryRowContext executes a query that returns at most one row with context
+func (c *Conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) pgx.Row {
+	if c.conn == nil {
+		return errRow{err: ErrConnReleased}
+	}
+	return c.conn.QueryRow(ctx, query, args...)
+}
+
+// GetContext retrieves a single row into dest with context
+func (c *Conn) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return StructScan(rows, dest)
+}
+
+// SelectContext retrieves multiple rows into dest with context
+func (c *Conn) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	rows, err := c.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	return StructsScan(rows, dest)
+}
+
+// SafeExec executes a query on the connection with automatic timeout
+func (c *Conn) SafeExec(query string, args ...interface{}) (pgconn.CommandTag, error) {
+	return c.SafeExecTimeout(DefaultDBTimeout, query, args...)
+}
+
+// SafeExecTimeout executes a query on the connection with custom timeout
+func (c *Conn) SafeExecTimeout(timeout time.Duration, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.ExecContext(ctx, query, args...)
+}
+
+// SafeQuery executes a query on the connection (no timeout - iterator consumed after return)
+func (c *Conn) SafeQuery(query string, args ...interface{}) (pgx.Rows, error) {
+	return c.QueryContext(context.Background(), query, args...)
+}
+
+// SafeQueryRow executes a single-row query on the connection (no timeout - Scan happens after return)
+func (c *Conn) SafeQueryRow(query string, args ...interface{}) pgx.Row {
+	return c.QueryRowContext(context.Background(), query, args...)
+}
+
+// SafeGet retrieves a single row on the connection with automatic timeout
+func (c *Conn) SafeGet(dest interface{}, query string, args ...interface{}) error {
+	return c.SafeGetTimeout(DefaultDBTimeout, dest, query, args...)
+}
+
+// SafeGetTimeout retrieves a single row on the connection with custom timeout
+func (c *Conn) SafeGetTimeout(timeout time.Duration, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.GetContext(ctx, dest, query, args...)
+}
+
+// SafeSelect retrieves multiple rows on the connection with automatic timeout
+func (c *Conn) SafeSelect(dest interface{}, query string, args ...interface{}) error {
+	return c.SafeSelectTimeout(DefaultDBTimeout, dest, query, args...)
+}
+
+// SafeSelectTimeout retrieves multiple rows on the connection with custom timeout
+func (c *Conn) SafeSelectTimeout(timeout time.Duration, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return c.SelectContext(ctx, dest, query, args...)
+}
+
+// SafeNamedExec converts named parameters to positional and executes on the connection
+func (c *Conn) SafeNamedExec(query string, arg interface{}) (pgconn.CommandTag, error) {
+	positionalQuery, args, err := namedToPositional(query, arg)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return c.SafeExec(positionalQuery, args...)
+}
+
+// AdvisoryLock takes a session-level advisory lock on key, waiting until it is available.
+// The lock is held until AdvisoryUnlock on this same Conn (or the session ends).
+func (c *Conn) AdvisoryLock(ctx context.Context, key int64) error {
+	_, err := c.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key)
+	if err != nil {
+		return fmt.Errorf("failed to take advisory lock %d: %w", key, err)
+	}
+	return nil
+}
+
+// TryAdvisoryLock takes a session-level advisory lock on key without waiting,
+// reporting whether it was acquired
+func (c *Conn) TryAdvisoryLock(ctx context.Context, key int64) (bool, error) {
+	var locked bool
+	if err := c.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&locked); err != nil {
+		return false, fmt.Errorf("failed to try advisory lock %d: %w", key, err)
+	}
+	return locked, nil
+}
+
+// AdvisoryUnlock releases a session-level advisory lock taken on this Conn,
+// reporting whether it was held
+func (c *Conn) AdvisoryUnlock(ctx context.Context, key int64) (bool, error) {
+	var unlocked bool
+	if err := c.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", key).Scan(&unlocked); err != nil {
+		return false, fmt.Errorf("failed to release advisory lock %d: %w", key, err)
+	}
+	return unlocked, nil
+}
+
+// errRow is a pgx.Row that always fails with err
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...any) error {
+	return r.err
+}
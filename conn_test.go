@@ -0,0 +1,127 @@
+// conn_test.go
+package fsql
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestAcquireConnSessionSetting tests that a GUC set on an acquired connection sticks to that session
+func TestAcquireConnSessionSetting(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	_, acquiredBefore, _, _, _ := GetPoolStats()
+
+	conn, err := AcquireConn(ctx)
+	if err != nil {
+		t.Fatalf("AcquireConn failed: %v", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.SafeExec("SET application_name = 'fsql_conn_test'"); err != nil {
+		t.Fatalf("Failed to set session GUC: %v", err)
+	}
+
+	var appName string
+	if err := conn.SafeGet(&appName, "SELECT current_setting('application_name')"); err != nil {
+		t.Fatalf("Failed to read session GUC: %v", err)
+	}
+	if appName != "fsql_conn_test" {
+		t.Errorf("Expected application_name fsql_conn_test, got %q", appName)
+	}
+
+	// Struct helpers work on the dedicated connection too
+	if _, err := conn.SafeExec("INSERT INTO realm (uuid, name) VALUES ($1, $2)", GenNewUUID(""), "Conn Realm"); err != nil {
+		t.Fatalf("Failed to insert on connection: %v", err)
+	}
+	var realms []Realm
+	if err := conn.SafeSelect(&realms, "SELECT * FROM realm"); err != nil {
+		t.Fatalf("Failed to select on connection: %v", err)
+	}
+	if len(realms) != 1 || realms[0].Name != "Conn Realm" {
+		t.Errorf("Expected the inserted realm, got %+v", realms)
+	}
+
+	if _, err := conn.SafeExec("RESET application_name"); err != nil {
+		t.Fatalf("Failed to reset session GUC: %v", err)
+	}
+
+	conn.Release()
+	conn.Release() // second release is a no-op
+
+	_, acquiredAfter, _, _, _ := GetPoolStats()
+	if acquiredAfter != acquiredBefore {
+		t.Errorf("Expected %d acquired connections after release, got %d", acquiredBefore, acquiredAfter)
+	}
+
+	if _, err := conn.SafeExec("SELECT 1"); !errors.Is(err, ErrConnReleased) {
+		t.Errorf("Expected ErrConnReleased after release, got %v", err)
+	}
+	var one int
+	if err := conn.SafeQueryRow("SELECT 1").Scan(&one); !errors.Is(err, ErrConnReleased) {
+		t.Errorf("Expected ErrConnReleased from QueryRow after release, got %v", err)
+	}
+}
+
+// TestConnAdvisoryLock tests that session advisory locks stay on their connection
+func TestConnAdvisoryLock(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+	const key = 554
+
+	_, acquiredBefore, _, _, _ := GetPoolStats()
+
+	err := WithConn(ctx, func(ctx context.Context, conn *Conn) error {
+		if err := conn.AdvisoryLock(ctx, key); err != nil {
+			return err
+		}
+
+		// Another session can't take the lock while this one holds it
+		other, err := AcquireConn(ctx)
+		if err != nil {
+			return err
+		}
+		defer other.Release()
+		locked, err := other.TryAdvisoryLock(ctx, key)
+		if err != nil {
+			return err
+		}
+		if locked {
+			t.Error("Expected the lock to be held by the first session")
+			other.AdvisoryUnlock(ctx, key)
+		}
+
+		unlocked, err := conn.AdvisoryUnlock(ctx, key)
+		if err != nil {
+			return err
+		}
+		if !unlocked {
+			t.Error("Expected AdvisoryUnlock to release the held lock")
+		}
+
+		locked, err = other.TryAdvisoryLock(ctx, key)
+		if err != nil {
+			return err
+		}
+		if !locked {
+			t.Error("Expected the lock to be free after unlock")
+		}
+		_, err = other.AdvisoryUnlock(ctx, key)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithConn failed: %v", err)
+	}
+
+	_, acquiredAfter, _, _, _ := GetPoolStats()
+	if acquiredAfter != acquiredBefore {
+		t.Errorf("Expected WithConn to release its connection: %d acquired, was %d", acquiredAfter, acquiredBefore)
+	}
+
+	sentinel := errors.New("fn failed")
+	if err := WithConn(ctx, func(ctx context.Context, conn *Conn) error { return sentinel }); !errors.Is(err, sentinel) {
+		t.Errorf("Expected WithConn to return fn's error, got %v", err)
+	}
+}
@@ -0,0 +1,150 @@
+// copy.go - Bulk loading with the COPY protocol
+package fsql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CopyInsert bulk-loads rows into table with COPY FROM STDIN and returns the number of rows copied.
+// Each row holds one value per column, in column order. It is much faster than multi-row INSERTs
+// for large imports, but COPY has no RETURNING: generated keys must be read back separately.
+// COPY is all-or-nothing: one bad row fails the whole load.
+func CopyInsert(ctx context.Context, tableName string, columns []string, rows [][]interface{}) (int64, error) {
+	if DB == nil {
+		return 0, errors.New("database not initialized")
+	}
+	if len(columns) == 0 {
+		return 0, errors.New("no columns to copy")
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return 0, fmt.Errorf("copy row %d has %d values, expected %d", i, len(row), len(columns))
+		}
+	}
+
+	next := 0
+	source := pgx.CopyFromFunc(func() ([]interface{}, error) {
+		if next >= len(rows) {
+			return nil, nil
+		}
+		row := rows[next]
+		next++
+
+		// JSONB Valuers are sent as their JSON text, as GetInsertQuery does.
+		// The row is cloned first so the caller's slice isn't modified.
+		cloned := false
+		for j, val := range row {
+			if isJSONBType(val) {
+				if !cloned {
+					row = append([]interface{}(nil), row...)
+					cloned = true
+				}
+				row[j] = jsonbArg(val)
+			}
+		}
+		return row, nil
+	})
+
+	count, err := DB.CopyFrom(ctx, pgx.Identifier{tableName}, columns, source)
+	if err != nil {
+		return count, fmt.Errorf("copy failed: %w", err)
+	}
+	return count, nil
+}
+
+// CopyInsertObjects is CopyInsert for a slice of structs (or struct pointers), copying the
+// dbMode "i" fields like BatchInsertExecutor does. A zero field with a dbInsertValue gets that
+// default: NULL, NOW() (the time the copy started), true/false, or the literal string.
+// Fields whose dbInsertValue is DEFAULT are left out of the COPY so the column default applies;
+// a raw SQL default ("sql:...") can't be sent through COPY, so such fields must be set.
+func CopyInsertObjects(ctx context.Context, tableName string, objects interface{}) (int64, error) {
+	val := reflect.ValueOf(objects)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Slice {
+		return 0, fmt.Errorf("objects must be a slice")
+	}
+	if val.Len() == 0 {
+		return 0, nil
+	}
+
+	objType := val.Type().Elem()
+	isPtr := objType.Kind() == reflect.Ptr
+	if isPtr {
+		objType = objType.Elem()
+	}
+
+	tagCache, err := getModelTagCache(objType)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get model tag cache: %w", err)
+	}
+
+	var columns []string
+	var fields []ModelField
+	for _, field := range tagCache.Fields {
+		if field.HasMode("i") && field.writable() && field.InsertValue != "DEFAULT" {
+			columns = append(columns, field.DbName)
+			fields = append(fields, field)
+		}
+	}
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("no fields marked for insertion")
+	}
+
+	now := time.Now()
+	rows := make([][]interface{}, 0, val.Len())
+	for i := 0; i < val.Len(); i++ {
+		obj := val.Index(i)
+		if isPtr {
+			if obj.IsNil() {
+				return 0, fmt.Errorf("nil object at index %d", i)
+			}
+			obj = obj.Elem()
+		}
+
+		row := make([]interface{}, len(fields))
+		for j, field := range fields {
+			// By name: tag cache positions skip untagged fields, so they aren't struct field indexes
+			fieldVal := obj.FieldByName(field.Name)
+			insertValue := field.InsertValue
+			if insertValue != "" && fieldVal.IsZero() {
+				if strings.HasPrefix(insertValue, RawSQLInsertPrefix) {
+					return 0, fmt.Errorf("object at index %d: field %s has a raw SQL default, which COPY can't apply", i, field.DbName)
+				}
+				row[j] = copyDefaultValue(insertValue, now)
+			} else {
+				row[j] = fieldVal.Interface()
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return CopyInsert(ctx, tableName, columns, rows)
+}
+
+// copyDefaultValue converts a dbInsertValue to the value sent through COPY
+func copyDefaultValue(insertValue string, now time.Time) interface{} {
+	switch insertValue {
+	case "NULL":
+		return nil
+	case "NOW()":
+		return now
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return insertValue
+}
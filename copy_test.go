@@ -0,0 +1,167 @@
+// copy_test.go
+package fsql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// TestCopyInsert tests bulk loading rows from column/value slices
+func TestCopyInsert(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	rows := make([][]interface{}, 0, 250)
+	for i := 0; i < 250; i++ {
+		rows = append(rows, []interface{}{GenNewUUID(""), fmt.Sprintf("copy_key_%03d", i), "copy_type", "copy_provider", jsonbSettings{"index": i}})
+	}
+
+	count, err := CopyInsert(ctx, "ai_model", []string{"uuid", "key", "type", "provider", "settings"}, rows)
+	if err != nil {
+		t.Fatalf("CopyInsert failed: %v", err)
+	}
+	if count != 250 {
+		t.Errorf("Expected 250 rows copied, got %d", count)
+	}
+	if _, ok := rows[0][4].(jsonbSettings); !ok {
+		t.Errorf("Expected the caller's rows to be left untouched, got %T", rows[0][4])
+	}
+
+	var model AIModel
+	if err := Db.Get(&model, aiModelBaseQuery+` WHERE "ai_model".key = $1`, "copy_key_042"); err != nil {
+		t.Fatalf("Failed to read copied row: %v", err)
+	}
+	if model.Type != "copy_type" || model.Settings == nil || *model.Settings != `{"index": 42}` {
+		t.Errorf("Unexpected copied row: %+v", model)
+	}
+
+	if _, err := CopyInsert(ctx, "ai_model", []string{"key", "type"}, [][]interface{}{{"only_key"}}); err == nil {
+		t.Error("Expected error for a row with the wrong number of values")
+	}
+}
+
+// TestCopyInsertObjects tests bulk loading structs through their insert tags
+func TestCopyInsertObjects(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	realms := make([]*Realm, 0, 100)
+	for i := 0; i < 100; i++ {
+		realms = append(realms, &Realm{UUID: GenNewUUID(""), Name: fmt.Sprintf("Copied Realm %02d", i)})
+	}
+
+	count, err := CopyInsertObjects(ctx, "realm", realms)
+	if err != nil {
+		t.Fatalf("CopyInsertObjects failed: %v", err)
+	}
+	if count != 100 {
+		t.Errorf("Expected 100 rows copied, got %d", count)
+	}
+
+	var fetched []Realm
+	if err := Db.Select(&fetched, realmBaseQuery+` ORDER BY name`); err != nil {
+		t.Fatalf("Failed to read copied realms: %v", err)
+	}
+	if len(fetched) != 100 {
+		t.Fatalf("Expected 100 realms, got %d", len(fetched))
+	}
+	// Zero CreatedAt/UpdatedAt took their NOW() default
+	if fetched[7].Name != "Copied Realm 07" || fetched[7].CreatedAt.IsZero() || fetched[7].UpdatedAt.IsZero() {
+		t.Errorf("Unexpected copied realm: %+v", fetched[7])
+	}
+
+	// dbInsertValue:"NULL" pointers stay NULL, set ones are copied
+	name := "Named Copy"
+	models := []AIModel{
+		{UUID: GenNewUUID(""), Key: "copy_obj_1", Type: "t", Provider: "p", Name: &name},
+		{UUID: GenNewUUID(""), Key: "copy_obj_2", Type: "t", Provider: "p"},
+	}
+	if _, err := CopyInsertObjects(ctx, "ai_model", models); err != nil {
+		t.Fatalf("CopyInsertObjects with models failed: %v", err)
+	}
+	var copied []AIModel
+	if err := Db.Select(&copied, aiModelBaseQuery+` ORDER BY "ai_model".key`); err != nil {
+		t.Fatalf("Failed to read copied models: %v", err)
+	}
+	if len(copied) != 2 || copied[0].Name == nil || *copied[0].Name != name || copied[1].Name != nil {
+		t.Errorf("Unexpected copied models: %+v", copied)
+	}
+}
+
+// realmWithUntagged has an untagged field ahead of its columns
+type realmWithUntagged struct {
+	Scratch string
+	UUID    string `db:"uuid" dbMode:"i"`
+	Name    string `db:"name" dbMode:"i,u"`
+}
+
+// TestCopyInsertObjectsUntaggedFields tests that untagged fields don't shift the copied values
+func TestCopyInsertObjectsUntaggedFields(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	id := GenNewUUID("")
+	objects := []realmWithUntagged{{Scratch: "not a column", UUID: id, Name: "Untagged Neighbour"}}
+	if _, err := CopyInsertObjects(ctx, "realm", objects); err != nil {
+		t.Fatalf("CopyInsertObjects failed: %v", err)
+	}
+
+	var name string
+	if err := Db.Get(&name, `SELECT name FROM realm WHERE uuid = $1`, id); err != nil {
+		t.Fatalf("Failed to read copied realm: %v", err)
+	}
+	if name != "Untagged Neighbour" {
+		t.Errorf("Expected the name field to be copied, got %q", name)
+	}
+}
+
+const copyBenchRows = 500000
+
+// BenchmarkCopyInsertRealms benchmarks a 500k-row import through COPY
+func BenchmarkCopyInsertRealms(b *testing.B) {
+	ctx := context.Background()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		rows := make([][]interface{}, copyBenchRows)
+		for j := range rows {
+			rows[j] = []interface{}{GenNewUUID(""), fmt.Sprintf("copy_bench_%d", j)}
+		}
+		b.StartTimer()
+
+		if _, err := CopyInsert(ctx, "realm", []string{"uuid", "name"}, rows); err != nil {
+			b.Fatalf("CopyInsert failed: %v", err)
+		}
+
+		b.StopTimer()
+		DB.Exec(ctx, `DELETE FROM realm WHERE name LIKE 'copy_bench_%'`)
+		b.StartTimer()
+	}
+}
+
+// BenchmarkBatchInsertRealms benchmarks the same 500k-row import through BatchInsertExecutor
+func BenchmarkBatchInsertRealms(b *testing.B) {
+	ctx := context.Background()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		rows := make([]map[string]interface{}, copyBenchRows)
+		for j := range rows {
+			rows[j] = map[string]interface{}{"uuid": GenNewUUID(""), "name": fmt.Sprintf("copy_bench_%d", j)}
+		}
+		b.StartTimer()
+
+		batch := NewBatchInsert("realm", []string{"uuid", "name"}, 1000)
+		for _, row := range rows {
+			if err := batch.Add(row); err != nil {
+				b.Fatalf("Batch add failed: %v", err)
+			}
+		}
+		if err := batch.FlushContext(ctx); err != nil {
+			b.Fatalf("Batch flush failed: %v", err)
+		}
+
+		b.StopTimer()
+		DB.Exec(ctx, `DELETE FROM realm WHERE name LIKE 'copy_bench_%'`)
+		b.StartTimer()
+	}
+}
@@ -0,0 +1,170 @@
+// cursor.go - Opaque continuation tokens for keyset pagination
+package fsql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Cursor value type tags - keep the token compact while preserving Go types
+const (
+	cursorTypeNull   = "n"
+	cursorTypeInt    = "i"
+	cursorTypeUint   = "u"
+	cursorTypeFloat  = "f"
+	cursorTypeString = "s"
+	cursorTypeBool   = "b"
+	cursorTypeTime   = "t"
+)
+
+// cursorField is the serialized form of a single sort value
+type cursorField struct {
+	Field string          `json:"f"`
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var timePtrType = reflect.TypeOf(&time.Time{})
+
+// ErrInvalidCursor is returned when a cursor token cannot be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor builds an opaque token from the sort field values of row.
+// sortFields are Go struct field names (the same names used in Filter and Sort).
+func EncodeCursor(row interface{}, sortFields []string) (string, error) {
+	v := reflect.ValueOf(row)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", errors.New("cursor row is nil")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return "", fmt.Errorf("cursor row must be a struct, got %s", v.Kind())
+	}
+
+	fields := make([]cursorField, 0, len(sortFields))
+	for _, name := range sortFields {
+		fv := v.FieldByName(name)
+		if !fv.IsValid() {
+			return "", fmt.Errorf("cursor field not found: %s", name)
+		}
+
+		cf, err := encodeCursorValue(name, fv)
+		if err != nil {
+			return "", err
+		}
+		fields = append(fields, cf)
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// encodeCursorValue serializes a single field value with its type tag
+func encodeCursorValue(name string, fv reflect.Value) (cursorField, error) {
+	for fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return cursorField{Field: name, Type: cursorTypeNull}, nil
+		}
+		fv = fv.Elem()
+	}
+
+	var typ string
+	var val interface{}
+
+	switch {
+	case fv.Type() == timeType:
+		typ = cursorTypeTime
+		val = fv.Interface().(time.Time).Format(time.RFC3339Nano)
+	default:
+		switch fv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			typ, val = cursorTypeInt, fv.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			typ, val = cursorTypeUint, fv.Uint()
+		case reflect.Float32, reflect.Float64:
+			typ, val = cursorTypeFloat, fv.Float()
+		case reflect.String:
+			typ, val = cursorTypeString, fv.String()
+		case reflect.Bool:
+			typ, val = cursorTypeBool, fv.Bool()
+		default:
+			return cursorField{}, fmt.Errorf("unsupported cursor field type for %s: %s", name, fv.Type())
+		}
+	}
+
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return cursorField{}, err
+	}
+	return cursorField{Field: name, Type: typ, Value: raw}, nil
+}
+
+// DecodeCursor decodes a token produced by EncodeCursor into field -> value.
+// Values come back as int64, uint64, float64, string, bool, time.Time or nil.
+func DecodeCursor(token string) (map[string]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	var fields []cursorField
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	result := make(map[string]interface{}, len(fields))
+	for _, cf := range fields {
+		val, err := decodeCursorValue(cf)
+		if err != nil {
+			return nil, fmt.Errorf("%w: field %s: %v", ErrInvalidCursor, cf.Field, err)
+		}
+		result[cf.Field] = val
+	}
+	return result, nil
+}
+
+// decodeCursorValue restores a typed value from its serialized form
+func decodeCursorValue(cf cursorField) (interface{}, error) {
+	switch cf.Type {
+	case cursorTypeNull:
+		return nil, nil
+	case cursorTypeInt:
+		var v int64
+		err := json.Unmarshal(cf.Value, &v)
+		return v, err
+	case cursorTypeUint:
+		var v uint64
+		err := json.Unmarshal(cf.Value, &v)
+		return v, err
+	case cursorTypeFloat:
+		var v float64
+		err := json.Unmarshal(cf.Value, &v)
+		return v, err
+	case cursorTypeString:
+		var v string
+		err := json.Unmarshal(cf.Value, &v)
+		return v, err
+	case cursorTypeBool:
+		var v bool
+		err := json.Unmarshal(cf.Value, &v)
+		return v, err
+	case cursorTypeTime:
+		var s string
+		if err := json.Unmarshal(cf.Value, &s); err != nil {
+			return nil, err
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	default:
+		return nil, fmt.Errorf("unknown type tag %q", cf.Type)
+	}
+}
@@ -0,0 +1,121 @@
+// ddl.go - CREATE TABLE statements from model metadata
+package fsql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// GenerateCreateTable builds a best-effort CREATE TABLE IF NOT EXISTS for a registered model,
+// meant to bootstrap test and dev schemas rather than replace migrations. Every selectable
+// column is included (linked, "s" and "agg" fields are not). Types come from the dbType tag
+// or the Go field type (string→text, int→bigint, time.Time→timestamptz, []string→text[], ...),
+// with other structs, maps and slices stored as jsonb. Pointers, sql.Scanner types (sql.Null*,
+// octypes.Null*, ...), maps and slices are nullable; other columns are NOT NULL. The primary key ("pk" mode) gets PRIMARY KEY, and SQL insert
+// defaults (NOW(), true, "sql:..." expressions) become column DEFAULTs.
+func GenerateCreateTable(tableName string) (string, error) {
+	modelInfo, ok := getModelInfo(tableName)
+	if !ok {
+		return "", fmt.Errorf("table name not initialized: %s", tableName)
+	}
+	if len(modelInfo.dbFieldsSelect) == 0 {
+		return "", fmt.Errorf("no columns for table %s", tableName)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE IF NOT EXISTS ")
+	sb.WriteString(modelInfo.quotedTableName)
+	sb.WriteString(" (")
+	for i, column := range modelInfo.dbFieldsSelect {
+		pgType, err := ddlColumnType(modelInfo, column)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString("\n\t")
+		sb.WriteString(modelInfo.quotedFields[column])
+		sb.WriteByte(' ')
+		sb.WriteString(pgType)
+
+		if column == modelInfo.primaryKey {
+			sb.WriteString(" PRIMARY KEY")
+		} else if !ddlNullable(modelInfo.fieldTypes[column]) {
+			sb.WriteString(" NOT NULL")
+		}
+		if expr, ok := insertDefaultSQL(modelInfo.dbInsertValueMap[column]); ok && expr != "DEFAULT" && expr != "NULL" {
+			sb.WriteString(" DEFAULT ")
+			sb.WriteString(expr)
+		}
+	}
+	sb.WriteString("\n)")
+	return sb.String(), nil
+}
+
+// ddlColumnType is columnPgType extended for table creation: slices of mappable elements become
+// arrays, Valuer/Scanner structs take the type of the sql.Null* they embed (e.g. octypes.NullString),
+// and other structs, maps and slices are stored as jsonb
+func ddlColumnType(modelInfo *modelInfo, column string) (string, error) {
+	pgType, err := columnPgType(modelInfo, column)
+	if err == nil {
+		return pgType, nil
+	}
+	t := modelInfo.fieldTypes[column]
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if pgType, ok := ddlElemType(t); ok {
+		return pgType, nil
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.Implements(valuerType) || reflect.PtrTo(t).Implements(scannerType) {
+			if t.NumField() > 0 && t.Field(0).Anonymous {
+				if pgType, ok := ddlElemType(t.Field(0).Type); ok {
+					return pgType, nil
+				}
+			}
+			// Stored by its own Value/Scan, so the column type can't be guessed
+			return "", err
+		}
+		return "jsonb", nil
+	case reflect.Slice:
+		// Scalar slices are sent and scanned as Postgres arrays
+		if elemType, ok := ddlElemType(t.Elem()); ok {
+			return elemType + "[]", nil
+		}
+		return "jsonb", nil
+	case reflect.Map:
+		return "jsonb", nil
+	}
+	return "", err
+}
+
+// ddlElemType is pgTypeForGoType plus uuid.UUID
+func ddlElemType(t reflect.Type) (string, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(uuid.UUID{}) {
+		return "uuid", true
+	}
+	return pgTypeForGoType(t)
+}
+
+// valuerType is the driver.Valuer interface type
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// ddlNullable reports whether a Go field type can hold NULL: pointers, maps, slices,
+// interfaces and sql.Scanner types (sql.Null*, octypes.Null*, pgtype.*)
+func ddlNullable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return true
+	}
+	return reflect.PtrTo(t).Implements(scannerType)
+}
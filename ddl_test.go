@@ -0,0 +1,133 @@
+// ddl_test.go
+package fsql
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coffyg/octypes"
+	"github.com/google/uuid"
+)
+
+// ddlModel covers the column types and options GenerateCreateTable infers
+type ddlModel struct {
+	UUID      string            `db:"uuid" dbMode:"i,pk" dbType:"uuid" dbInsertValue:"sql:gen_random_uuid()"`
+	Name      string            `db:"name" dbMode:"i,u"`
+	Position  int               `db:"position" dbMode:"i,u"`
+	Note      *string           `db:"note" dbMode:"i,u"`
+	Meta      map[string]string `db:"meta" dbMode:"i,u"`
+	CreatedAt time.Time         `db:"created_at" dbMode:"i" dbInsertValue:"NOW()"`
+	Computed  int               `db:"computed" dbMode:"s"`
+}
+
+// TestGenerateCreateTable tests building and running a CREATE TABLE from model tags
+func TestGenerateCreateTable(t *testing.T) {
+	ctx := context.Background()
+	InitModelTagCache(ddlModel{}, "ddl_models")
+	defer ClearModelCache("ddl_models")
+
+	if _, err := GenerateCreateTable("not_registered"); err == nil {
+		t.Error("Expected an error for an unregistered table")
+	}
+
+	ddl, err := GenerateCreateTable("ddl_models")
+	if err != nil {
+		t.Fatalf("GenerateCreateTable failed: %v", err)
+	}
+	for _, want := range []string{
+		`CREATE TABLE IF NOT EXISTS "ddl_models" (`,
+		`"uuid" uuid PRIMARY KEY DEFAULT gen_random_uuid()`,
+		`"name" text NOT NULL`,
+		`"position" bigint NOT NULL`,
+		`"note" text,`,
+		`"meta" jsonb,`,
+		`"created_at" timestamptz NOT NULL DEFAULT NOW()`,
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("Expected %q in:\n%s", want, ddl)
+		}
+	}
+	if strings.Contains(ddl, "computed") {
+		t.Errorf("Skipped fields should not become columns:\n%s", ddl)
+	}
+
+	if _, err := SafeExec(ddl); err != nil {
+		t.Fatalf("Generated DDL failed: %v", err)
+	}
+	defer SafeExec(`DROP TABLE IF EXISTS ddl_models`)
+
+	query, args := GetInsertQuery("ddl_models", map[string]interface{}{"name": "first", "position": 1}, "uuid")
+	var id string
+	if err := DB.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		t.Fatalf("Insert into the generated table failed: %v", err)
+	}
+	if id == "" {
+		t.Error("Expected a generated primary key")
+	}
+}
+
+// ddlArrayModel has array and Scanner columns the library writes natively
+type ddlArrayModel struct {
+	UUID   string             `db:"uuid" dbMode:"i,pk" dbType:"uuid"`
+	Tags   []string           `db:"tags" dbMode:"i,u"`
+	Scores []int64            `db:"scores" dbMode:"i,u"`
+	Owners []uuid.UUID        `db:"owners" dbMode:"i,u"`
+	Bio    octypes.NullString `db:"bio" dbMode:"i,u"`
+	Seen   octypes.CustomTime `db:"seen" dbMode:"i,u"`
+}
+
+// TestGenerateCreateTableArrays tests array columns and nullable Scanner types
+func TestGenerateCreateTableArrays(t *testing.T) {
+	ctx := context.Background()
+	InitModelTagCache(ddlArrayModel{}, "ddl_array_models")
+	defer ClearModelCache("ddl_array_models")
+
+	ddl, err := GenerateCreateTable("ddl_array_models")
+	if err != nil {
+		t.Fatalf("GenerateCreateTable failed: %v", err)
+	}
+	for _, want := range []string{
+		`"tags" text[],`,
+		`"scores" bigint[],`,
+		`"owners" uuid[],`,
+		`"bio" text,`,
+		`"seen" timestamptz`,
+	} {
+		if !strings.Contains(ddl, want) {
+			t.Errorf("Expected %q in:\n%s", want, ddl)
+		}
+	}
+	if strings.Contains(ddl, "jsonb") || strings.Contains(ddl, `"seen" timestamptz NOT NULL`) {
+		t.Errorf("Expected arrays and nullable Scanner columns, got:\n%s", ddl)
+	}
+
+	if _, err := SafeExec(ddl); err != nil {
+		t.Fatalf("Generated DDL failed: %v", err)
+	}
+	defer SafeExec(`DROP TABLE IF EXISTS ddl_array_models`)
+
+	row := ddlArrayModel{
+		UUID:   GenNewUUID(""),
+		Tags:   []string{"a", "b"},
+		Scores: []int64{1, 2},
+		Owners: []uuid.UUID{uuid.New()},
+	}
+	values, err := StructToInsertMap(row)
+	if err != nil {
+		t.Fatalf("StructToInsertMap failed: %v", err)
+	}
+	query, args := GetInsertQuery("ddl_array_models", values, "")
+	if _, err := DB.Exec(ctx, query, args...); err != nil {
+		t.Fatalf("Insert into the generated table failed: %v", err)
+	}
+
+	var fetched ddlArrayModel
+	if err := SafeGet(&fetched, SelectBase("ddl_array_models", "").Build()); err != nil {
+		t.Fatalf("Failed to read back the row: %v", err)
+	}
+	if len(fetched.Tags) != 2 || len(fetched.Scores) != 2 || len(fetched.Owners) != 1 || fetched.Bio.Valid {
+		t.Errorf("Unexpected row: %+v", fetched)
+	}
+}
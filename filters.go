@@ -3,15 +3,58 @@ package fsql
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Filter map[string]interface{}
+
+// Sort maps Go field names to ASC/DESC. Map iteration is unordered, so multi-column
+// sorts are emitted in field name order; use SortList to control the column sequence.
 type Sort map[string]string
 
+// SortField is one ORDER BY column of a SortList
+type SortField struct {
+	Field     string // Go struct field name
+	Direction string // ASC or DESC
+}
+
+// SortList is an ordered sort: ORDER BY columns are emitted in slice order
+type SortList []SortField
+
+// SortOrder is accepted wherever a sort is expected: *Sort or SortList
+type SortOrder interface {
+	sortFields() []SortField
+}
+
+// sortFields returns the map entries ordered by field name so the SQL is deterministic
+func (s *Sort) sortFields() []SortField {
+	if s == nil || len(*s) == 0 {
+		return nil
+	}
+	fields := make([]SortField, 0, len(*s))
+	for field, order := range *s {
+		fields = append(fields, SortField{Field: field, Direction: order})
+	}
+	if len(fields) > 1 {
+		slices.SortFunc(fields, func(a, b SortField) int { return strings.Compare(a.Field, b.Field) })
+	}
+	return fields
+}
+
+func (s SortList) sortFields() []SortField {
+	return s
+}
+
 // Condition operator constants - improves readability and avoids string comparisons
 const (
 	opPrefix       = "$prefix"
@@ -29,6 +72,17 @@ const (
 	opNotIn        = "$nin"
 	opEqual        = "$eq"
 	opEuroEqual    = "€eq"
+	opSince        = "$since"
+	opRecent       = "$recent"
+	opBetween      = "$between"
+	opIsNotEqual   = "$isne"
+	opIsEqual      = "$iseq"
+	opJSONEqual    = "$jsoneq"
+	opJSONContains = "$jsoncontains"
+
+	// filterKeyOr maps to a slice of sub-filters ORed together: Filter{"$or": []Filter{...}},
+	// or []map[string]*Filter{...} keyed by table for an OR across joined tables
+	filterKeyOr = "$or"
 )
 
 // Operator to SQL condition mapping - faster lookup than switch statement
@@ -48,9 +102,44 @@ var operatorConditions = map[string]string{
 	opNotIn:        `!= ALL($%d)`,
 	opEqual:        `= $%d`,
 	opEuroEqual:    `= $%d`,
+	opSince:        `>= NOW() - $%d::interval`,
+	opRecent:       `>= NOW() - $%d::interval`,
+	opBetween:      `BETWEEN $%d AND $%d`,
+	opIsNotEqual:   `IS DISTINCT FROM $%d`,
+	opIsEqual:      `IS NOT DISTINCT FROM $%d`,
+	opJSONEqual:    `= $%d`,
+	opJSONContains: `@> $%d::jsonb`,
 	"":             `= $%d`, // Default case
 }
 
+// EmptyInPolicy decides how a $in filter with an empty slice is handled
+type EmptyInPolicy int32
+
+const (
+	// EmptyInMatchesNone keeps the condition, so an empty set matches no rows (default)
+	EmptyInMatchesNone EmptyInPolicy = iota
+	// EmptyInError makes FilterQuery fail with ErrEmptyInFilter
+	EmptyInError
+	// EmptyInIgnores drops the condition, as if the filter was not set
+	EmptyInIgnores
+)
+
+// ErrEmptyInFilter is returned for an empty $in slice under EmptyInError
+var ErrEmptyInFilter = errors.New("empty $in filter")
+
+// emptyInPolicy holds the current EmptyInPolicy
+var emptyInPolicy int32
+
+// SetEmptyInPolicy sets how $in filters with an empty slice are handled
+func SetEmptyInPolicy(policy EmptyInPolicy) {
+	atomic.StoreInt32(&emptyInPolicy, int32(policy))
+}
+
+// GetEmptyInPolicy returns how $in filters with an empty slice are handled
+func GetEmptyInPolicy() EmptyInPolicy {
+	return EmptyInPolicy(atomic.LoadInt32(&emptyInPolicy))
+}
+
 // Reusable pools for string building operations
 var (
 	filterConditionBuilderPool = sync.Pool{
@@ -61,127 +150,394 @@ var (
 		},
 	}
 	
+	// Conditions are transient (joined into the query string) so they are pooled.
+	// Args are NOT pooled: they are handed to the caller and outlive the call.
 	filterConditionsPool = sync.Pool{
 		New: func() interface{} {
 			return make([]string, 0, 8) // Typical number of conditions
 		},
 	}
-	
-	filterArgsPool = sync.Pool{
-		New: func() interface{} {
-			return make([]interface{}, 0, 8) // Typical number of args
-		},
-	}
 )
 
+// constructConditions builds WHERE conditions and their args for filters.
+// The returned conditions slice comes from filterConditionsPool and should be Put back once
+// joined into the query; the args slice is freshly allocated and owned by the caller.
 func constructConditions(t string, filters *Filter, table string) ([]string, []interface{}, error) {
+	return constructConditionsFrom(t, filters, table, 1)
+}
+
+// constructConditionsFrom builds conditions numbering placeholders from startArg
+func constructConditionsFrom(t string, filters *Filter, table string, startArg int) ([]string, []interface{}, error) {
 	modelInfo, ok := getModelInfo(table)
 	if !ok {
 		return nil, nil, fmt.Errorf("table name not initialized: %s", table)
 	}
 
-	// Get pre-allocated slices from pools
+	if filters == nil || len(*filters) == 0 {
+		return nil, nil, nil
+	}
+
+	// Conditions come from the pool; args escape to the caller so they're allocated exactly
 	conditions := filterConditionsPool.Get().([]string)
 	conditions = conditions[:0] // Reset slice without allocating
-	
-	args := filterArgsPool.Get().([]interface{})
-	args = args[:0] // Reset slice without allocating
-	
+	args := make([]interface{}, 0, len(*filters))
+
 	// Reusable string builder
 	sb := filterConditionBuilderPool.Get().(*strings.Builder)
 	defer filterConditionBuilderPool.Put(sb)
 	
 	// Counter for parameter placeholders
-	argCounter := 1
+	argCounter := startArg
+
+	// Pre-build the quote+table part once
+	quotedTable := `"` + t + `"`
+	
+	for filterKey, filterValue := range *filters {
+		// OR groups recurse with the shared placeholder counter
+		if filterKey == filterKeyOr {
+			condition, groupArgs, err := orGroupCondition(t, filterValue, table, argCounter)
+			if err != nil {
+				filterConditionsPool.Put(conditions)
+				return nil, nil, err
+			}
+			if condition != "" {
+				conditions = append(conditions, condition)
+				args = append(args, groupArgs...)
+				argCounter += len(groupArgs)
+			}
+			continue
+		}
 
-	if filters != nil && len(*filters) > 0 {
-		// Pre-allocate enough capacity for the expected number of conditions
-		if cap(conditions) < len(*filters) {
-			newConditions := make([]string, 0, len(*filters))
-			copy(newConditions, conditions)
-			conditions = newConditions
+		// Parse filter key more efficiently
+		var fieldName, operator string
+		bracketIdx := strings.IndexByte(filterKey, '[')
+		if bracketIdx >= 0 {
+			fieldName = filterKey[:bracketIdx]
+			// Use len-1 to trim the closing bracket too
+			operator = filterKey[bracketIdx+1 : len(filterKey)-1]
+		} else {
+			fieldName = filterKey
+			// Default case - empty operator means equals
+			operator = ""
 		}
-		
-		if cap(args) < len(*filters) {
-			newArgs := make([]interface{}, 0, len(*filters))
-			copy(newArgs, args)
-			args = newArgs
+
+		dbField, exists := modelInfo.dbTagMap[fieldName]
+		if !exists {
+			continue
 		}
-		
-		// Pre-build the quote+table part once
-		quotedTable := `"` + t + `"`
-		
-		for filterKey, filterValue := range *filters {
-			// Parse filter key more efficiently
-			var fieldName, operator string
-			bracketIdx := strings.IndexByte(filterKey, '[')
-			if bracketIdx >= 0 {
-				fieldName = filterKey[:bracketIdx]
-				// Use len-1 to trim the closing bracket too
-				operator = filterKey[bracketIdx+1 : len(filterKey)-1]
-			} else {
-				fieldName = filterKey
-				// Default case - empty operator means equals
-				operator = ""
+
+		// JSONB operators address a key of the column: Settings[$jsoneq:model]
+		if jsonOp, _, _ := strings.Cut(operator, ":"); jsonOp == opJSONEqual || jsonOp == opJSONContains {
+			condition, arg, err := jsonFilterCondition(quotedTable+"."+dbField, operator, filterValue, argCounter)
+			if err != nil {
+				filterConditionsPool.Put(conditions)
+				return nil, nil, fmt.Errorf("filter %s: %w", filterKey, err)
 			}
+			conditions = append(conditions, condition)
+			args = append(args, arg)
+			argCounter++
+			continue
+		}
 
-			dbField, exists := modelInfo.dbTagMap[fieldName]
-			if !exists {
+		// Empty IN sets follow the configured policy
+		if operator == opIn && isEmptySlice(filterValue) {
+			switch GetEmptyInPolicy() {
+			case EmptyInError:
+				filterConditionsPool.Put(conditions)
+				return nil, nil, fmt.Errorf("filter %s: %w", filterKey, ErrEmptyInFilter)
+			case EmptyInIgnores:
 				continue
 			}
+		}
 
-			// Get condition string from pre-built map
-			conditionStr, exists := operatorConditions[operator]
-			if !exists {
-				// Default to equals if not found
-				conditionStr = operatorConditions[""]
+		// Get condition string from pre-built map
+		conditionStr, exists := operatorConditions[operator]
+		if !exists {
+			// Default to equals if not found
+			conditionStr = operatorConditions[""]
+		}
+
+		// Relative time ranges bind an interval instead of the raw value
+		if operator == opSince || operator == opRecent {
+			if err := checkTimestampField(modelInfo, dbField); err != nil {
+				filterConditionsPool.Put(conditions)
+				return nil, nil, err
 			}
+			interval, err := relativeIntervalArg(operator, filterValue)
+			if err != nil {
+				filterConditionsPool.Put(conditions)
+				return nil, nil, err
+			}
+			filterValue = interval
+		}
 
-			// Check if we need to use LOWER() for case-insensitive search
-			shouldLower := strings.HasPrefix(operator, "€")
-			
-			// Build the condition string
+		// Ranges consume two placeholders
+		if operator == opBetween {
+			low, high, err := betweenBounds(filterValue)
+			if err != nil {
+				filterConditionsPool.Put(conditions)
+				return nil, nil, fmt.Errorf("filter %s: %w", filterKey, err)
+			}
 			sb.Reset()
-			
-			if shouldLower {
-				sb.WriteString("LOWER(")
-				sb.WriteString(quotedTable)
-				sb.WriteByte('.')
-				sb.WriteString(dbField)
-				sb.WriteString(") ")
-				sb.WriteString(conditionStr)
-				
-				// Convert string values to lowercase for case-insensitive search
-				if strVal, ok := filterValue.(string); ok {
-					filterValue = strings.ToLower(strVal)
-				}
-			} else {
-				sb.WriteString(quotedTable)
-				sb.WriteByte('.')
-				sb.WriteString(dbField)
-				sb.WriteByte(' ')
-				sb.WriteString(conditionStr)
+			sb.WriteString(quotedTable)
+			sb.WriteByte('.')
+			sb.WriteString(dbField)
+			sb.WriteByte(' ')
+			sb.WriteString(conditionStr)
+			conditions = append(conditions, fmt.Sprintf(sb.String(), argCounter, argCounter+1))
+			args = append(args, low, high)
+			argCounter += 2
+			continue
+		}
+
+		// NULL-safe comparisons against nil don't need a placeholder
+		if (operator == opIsNotEqual || operator == opIsEqual) && isNilValue(filterValue) {
+			nullCheck := " IS NULL"
+			if operator == opIsNotEqual {
+				nullCheck = " IS NOT NULL"
 			}
-			
-			// Format the parameter placeholder
-			condition := fmt.Sprintf(sb.String(), argCounter)
-			conditions = append(conditions, condition)
+			conditions = append(conditions, quotedTable+"."+dbField+nullCheck)
+			continue
+		}
 
-			// pgx handles arrays natively - no wrapping needed
-			args = append(args, filterValue)
-			argCounter++
+		// Check if we need to use LOWER() for case-insensitive search
+		shouldLower := strings.HasPrefix(operator, "€")
+		
+		// Build the condition string
+		sb.Reset()
+		
+		if shouldLower {
+			sb.WriteString("LOWER(")
+			sb.WriteString(quotedTable)
+			sb.WriteByte('.')
+			sb.WriteString(dbField)
+			sb.WriteString(") ")
+			sb.WriteString(conditionStr)
+			
+			// Convert string values to lowercase for case-insensitive search
+			if strVal, ok := filterValue.(string); ok {
+				filterValue = strings.ToLower(strVal)
+			}
+		} else {
+			sb.WriteString(quotedTable)
+			sb.WriteByte('.')
+			sb.WriteString(dbField)
+			sb.WriteByte(' ')
+			sb.WriteString(conditionStr)
 		}
+		
+		// Format the parameter placeholder
+		condition := fmt.Sprintf(sb.String(), argCounter)
+		conditions = append(conditions, condition)
+
+		// pgx handles arrays natively - no wrapping needed
+		args = append(args, filterValue)
+		argCounter++
 	}
 
-	// Wrap slices in a defer to return them to pool after they're used
 	return conditions, args, nil
 }
 
-// sortClausePool provides reusable slice for sort clauses to reduce allocations
-var sortClausePool = sync.Pool{
-	New: func() interface{} {
-		return make([]string, 0, 4) // Typical number of sort fields
-	},
+// jsonFilterCondition builds a JSONB condition on column from "$jsoneq:key" or "$jsoncontains[:key]":
+// $jsoneq compares the key's text (column->>'key' = $n) and requires a key;
+// $jsoncontains tests containment of the column, or of its key (column->'key' @> $n::jsonb).
+// Non-string $jsoncontains values are encoded as JSON.
+func jsonFilterCondition(column, operator string, value interface{}, argN int) (string, interface{}, error) {
+	operator, key, hasKey := strings.Cut(operator, ":")
+	if hasKey && key == "" {
+		return "", nil, fmt.Errorf("%s has an empty key", operator)
+	}
+
+	if operator == opJSONEqual {
+		if !hasKey {
+			return "", nil, fmt.Errorf("%s requires a key, e.g. [%s:name]", operator, opJSONEqual)
+		}
+		return fmt.Sprintf("%s->>%s %s", column, jsonKeyLiteral(key), fmt.Sprintf(operatorConditions[opJSONEqual], argN)), value, nil
+	}
+
+	switch v := value.(type) {
+	case string:
+	case []byte:
+		value = string(v)
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to encode %s value: %w", operator, err)
+		}
+		value = string(encoded)
+	}
+	if hasKey {
+		column = column + "->" + jsonKeyLiteral(key)
+	}
+	return column + " " + fmt.Sprintf(operatorConditions[opJSONContains], argN), value, nil
+}
+
+// jsonKeyLiteral quotes a JSON object key as a SQL string literal
+func jsonKeyLiteral(key string) string {
+	return "'" + strings.ReplaceAll(key, "'", "''") + "'"
+}
+
+// orGroupCondition builds "(a OR (b AND c))" from the sub-filters of a $or key.
+// Each sub-filter's own conditions are ANDed. An empty sub-filter matches everything,
+// so the whole group is dropped and "" is returned. Sub-filters given as
+// []map[string]*Filter are keyed by table like FilterQueryTables, so a group can span joins.
+func orGroupCondition(t string, value interface{}, table string, startArg int) (string, []interface{}, error) {
+	var groups []*Filter
+	switch v := value.(type) {
+	case []map[string]*Filter:
+		return orTablesCondition(v, startArg)
+	case []Filter:
+		for i := range v {
+			groups = append(groups, &v[i])
+		}
+	case []*Filter:
+		groups = v
+	case []map[string]interface{}:
+		for _, m := range v {
+			f := Filter(m)
+			groups = append(groups, &f)
+		}
+	default:
+		return "", nil, fmt.Errorf("%s expects a slice of filters, got %T", filterKeyOr, value)
+	}
+	if len(groups) == 0 {
+		return "", nil, nil
+	}
+
+	alternatives := make([]string, 0, len(groups))
+	var args []interface{}
+	for _, group := range groups {
+		groupConditions, groupArgs, err := constructConditionsFrom(t, group, table, startArg+len(args))
+		if err != nil {
+			return "", nil, err
+		}
+		if len(groupConditions) == 0 {
+			return "", nil, nil
+		}
+
+		alternative := strings.Join(groupConditions, " AND ")
+		if len(groupConditions) > 1 {
+			alternative = "(" + alternative + ")"
+		}
+		filterConditionsPool.Put(groupConditions)
+
+		alternatives = append(alternatives, alternative)
+		args = append(args, groupArgs...)
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
+
+// orTablesCondition is orGroupCondition for sub-filters keyed by "table" or "table:alias"
+func orTablesCondition(branches []map[string]*Filter, startArg int) (string, []interface{}, error) {
+	if len(branches) == 0 {
+		return "", nil, nil
+	}
+
+	alternatives := make([]string, 0, len(branches))
+	var args []interface{}
+	for _, branch := range branches {
+		branchConditions, branchArgs, err := tablesConditions(branch, startArg+len(args))
+		if err != nil {
+			return "", nil, err
+		}
+		if len(branchConditions) == 0 {
+			return "", nil, nil
+		}
+
+		alternative := strings.Join(branchConditions, " AND ")
+		if len(branchConditions) > 1 {
+			alternative = "(" + alternative + ")"
+		}
+		alternatives = append(alternatives, alternative)
+		args = append(args, branchArgs...)
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
+
+// isEmptySlice reports whether v is a slice or array with no elements (nil slices included)
+func isEmptySlice(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Len() == 0
+}
+
+// isNilValue reports whether v is nil or a typed nil pointer
+func isNilValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// betweenBounds extracts the low and high bounds of a $between value (a two-element slice)
+func betweenBounds(value interface{}) (interface{}, interface{}, error) {
+	if bounds, ok := value.([]interface{}); ok {
+		if len(bounds) != 2 {
+			return nil, nil, fmt.Errorf("$between expects a two-element slice, got %d elements", len(bounds))
+		}
+		return bounds[0], bounds[1], nil
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, nil, fmt.Errorf("$between expects a two-element slice, got %T", value)
+	}
+	if v.Len() != 2 {
+		return nil, nil, fmt.Errorf("$between expects a two-element slice, got %d elements", v.Len())
+	}
+	return v.Index(0).Interface(), v.Index(1).Interface(), nil
+}
+
+// relativeIntervalArg converts a $since/$recent filter value to a Postgres interval string
+// $since takes a time.Duration (or an interval string like "2 hours"), $recent takes a number of days
+func relativeIntervalArg(operator string, value interface{}) (string, error) {
+	if operator == opSince {
+		switch v := value.(type) {
+		case time.Duration:
+			return fmt.Sprintf("%d microseconds", v.Microseconds()), nil
+		case string:
+			return v, nil
+		}
+		return "", fmt.Errorf("%s expects a time.Duration or interval string, got %T", operator, value)
+	}
+
+	switch v := value.(type) {
+	case int:
+		return fmt.Sprintf("%d days", v), nil
+	case int32:
+		return fmt.Sprintf("%d days", v), nil
+	case int64:
+		return fmt.Sprintf("%d days", v), nil
+	case float64:
+		return fmt.Sprintf("%g days", v), nil
+	}
+	return "", fmt.Errorf("%s expects a number of days, got %T", operator, value)
+}
+
+// checkTimestampField rejects relative time filters on columns whose Go type is clearly not a
+// time (strings, numbers, bools). time.Time-convertible and sql.Scanner types (sql.NullTime,
+// octypes.CustomTime, pgtype.Timestamptz, ...) are accepted, pointers included.
+func checkTimestampField(modelInfo *modelInfo, dbField string) error {
+	fieldType, ok := modelInfo.fieldTypes[dbField]
+	if !ok {
+		return nil
+	}
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.ConvertibleTo(timeType) || reflect.PtrTo(fieldType).Implements(scannerType) {
+		return nil
+	}
+	switch fieldType.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Errorf("relative time filter on non-timestamp column: %s", dbField)
+	}
+	return nil
 }
 
 // queryBuilderPool provides reusable string builders for query construction
@@ -193,12 +549,95 @@ var queryBuilderPool = sync.Pool{
 	},
 }
 
-func FilterQuery(baseQuery string, t string, filters *Filter, sort *Sort, table string, perPage int, page int) (string, []interface{}, error) {
-	conditions, args, err := constructConditions(t, filters, table)
+// FilterQuery appends filters, sort and pagination to baseQuery.
+// perPage <= 0 disables pagination (no LIMIT/OFFSET), see FilterQueryAll.
+func FilterQuery(baseQuery string, t string, filters *Filter, sort SortOrder, table string, perPage int, page int) (string, []interface{}, error) {
+	return filterQueryWithArgs(baseQuery, t, filters, sort, table, perPage, page, nil)
+}
+
+// filterQueryWithArgs is FilterQuery for a base query that already binds baseArgs;
+// filter placeholders are numbered after them and baseArgs lead the returned args
+func filterQueryWithArgs(baseQuery string, t string, filters *Filter, sort SortOrder, table string, perPage int, page int, baseArgs []interface{}) (string, []interface{}, error) {
+	conditions, args, err := constructConditionsFrom(t, filters, table, len(baseArgs)+1)
 	if err != nil {
 		return "", nil, err
 	}
-	
+	if len(baseArgs) > 0 {
+		args = append(append(make([]interface{}, 0, len(baseArgs)+len(args)), baseArgs...), args...)
+	}
+
+	query, err := buildFilteredQuery(baseQuery, t, conditions, sort, table, perPage, page)
+
+	// If conditions slice was from pool, return it
+	if conditions != nil {
+		filterConditionsPool.Put(conditions)
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+	return query, args, nil
+}
+
+// FilterQueryAll is FilterQuery without pagination: filters and ORDER BY only (e.g. exports)
+func FilterQueryAll(baseQuery string, t string, filters *Filter, sort SortOrder, table string) (string, []interface{}, error) {
+	return FilterQuery(baseQuery, t, filters, sort, table, 0, 0)
+}
+
+// FilterQueryTables is FilterQuery for joined queries, with filters grouped per table.
+// Each key is a registered table name, or "table:alias" when the table is joined under an alias
+// (e.g. "realm:r" for LEFT JOIN realm AS r); field names are resolved against that table's model
+// and conditions reference the alias. All conditions are ANDed; to OR across tables, give any
+// table's filter a "$or" of []map[string]*Filter branches keyed the same way. Sort is resolved
+// against table.
+func FilterQueryTables(baseQuery string, t string, filters map[string]*Filter, sort SortOrder, table string, perPage int, page int) (string, []interface{}, error) {
+	conditions, args, err := tablesConditions(filters, 1)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query, err := buildFilteredQuery(baseQuery, t, conditions, sort, table, perPage, page)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, args, nil
+}
+
+// tablesConditions builds the conditions of per-table filters keyed by "table" or "table:alias",
+// numbering placeholders from startArg
+func tablesConditions(filters map[string]*Filter, startArg int) ([]string, []interface{}, error) {
+	// Sort keys so placeholder numbering is stable
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var conditions []string
+	var args []interface{}
+
+	for _, key := range keys {
+		filterTable, alias := key, key
+		if idx := strings.IndexByte(key, ':'); idx >= 0 {
+			filterTable, alias = key[:idx], key[idx+1:]
+		}
+
+		tableConditions, tableArgs, err := constructConditionsFrom(alias, filters[key], filterTable, startArg+len(args))
+		if err != nil {
+			return nil, nil, err
+		}
+		conditions = append(conditions, tableConditions...)
+		args = append(args, tableArgs...)
+
+		if tableConditions != nil {
+			filterConditionsPool.Put(tableConditions)
+		}
+	}
+	return conditions, args, nil
+}
+
+// buildFilteredQuery appends WHERE conditions, ORDER BY and pagination to baseQuery
+func buildFilteredQuery(baseQuery string, t string, conditions []string, sort SortOrder, table string, perPage int, page int) (string, error) {
 	// Get a string builder from pool
 	sb := queryBuilderPool.Get().(*strings.Builder)
 	defer queryBuilderPool.Put(sb)
@@ -220,84 +659,26 @@ func FilterQuery(baseQuery string, t string, filters *Filter, sort *Sort, table
 		}
 	}
 
-	// Process sort if present
-	if sort != nil && len(*sort) > 0 {
-		// Get sort clauses array from pool
-		sortClauses := sortClausePool.Get().([]string)
-		sortClauses = sortClauses[:0] // Reset without allocating
-		
-		// Use a local string builder for each sort clause
-		clauseSb := filterConditionBuilderPool.Get().(*strings.Builder)
-		
-		// Get model info once
-		modelInfo, _ := getModelInfo(table)
-		quotedTable := `"` + t + `"`
-
-		// Ensure capacity
-		if cap(sortClauses) < len(*sort) {
-			newSortClauses := make([]string, 0, len(*sort))
-			copy(newSortClauses, sortClauses)
-			sortClauses = newSortClauses
-		}
-
-		for field, order := range *sort {
-			// Fast check for valid order
-			order = strings.ToUpper(order)
-			if order != "ASC" && order != "DESC" {
-				// Clean up pooled resources
-				filterConditionBuilderPool.Put(clauseSb)
-				sortClausePool.Put(sortClauses)
-				return "", nil, fmt.Errorf("invalid sort order: %s", order)
-			}
-			
-			dbField, exists := modelInfo.dbTagMap[field]
-			if exists {
-				// Build sort clause efficiently
-				clauseSb.Reset()
-				clauseSb.WriteString(quotedTable)
-				clauseSb.WriteByte('.')
-				clauseSb.WriteString(dbField)
-				clauseSb.WriteByte(' ')
-				clauseSb.WriteString(order)
-				
-				sortClauses = append(sortClauses, clauseSb.String())
-			}
-		}
-		
-		// Return clause builder to pool
-		filterConditionBuilderPool.Put(clauseSb)
-
-		// Add ORDER BY if there are sort clauses
-		if len(sortClauses) > 0 {
-			sb.WriteString(" ORDER BY ")
-			
-			// Join sort clauses
-			for i, clause := range sortClauses {
-				if i > 0 {
-					sb.WriteString(", ")
-				}
-				sb.WriteString(clause)
-			}
-		}
-		
-		// Return sort clauses to pool
-		sortClausePool.Put(sortClauses)
+	// Add ORDER BY if a sort is present
+	if err := writeSortClause(sb, sort, t, table); err != nil {
+		return "", err
 	}
 
-	// Add pagination
-	limit := perPage
-	offset := (page - 1) * perPage
-	sb.WriteString(" LIMIT ")
-	sb.WriteString(fmt.Sprint(limit))
-	sb.WriteString(" OFFSET ")
-	sb.WriteString(fmt.Sprint(offset))
+	// Add pagination (perPage <= 0 fetches all rows)
+	writePagination(sb, perPage, page)
 
-	// If conditions slice was from pool, return it
-	if conditions != nil {
-		filterConditionsPool.Put(conditions)
-	}
+	return sb.String(), nil
+}
 
-	return sb.String(), args, nil
+// writePagination appends LIMIT/OFFSET unless perPage <= 0
+func writePagination(sb *strings.Builder, perPage int, page int) {
+	if perPage <= 0 {
+		return
+	}
+	sb.WriteString(" LIMIT ")
+	sb.WriteString(strconv.Itoa(perPage))
+	sb.WriteString(" OFFSET ")
+	sb.WriteString(strconv.Itoa((page - 1) * perPage))
 }
 
 // Pre-compiled regular expressions for query parsing
@@ -390,68 +771,88 @@ func indexCaseInsensitive(s, substr string) int {
 	return -1
 }
 
-// GetSortCondition builds a sort condition clause from a Sort map
-func GetSortCondition(sort *Sort, table string) (string, error) {
-	if sort == nil || len(*sort) == 0 {
-		return "", nil
-	}
-	
-	// Get pre-allocated resources from pools
-	sortClauses := sortClausePool.Get().([]string)
-	sortClauses = sortClauses[:0] // Reset without allocating
-	defer sortClausePool.Put(sortClauses)
-	
+// GetSortCondition builds a sort condition clause from a Sort map or SortList
+func GetSortCondition(sort SortOrder, table string) (string, error) {
 	sb := filterConditionBuilderPool.Get().(*strings.Builder)
 	defer filterConditionBuilderPool.Put(sb)
-	
+
+	sb.Reset()
+	if err := writeSortClause(sb, sort, table, table); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// writeSortClause appends " ORDER BY ..." for sort, with columns qualified by t and
+// field names resolved against table. Fields unknown to the model are skipped.
+func writeSortClause(sb *strings.Builder, sort SortOrder, t string, table string) error {
+	if sort == nil {
+		return nil
+	}
+	fields := sort.sortFields()
+	if len(fields) == 0 {
+		return nil
+	}
+
 	// Get model info once
 	modelInfo, _ := getModelInfo(table)
-	quotedTable := `"` + table + `"`
-	
-	// Ensure capacity
-	if cap(sortClauses) < len(*sort) {
-		newSortClauses := make([]string, 0, len(*sort))
-		copy(newSortClauses, sortClauses)
-		sortClauses = newSortClauses
-	}
-	
-	for field, order := range *sort {
+	quotedTable := `"` + t + `"`
+
+	written := false
+	for _, sf := range fields {
 		// Fast check for valid order
-		order = strings.ToUpper(order)
+		order := strings.ToUpper(sf.Direction)
 		if order != "ASC" && order != "DESC" {
-			return "", fmt.Errorf("invalid sort order: %s", order)
+			return fmt.Errorf("invalid sort order: %s", order)
 		}
-		
-		dbField, exists := modelInfo.dbTagMap[field]
-		if exists {
-			// Build sort clause efficiently
-			sb.Reset()
-			sb.WriteString(quotedTable)
-			sb.WriteByte('.')
-			sb.WriteString(dbField)
-			sb.WriteByte(' ')
-			sb.WriteString(order)
-			
-			sortClauses = append(sortClauses, sb.String())
+
+		dbField, exists := modelInfo.dbTagMap[sf.Field]
+		if !exists {
+			continue
+		}
+		if written {
+			sb.WriteString(", ")
+		} else {
+			sb.WriteString(" ORDER BY ")
+			written = true
 		}
+		sb.WriteString(quotedTable)
+		sb.WriteByte('.')
+		sb.WriteString(dbField)
+		sb.WriteByte(' ')
+		sb.WriteString(order)
 	}
-	
-	// Generate final sort clause
-	if len(sortClauses) > 0 {
-		sb.Reset()
-		sb.WriteString(" ORDER BY ")
-		
-		for i, clause := range sortClauses {
-			if i > 0 {
-				sb.WriteString(", ")
-			}
-			sb.WriteString(clause)
+	return nil
+}
+
+// OrderByField returns a validated ORDER BY item ("table"."column" ASC|DESC) for a sort
+// column chosen by API users: field must be a Go field name or column of modelTable's model
+// and direction ASC or DESC (any case). Pass the result to QueryBuilder.OrderBy or FilterQueryCustom.
+func OrderByField(modelTable, field, direction string) (string, error) {
+	modelInfo, ok := getModelInfo(modelTable)
+	if !ok {
+		return "", fmt.Errorf("table name not initialized: %s", modelTable)
+	}
+
+	order := strings.ToUpper(direction)
+	if order != "ASC" && order != "DESC" {
+		return "", fmt.Errorf("invalid sort order: %s", direction)
+	}
+
+	column, ok := modelInfo.dbTagMap[field]
+	if !ok {
+		if _, isColumn := modelInfo.fieldTypes[field]; !isColumn {
+			return "", fmt.Errorf("unknown sort field %s for table %s", field, modelTable)
 		}
-		
-		return sb.String(), nil
+		column = field
 	}
-	
-	return "", nil
+	return modelInfo.quotedTableName + "." + modelInfo.quotedFields[column] + " " + order, nil
+}
+
+// BuildExistsQuery wraps baseQuery in SELECT EXISTS(...), which stops at the first row
+// instead of counting them all
+func BuildExistsQuery(baseQuery string) string {
+	return "SELECT EXISTS(SELECT 1 FROM (" + baseQuery + ") AS exists_subquery)"
 }
 
 // GetFilterCount executes a count query and returns the result
@@ -472,13 +873,8 @@ func FilterQueryCustom(baseQuery string, t string, orderBy string, args []interf
 	sb.WriteString(" ORDER BY ")
 	sb.WriteString(orderBy)
 	
-	// Add pagination
-	limit := perPage
-	offset := (page - 1) * perPage
-	sb.WriteString(" LIMIT ")
-	sb.WriteString(fmt.Sprint(limit))
-	sb.WriteString(" OFFSET ")
-	sb.WriteString(fmt.Sprint(offset))
+	// Add pagination (perPage <= 0 fetches all rows)
+	writePagination(sb, perPage, page)
 	
 	return sb.String(), args, nil
 }
@@ -514,3 +910,91 @@ func BuildFilterCountCustom(baseQuery string) string {
 	sb.WriteString(selectCountSuffix)
 	return sb.String()
 }
+
+// KeysetCursor is the position after the last row of a keyset page whose sort column
+// isn't unique: Value is that row's sort value and Key its primary key, which breaks ties.
+type KeysetCursor struct {
+	Value interface{}
+	Key   interface{}
+}
+
+// KeysetPaginate appends filters and a keyset page to baseQuery: rows after lastValue in
+// sortField order (WHERE "t".col > $n ORDER BY "t".col LIMIT pageSize), so deep pages cost the
+// same as the first one, unlike LIMIT/OFFSET. sortField is a Go field name resolved through the
+// model, direction is ASC or DESC, and a nil lastValue fetches the first page.
+// When the table has a primary key it is appended to ORDER BY so ordering is stable; if the sort
+// column isn't unique, pass a KeysetCursor as lastValue so rows sharing a value aren't skipped.
+// The sort column should be NOT NULL: NULL values never satisfy the cursor condition.
+func KeysetPaginate(baseQuery string, t string, filters *Filter, table string, sortField string, direction string, lastValue interface{}, pageSize int) (string, []interface{}, error) {
+	modelInfo, ok := getModelInfo(table)
+	if !ok {
+		return "", nil, fmt.Errorf("table name not initialized: %s", table)
+	}
+	dbField, ok := modelInfo.dbTagMap[sortField]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown sort field %s for table %s", sortField, table)
+	}
+
+	direction = strings.ToUpper(direction)
+	comparison := ">"
+	switch direction {
+	case "ASC":
+	case "DESC":
+		comparison = "<"
+	default:
+		return "", nil, fmt.Errorf("invalid sort order: %s", direction)
+	}
+
+	quotedTable := `"` + t + `"`
+	sortColumn := quotedTable + "." + dbField
+	tieColumn := ""
+	if pk := modelInfo.primaryKey; pk != "" && pk != dbField {
+		tieColumn = quotedTable + "." + pk
+	}
+
+	conditions, args, err := constructConditionsFrom(t, filters, table, 1)
+	if err != nil {
+		return "", nil, err
+	}
+	if conditions != nil {
+		defer filterConditionsPool.Put(conditions)
+	}
+
+	if !isNilValue(lastValue) {
+		next := len(args) + 1
+		if cursor, ok := lastValue.(KeysetCursor); ok {
+			if tieColumn == "" {
+				return "", nil, fmt.Errorf("keyset cursor needs a primary key for table %s", table)
+			}
+			conditions = append(conditions, fmt.Sprintf("(%s, %s) %s ($%d, $%d)", sortColumn, tieColumn, comparison, next, next+1))
+			args = append(args, cursor.Value, cursor.Key)
+		} else {
+			conditions = append(conditions, fmt.Sprintf("%s %s $%d", sortColumn, comparison, next))
+			args = append(args, lastValue)
+		}
+	}
+
+	query, err := buildFilteredQuery(baseQuery, t, conditions, nil, table, 0, 0)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(query)
+	sb.WriteString(" ORDER BY ")
+	sb.WriteString(sortColumn)
+	sb.WriteByte(' ')
+	sb.WriteString(direction)
+	if tieColumn != "" {
+		sb.WriteString(", ")
+		sb.WriteString(tieColumn)
+		sb.WriteByte(' ')
+		sb.WriteString(direction)
+	}
+	if pageSize > 0 {
+		sb.WriteString(" LIMIT ")
+		sb.WriteString(strconv.Itoa(pageSize))
+	}
+
+	return sb.String(), args, nil
+}
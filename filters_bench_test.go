@@ -14,7 +14,8 @@ func BenchmarkConstructConditions(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _, _ = constructConditions("ai_model", filters, "ai_model")
+		conditions, _, _ := constructConditions("ai_model", filters, "ai_model")
+		filterConditionsPool.Put(conditions)
 	}
 }
 
@@ -34,6 +35,25 @@ func BenchmarkFilterQuery(b *testing.B) {
 	}
 }
 
+// BenchmarkFilterQueryParallel benchmarks filter query building under contention on the pools
+func BenchmarkFilterQueryParallel(b *testing.B) {
+	filters := &Filter{
+		"Type":     "test_type",
+		"Provider": "test_provider",
+	}
+	sort := &Sort{
+		"Key": "ASC",
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _, _ = FilterQuery(aiModelBaseQuery, "ai_model", filters, sort, "ai_model", 10, 1)
+		}
+	})
+}
+
 // BenchmarkBuildFilterCount benchmarks count query generation
 func BenchmarkBuildFilterCount(b *testing.B) {
 	query := aiModelBaseQuery + ` WHERE "ai_model".type = $1 ORDER BY "ai_model".key ASC LIMIT 10 OFFSET 0`
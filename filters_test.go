@@ -1,9 +1,16 @@
 package fsql
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"math"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/coffyg/octypes"
 )
 
 func TestBuildFilterCountCustom(t *testing.T) {
@@ -159,3 +166,911 @@ func TestFilterQuery(t *testing.T) {
 		t.Errorf("Expected total count 30, got %d", count)
 	}
 }
+
+// TestSortList tests that SortList emits ORDER BY columns in the given sequence
+func TestSortList(t *testing.T) {
+	cleanDatabase(t)
+
+	for i, provider := range []string{"b", "a", "b", "a"} {
+		model := AIModel{Key: fmt.Sprintf("sort_key_%d", i), Type: "sort_type", Provider: provider}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	sort := SortList{{Field: "Provider", Direction: "ASC"}, {Field: "Key", Direction: "DESC"}}
+	orderBy, err := GetSortCondition(sort, "ai_model")
+	if err != nil {
+		t.Fatalf("GetSortCondition error: %v", err)
+	}
+	if orderBy != ` ORDER BY "ai_model".provider ASC, "ai_model".key DESC` {
+		t.Errorf("Unexpected ORDER BY: %s", orderBy)
+	}
+
+	query, args, err := FilterQuery(aiModelBaseQuery, "ai_model", &Filter{"Type": "sort_type"}, sort, "ai_model", 10, 1)
+	if err != nil {
+		t.Fatalf("FilterQuery error: %v", err)
+	}
+	var models []AIModel
+	if err := Db.Select(&models, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	expected := []string{"sort_key_3", "sort_key_1", "sort_key_2", "sort_key_0"}
+	for i, model := range models {
+		if i >= len(expected) || model.Key != expected[i] {
+			t.Fatalf("Unexpected order at %d: %s", i, model.Key)
+		}
+	}
+
+	// Multi-column Sort maps are emitted in field name order
+	for i := 0; i < 10; i++ {
+		orderBy, err = GetSortCondition(&Sort{"Provider": "ASC", "Key": "DESC"}, "ai_model")
+		if err != nil || orderBy != ` ORDER BY "ai_model".key DESC, "ai_model".provider ASC` {
+			t.Fatalf("Unexpected ORDER BY for Sort: %s (%v)", orderBy, err)
+		}
+	}
+
+	if _, err := GetSortCondition(SortList{{Field: "Key", Direction: "sideways"}}, "ai_model"); err == nil {
+		t.Error("Expected error for an invalid sort order")
+	}
+}
+
+// TestOrderByField tests validating user-chosen sort columns
+func TestOrderByField(t *testing.T) {
+	cleanDatabase(t)
+
+	for _, key := range []string{"order_b", "order_a", "order_c"} {
+		model := AIModel{Key: key, Type: "order_type", Provider: "order_provider"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	clause, err := OrderByField("ai_model", "Key", "desc")
+	if err != nil {
+		t.Fatalf("OrderByField failed: %v", err)
+	}
+	if clause != `"ai_model"."key" DESC` {
+		t.Errorf("Unexpected clause: %s", clause)
+	}
+
+	query, args, err := FilterQueryCustom(aiModelBaseQuery+` WHERE "ai_model".type = $1`, "ai_model", clause, []interface{}{"order_type"}, 10, 1)
+	if err != nil {
+		t.Fatalf("FilterQueryCustom error: %v", err)
+	}
+	var models []AIModel
+	if err := Db.Select(&models, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(models) != 3 || models[0].Key != "order_c" || models[2].Key != "order_a" {
+		t.Errorf("Unexpected order: %+v", models)
+	}
+
+	// Column names work too
+	if clause, err := OrderByField("ai_model", "provider", "ASC"); err != nil || clause != `"ai_model"."provider" ASC` {
+		t.Errorf("Unexpected clause for a column name: %s (%v)", clause, err)
+	}
+
+	invalid := [][3]string{
+		{"ai_model", "key; DROP TABLE ai_model", "ASC"},
+		{"ai_model", "Key", "ASC; DROP TABLE ai_model"},
+		{"not_registered", "Key", "ASC"},
+	}
+	for _, in := range invalid {
+		if _, err := OrderByField(in[0], in[1], in[2]); err == nil {
+			t.Errorf("Expected error for %v", in)
+		}
+	}
+}
+
+// TestFromRawFilterQuery tests appending filters and sort to a hand-written base query
+func TestFromRawFilterQuery(t *testing.T) {
+	cleanDatabase(t)
+
+	for i := 1; i <= 15; i++ {
+		model := AIModel{
+			Key:      fmt.Sprintf("raw_key_%02d", i),
+			Type:     "raw_type",
+			Provider: "raw_provider",
+		}
+		if i > 10 {
+			model.Type = "other_type"
+		}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	qb := FromRaw(`SELECT "ai_model".uuid, "ai_model".key, "ai_model".type FROM "ai_model"`, "ai_model")
+
+	query, args, err := qb.FilterQuery(&Filter{"Type": "raw_type"}, &Sort{"Key": "DESC"}, 5, 1)
+	if err != nil {
+		t.Fatalf("FilterQuery error: %v", err)
+	}
+
+	var models []AIModel
+	if err := Db.Select(&models, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+
+	if len(models) != 5 {
+		t.Fatalf("Expected 5 models, got %d", len(models))
+	}
+	if models[0].Key != "raw_key_10" {
+		t.Errorf("Expected first key 'raw_key_10', got '%s'", models[0].Key)
+	}
+
+	count, err := GetFilterCount(BuildFilterCount(query), args)
+	if err != nil {
+		t.Fatalf("GetFilterCount error: %v", err)
+	}
+	if count != 10 {
+		t.Errorf("Expected count 10, got %d", count)
+	}
+
+	orderBy, err := qb.SortCondition(&Sort{"Key": "ASC"})
+	if err != nil {
+		t.Fatalf("SortCondition error: %v", err)
+	}
+	if orderBy != ` ORDER BY "ai_model".key ASC` {
+		t.Errorf("Unexpected sort condition: %s", orderBy)
+	}
+}
+
+// TestCursorRoundTrip tests encoding/decoding cursors and resuming pagination from one
+func TestCursorRoundTrip(t *testing.T) {
+	cleanDatabase(t)
+
+	for i := 1; i <= 12; i++ {
+		model := AIModel{
+			Key:      fmt.Sprintf("cursor_key_%02d", i),
+			Type:     "cursor_type",
+			Provider: "cursor_provider",
+		}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	query, args, err := FilterQuery(aiModelBaseQuery, "ai_model", &Filter{"Type": "cursor_type"}, &Sort{"Key": "ASC"}, "ai_model", 5, 1)
+	if err != nil {
+		t.Fatalf("FilterQuery error: %v", err)
+	}
+	var firstPage []AIModel
+	if err := Db.Select(&firstPage, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+
+	token, err := EncodeCursor(&firstPage[len(firstPage)-1], []string{"Key"})
+	if err != nil {
+		t.Fatalf("EncodeCursor error: %v", err)
+	}
+
+	values, err := DecodeCursor(token)
+	if err != nil {
+		t.Fatalf("DecodeCursor error: %v", err)
+	}
+	if values["Key"] != "cursor_key_05" {
+		t.Fatalf("Expected cursor Key 'cursor_key_05', got %v", values["Key"])
+	}
+
+	// Resume from the cursor: the next page starts right after the last seen key
+	query, args, err = FilterQuery(aiModelBaseQuery, "ai_model", &Filter{
+		"Type":     "cursor_type",
+		"Key[$gt]": values["Key"],
+	}, &Sort{"Key": "ASC"}, "ai_model", 5, 1)
+	if err != nil {
+		t.Fatalf("FilterQuery error: %v", err)
+	}
+	var secondPage []AIModel
+	if err := Db.Select(&secondPage, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(secondPage) != 5 || secondPage[0].Key != "cursor_key_06" {
+		t.Errorf("Unexpected second page: %d rows, first key %v", len(secondPage), secondPage)
+	}
+
+	// Type fidelity for ints, timestamps, nil pointers and bools
+	ts := time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)
+	row := struct {
+		ID      int
+		Created time.Time
+		Name    *string
+		Active  bool
+	}{ID: 42, Created: ts, Active: true}
+
+	token, err = EncodeCursor(row, []string{"ID", "Created", "Name", "Active"})
+	if err != nil {
+		t.Fatalf("EncodeCursor error: %v", err)
+	}
+	values, err = DecodeCursor(token)
+	if err != nil {
+		t.Fatalf("DecodeCursor error: %v", err)
+	}
+	if values["ID"] != int64(42) {
+		t.Errorf("Expected ID int64(42), got %#v", values["ID"])
+	}
+	if created, ok := values["Created"].(time.Time); !ok || !created.Equal(ts) {
+		t.Errorf("Expected Created %v, got %#v", ts, values["Created"])
+	}
+	if values["Name"] != nil {
+		t.Errorf("Expected nil Name, got %#v", values["Name"])
+	}
+	if values["Active"] != true {
+		t.Errorf("Expected Active true, got %#v", values["Active"])
+	}
+
+	if _, err := DecodeCursor("not a cursor!"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+// TestRelativeTimeFilters tests the $since and $recent operators
+func TestRelativeTimeFilters(t *testing.T) {
+	cleanDatabase(t)
+
+	for i := 1; i <= 3; i++ {
+		insertRealm(t, Realm{UUID: GenNewUUID(""), Name: fmt.Sprintf("Recent Realm %d", i)})
+	}
+	old := Realm{UUID: GenNewUUID(""), Name: "Old Realm"}
+	insertRealm(t, old)
+	if _, err := Db.Exec(`UPDATE realm SET created_at = NOW() - INTERVAL '10 days' WHERE uuid = $1`, old.UUID); err != nil {
+		t.Fatalf("Failed to age realm: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filters  *Filter
+		expected int
+	}{
+		{"since duration", &Filter{"CreatedAt[$since]": 24 * time.Hour}, 3},
+		{"since interval string", &Filter{"CreatedAt[$since]": "30 days"}, 4},
+		{"recent days", &Filter{"CreatedAt[$recent]": 7}, 3},
+		{"recent days wide", &Filter{"CreatedAt[$recent]": 11}, 4},
+	}
+
+	for _, tt := range tests {
+		query, args, err := FilterQuery(realmBaseQuery, "realm", tt.filters, nil, "realm", 50, 1)
+		if err != nil {
+			t.Fatalf("%s: FilterQuery error: %v", tt.name, err)
+		}
+		var realms []Realm
+		if err := Db.Select(&realms, query, args...); err != nil {
+			t.Fatalf("%s: Select error: %v", tt.name, err)
+		}
+		if len(realms) != tt.expected {
+			t.Errorf("%s: expected %d realms, got %d", tt.name, tt.expected, len(realms))
+		}
+	}
+
+	if _, _, err := FilterQuery(realmBaseQuery, "realm", &Filter{"Name[$since]": time.Hour}, nil, "realm", 50, 1); err == nil {
+		t.Error("Expected error for relative filter on a non-timestamp column")
+	}
+	if _, _, err := FilterQuery(realmBaseQuery, "realm", &Filter{"CreatedAt[$recent]": "seven"}, nil, "realm", 50, 1); err == nil {
+		t.Error("Expected error for non-numeric $recent value")
+	}
+
+	// Time and Scanner columns qualify, pointers included; strings don't, whatever the type is called
+	InitModelTagCache(timeZoneSetting{}, "time_zone_setting")
+	defer ClearModelCache("time_zone_setting")
+	if _, _, err := FilterQuery(`SELECT * FROM "time_zone_setting"`, "time_zone_setting", &Filter{"Zone[$since]": time.Hour}, nil, "time_zone_setting", 50, 1); err == nil {
+		t.Error("Expected error for relative filter on a non-timestamp type named *Time*")
+	}
+	for _, field := range []string{"ChangedAt", "SeenAt", "ExpiresAt", "ArchivedAt", "VisitedAt"} {
+		if _, _, err := FilterQuery(`SELECT * FROM "time_zone_setting"`, "time_zone_setting", &Filter{field + "[$recent]": 7}, nil, "time_zone_setting", 50, 1); err != nil {
+			t.Errorf("Expected relative filter on %s to be accepted, got %v", field, err)
+		}
+	}
+}
+
+// TimeZoneName is a string type whose name mentions Time
+type TimeZoneName string
+
+// timeZoneSetting has a non-timestamp *Time* column next to accepted timestamp types
+type timeZoneSetting struct {
+	Zone       TimeZoneName       `db:"zone"`
+	ChangedAt  time.Time          `db:"changed_at"`
+	SeenAt     *time.Time         `db:"seen_at"`
+	ExpiresAt  sql.NullTime       `db:"expires_at"`
+	ArchivedAt *sql.NullTime      `db:"archived_at"`
+	VisitedAt  octypes.CustomTime `db:"visited_at"`
+}
+
+// TestFilterQueryTables tests filtering on joined-table columns
+func TestFilterQueryTables(t *testing.T) {
+	cleanDatabase(t)
+
+	realmOne := Realm{UUID: GenNewUUID(""), Name: "Realm One"}
+	realmTwo := Realm{UUID: GenNewUUID(""), Name: "Realm Two"}
+	insertRealm(t, realmOne)
+	insertRealm(t, realmTwo)
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "example.com", RealmUUID: realmOne.UUID})
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "sample.org", RealmUUID: realmOne.UUID})
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "test.com", RealmUUID: realmTwo.UUID})
+
+	filters := map[string]*Filter{
+		"realm:r": {"Name": "Realm One"},
+		"website": {"Domain[$suffix]": "%.com"},
+	}
+	sort := &Sort{"Domain": "ASC"}
+
+	query, args, err := FilterQueryTables(websiteBaseQuery, "website", filters, sort, "website", 10, 1)
+	if err != nil {
+		t.Fatalf("FilterQueryTables error: %v", err)
+	}
+
+	if !strings.Contains(query, `"r".name = $1`) || !strings.Contains(query, `"website".domain LIKE $2`) {
+		t.Errorf("Expected aliased conditions in query, got: %s", query)
+	}
+	if len(args) != 2 || args[0] != "Realm One" || args[1] != "%.com" {
+		t.Errorf("Unexpected args: %v", args)
+	}
+
+	var websites []Website
+	if err := Db.Select(&websites, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(websites) != 1 || websites[0].Domain != "example.com" {
+		t.Fatalf("Expected only example.com, got %+v", websites)
+	}
+	if websites[0].Realm == nil || websites[0].Realm.Name != "Realm One" {
+		t.Errorf("Expected linked Realm One, got %+v", websites[0].Realm)
+	}
+
+	// Realm-only filter lists every website of that realm
+	query, args, err = FilterQueryTables(websiteBaseQuery, "website", map[string]*Filter{"realm:r": {"Name": "Realm One"}}, nil, "website", 10, 1)
+	if err != nil {
+		t.Fatalf("FilterQueryTables error: %v", err)
+	}
+	websites = nil
+	if err := Db.Select(&websites, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(websites) != 2 {
+		t.Errorf("Expected 2 websites in Realm One, got %d", len(websites))
+	}
+
+	// OR across the joined tables: Realm One's websites or test.com
+	orFilters := map[string]*Filter{
+		"website": {
+			"$or": []map[string]*Filter{
+				{"realm:r": {"Name": "Realm One"}},
+				{"website": {"Domain": "test.com"}},
+			},
+			"Domain[$ne]": "sample.org",
+		},
+	}
+	query, args, err = FilterQueryTables(websiteBaseQuery, "website", orFilters, sort, "website", 10, 1)
+	if err != nil {
+		t.Fatalf("FilterQueryTables error: %v", err)
+	}
+	if !strings.Contains(query, `("r".name = $`) || !strings.Contains(query, ` OR "website".domain = $`) || len(args) != 3 {
+		t.Errorf("Expected a cross-table OR, got: %s %v", query, args)
+	}
+	websites = nil
+	if err := Db.Select(&websites, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(websites) != 2 || websites[0].Domain != "example.com" || websites[1].Domain != "test.com" {
+		t.Errorf("Expected example.com and test.com, got %+v", websites)
+	}
+
+	if _, _, err := FilterQueryTables(websiteBaseQuery, "website", map[string]*Filter{"unknown:u": {"Name": "x"}}, nil, "website", 10, 1); err == nil {
+		t.Error("Expected error for unregistered table")
+	}
+}
+
+// TestFilterQueryAll tests that perPage <= 0 disables pagination
+func TestFilterQueryAll(t *testing.T) {
+	cleanDatabase(t)
+
+	for i := 1; i <= 15; i++ {
+		model := AIModel{Key: fmt.Sprintf("export_%02d", i), Type: "export", Provider: "test"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Failed to insert model: %v", err)
+		}
+	}
+	other := AIModel{Key: "other", Type: "other", Provider: "test"}
+	if err := other.Insert(); err != nil {
+		t.Fatalf("Failed to insert model: %v", err)
+	}
+
+	filters := &Filter{"Type": "export"}
+	sort := &Sort{"Key": "DESC"}
+
+	query, args, err := FilterQueryAll(aiModelBaseQuery, "ai_model", filters, sort, "ai_model")
+	if err != nil {
+		t.Fatalf("FilterQueryAll error: %v", err)
+	}
+	if strings.Contains(query, "LIMIT") || strings.Contains(query, "OFFSET") {
+		t.Errorf("Expected no pagination, got: %s", query)
+	}
+	if !strings.Contains(query, "ORDER BY") {
+		t.Errorf("Expected ORDER BY, got: %s", query)
+	}
+
+	var models []AIModel
+	if err := Db.Select(&models, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(models) != 15 {
+		t.Fatalf("Expected all 15 models, got %d", len(models))
+	}
+	if models[0].Key != "export_15" {
+		t.Errorf("Expected export_15 first, got %s", models[0].Key)
+	}
+
+	// Count still works on the unpaginated query
+	count, err := GetFilterCount(BuildFilterCount(query), args)
+	if err != nil {
+		t.Fatalf("GetFilterCount error: %v", err)
+	}
+	if count != 15 {
+		t.Errorf("Expected count 15, got %d", count)
+	}
+
+	// Same behavior through FilterQuery with perPage 0
+	paged, _, err := FilterQuery(aiModelBaseQuery, "ai_model", filters, sort, "ai_model", 0, 1)
+	if err != nil {
+		t.Fatalf("FilterQuery error: %v", err)
+	}
+	if paged != query {
+		t.Errorf("Expected perPage 0 to match FilterQueryAll:\n%s\n%s", paged, query)
+	}
+}
+
+// TestFilterQueryArgsConcurrent tests that args returned to callers are never shared across goroutines
+func TestFilterQueryArgsConcurrent(t *testing.T) {
+	const goroutines = 16
+	const iterations = 500
+
+	errs := make(chan error, goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func(g int) {
+			var held [][]interface{}
+			for i := 0; i < iterations; i++ {
+				key := fmt.Sprintf("key_%d_%d", g, i)
+				filters := &Filter{"Key": key, "Type": g}
+				_, args, err := FilterQuery(aiModelBaseQuery, "ai_model", filters, nil, "ai_model", 10, 1)
+				if err != nil {
+					errs <- err
+					return
+				}
+				held = append(held, args)
+			}
+
+			// Every args slice must still hold its own values after later calls
+			for i, args := range held {
+				key := fmt.Sprintf("key_%d_%d", g, i)
+				if len(args) != 2 {
+					errs <- fmt.Errorf("goroutine %d call %d: expected 2 args, got %v", g, i, args)
+					return
+				}
+				foundKey, foundType := false, false
+				for _, arg := range args {
+					if arg == key {
+						foundKey = true
+					}
+					if arg == g {
+						foundType = true
+					}
+				}
+				if !foundKey || !foundType {
+					errs <- fmt.Errorf("goroutine %d call %d: args corrupted: %v", g, i, args)
+					return
+				}
+			}
+			errs <- nil
+		}(g)
+	}
+
+	for g := 0; g < goroutines; g++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// TestBetweenFilter tests the $between operator
+func TestBetweenFilter(t *testing.T) {
+	cleanDatabase(t)
+
+	for i := 1; i <= 4; i++ {
+		insertRealm(t, Realm{UUID: GenNewUUID(""), Name: fmt.Sprintf("Realm %d", i)})
+	}
+	ages := map[string]string{"Realm 1": "1 hour", "Realm 2": "3 days", "Realm 3": "10 days", "Realm 4": "40 days"}
+	for name, age := range ages {
+		if _, err := Db.Exec(`UPDATE realm SET created_at = NOW() - $1::interval WHERE name = $2`, age, name); err != nil {
+			t.Fatalf("Failed to age realm: %v", err)
+		}
+	}
+
+	now := time.Now()
+	filters := &Filter{
+		"CreatedAt[$between]": []interface{}{now.AddDate(0, 0, -14), now.AddDate(0, 0, -1)},
+		"Name[$ne]":           "Realm 3",
+		"Name[$like]":         "Realm%",
+	}
+	query, args, err := FilterQuery(realmBaseQuery, "realm", filters, &Sort{"Name": "ASC"}, "realm", 10, 1)
+	if err != nil {
+		t.Fatalf("FilterQuery error: %v", err)
+	}
+	if len(args) != 4 {
+		t.Fatalf("Expected 4 args (two for the range), got %d: %v", len(args), args)
+	}
+	if !strings.Contains(query, "BETWEEN $") {
+		t.Errorf("Expected BETWEEN in query, got: %s", query)
+	}
+
+	var realms []Realm
+	if err := Db.Select(&realms, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(realms) != 1 || realms[0].Name != "Realm 2" {
+		t.Errorf("Expected only Realm 2, got %+v", realms)
+	}
+
+	// Typed slices are accepted too
+	query, args, err = FilterQuery(realmBaseQuery, "realm", &Filter{"CreatedAt[$between]": []time.Time{now.AddDate(0, 0, -14), now}}, nil, "realm", 10, 1)
+	if err != nil {
+		t.Fatalf("FilterQuery error: %v", err)
+	}
+	realms = nil
+	if err := Db.Select(&realms, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(realms) != 3 {
+		t.Errorf("Expected 3 realms in the last 14 days, got %d", len(realms))
+	}
+
+	for _, bad := range []interface{}{now, []interface{}{now}, []interface{}{1, 2, 3}} {
+		if _, _, err := FilterQuery(realmBaseQuery, "realm", &Filter{"CreatedAt[$between]": bad}, nil, "realm", 10, 1); err == nil {
+			t.Errorf("Expected error for $between value %v", bad)
+		}
+	}
+}
+
+// TestNullSafeEqualityFilters tests the $iseq and $isne operators on a nullable column
+func TestNullSafeEqualityFilters(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	ClearModelCache("user_profile")
+	InitModelTagCache(UserProfile{}, "user_profile")
+	defer ClearModelCache("user_profile")
+
+	bioA, bioB := "likes go", "likes rust"
+	profiles := []UserProfile{
+		{UUID: GenNewUUID(""), Username: "a", Bio: &bioA},
+		{UUID: GenNewUUID(""), Username: "b", Bio: &bioB},
+		{UUID: GenNewUUID(""), Username: "c"},
+	}
+	for i := range profiles {
+		if err := InsertObjectContext(ctx, &profiles[i], "user_profile"); err != nil {
+			t.Fatalf("Failed to insert profile: %v", err)
+		}
+	}
+
+	baseQuery := SelectBase("user_profile", "").Build()
+	var nilBio *string
+	tests := []struct {
+		name     string
+		filters  *Filter
+		expected []string
+	}{
+		{"iseq value", &Filter{"Bio[$iseq]": "likes go"}, []string{"a"}},
+		{"isne value keeps NULL rows", &Filter{"Bio[$isne]": "likes go"}, []string{"b", "c"}},
+		{"iseq nil", &Filter{"Bio[$iseq]": nil}, []string{"c"}},
+		{"isne nil", &Filter{"Bio[$isne]": nil}, []string{"a", "b"}},
+		{"iseq typed nil", &Filter{"Bio[$iseq]": nilBio}, []string{"c"}},
+		{"plain ne drops NULL rows", &Filter{"Bio[$ne]": "likes go"}, []string{"b"}},
+	}
+
+	for _, tt := range tests {
+		query, args, err := FilterQuery(baseQuery, "user_profile", tt.filters, &Sort{"Username": "ASC"}, "user_profile", 10, 1)
+		if err != nil {
+			t.Fatalf("%s: FilterQuery error: %v", tt.name, err)
+		}
+		var got []UserProfile
+		if err := Db.Select(&got, query, args...); err != nil {
+			t.Fatalf("%s: Select error: %v", tt.name, err)
+		}
+		names := make([]string, len(got))
+		for i, p := range got {
+			names[i] = p.Username
+		}
+		if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, names)
+		}
+	}
+}
+
+// TestOrFilterGroups tests $or sub-filters combined with regular conditions
+func TestOrFilterGroups(t *testing.T) {
+	cleanDatabase(t)
+
+	// 12 models: types a/b/c cycle, providers x/y alternate
+	for i := 1; i <= 12; i++ {
+		model := AIModel{
+			Key:      fmt.Sprintf("or_key_%02d", i),
+			Type:     []string{"a", "b", "c"}[i%3],
+			Provider: []string{"x", "y"}[i%2],
+		}
+		if i > 10 {
+			model.Key = fmt.Sprintf("other_key_%02d", i)
+		}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	// (type = a OR (type = b AND provider = x)) AND key LIKE 'or_key_%'
+	filters := &Filter{
+		"$or": []Filter{
+			{"Type": "a"},
+			{"Type": "b", "Provider": "x"},
+		},
+		"Key[$prefix]": "or_key_%",
+	}
+
+	query, args, err := FilterQuery(aiModelBaseQuery, "ai_model", filters, &Sort{"Key": "ASC"}, "ai_model", 2, 1)
+	if err != nil {
+		t.Fatalf("FilterQuery error: %v", err)
+	}
+	if !strings.Contains(query, " OR ") || len(args) != 4 {
+		t.Errorf("Expected an OR group with 4 args, got %s %v", query, args)
+	}
+
+	var models []AIModel
+	if err := Db.Select(&models, query, args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(models) != 2 {
+		t.Errorf("Expected a full page of 2 models, got %d", len(models))
+	}
+
+	// Type a: keys 3, 6, 9; type b with provider x: keys 4, 10 -> 5 rows
+	count, err := GetFilterCount(BuildFilterCount(query), args)
+	if err != nil {
+		t.Fatalf("GetFilterCount error: %v", err)
+	}
+	if count != 5 {
+		t.Errorf("Expected count 5, got %d", count)
+	}
+
+	// An empty alternative matches everything, so the group is dropped
+	query, args, err = FilterQuery(aiModelBaseQuery, "ai_model", &Filter{"$or": []Filter{{"Type": "a"}, {}}}, nil, "ai_model", 0, 0)
+	if err != nil {
+		t.Fatalf("FilterQuery error: %v", err)
+	}
+	if strings.Contains(query, "WHERE") || len(args) != 0 {
+		t.Errorf("Expected no WHERE clause, got %s %v", query, args)
+	}
+
+	if _, _, err := FilterQuery(aiModelBaseQuery, "ai_model", &Filter{"$or": "Type"}, nil, "ai_model", 10, 1); err == nil {
+		t.Error("Expected error for a non-slice $or value")
+	}
+}
+
+// TestEmptyInPolicy tests each policy for a $in filter with an empty slice
+func TestEmptyInPolicy(t *testing.T) {
+	cleanDatabase(t)
+	defer SetEmptyInPolicy(EmptyInMatchesNone)
+
+	for i := 1; i <= 3; i++ {
+		model := AIModel{Key: fmt.Sprintf("in_key_%d", i), Type: "in_type", Provider: "in_provider"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	filters := &Filter{"Key[$in]": []string{}}
+	countFor := func() (int, error) {
+		query, args, err := FilterQuery(aiModelBaseQuery, "ai_model", filters, nil, "ai_model", 10, 1)
+		if err != nil {
+			return 0, err
+		}
+		return GetFilterCount(BuildFilterCount(query), args)
+	}
+
+	if GetEmptyInPolicy() != EmptyInMatchesNone {
+		t.Fatalf("Expected EmptyInMatchesNone by default, got %v", GetEmptyInPolicy())
+	}
+	count, err := countFor()
+	if err != nil {
+		t.Fatalf("EmptyInMatchesNone: unexpected error: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("EmptyInMatchesNone: expected 0 rows, got %d", count)
+	}
+
+	SetEmptyInPolicy(EmptyInError)
+	if _, err := countFor(); !errors.Is(err, ErrEmptyInFilter) {
+		t.Errorf("EmptyInError: expected ErrEmptyInFilter, got %v", err)
+	}
+
+	SetEmptyInPolicy(EmptyInIgnores)
+	count, err = countFor()
+	if err != nil {
+		t.Fatalf("EmptyInIgnores: unexpected error: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("EmptyInIgnores: expected 3 rows, got %d", count)
+	}
+
+	// Non-empty slices are unaffected by the policy
+	filters = &Filter{"Key[$in]": []string{"in_key_1", "in_key_2"}}
+	count, err = countFor()
+	if err != nil || count != 2 {
+		t.Errorf("Expected 2 rows for a populated $in, got %d (%v)", count, err)
+	}
+}
+
+// TestKeysetPaginate tests cursor pagination with scalar and tie-breaking cursors
+func TestKeysetPaginate(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		model := AIModel{Key: fmt.Sprintf("keyset_%d", i), Type: "keyset_type", Provider: "keyset_provider"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	// First page: no cursor condition
+	query, args, err := KeysetPaginate(aiModelBaseQuery, "ai_model", &Filter{"Type": "keyset_type"}, "ai_model", "Key", "asc", nil, 2)
+	if err != nil {
+		t.Fatalf("KeysetPaginate failed: %v", err)
+	}
+	if !strings.HasSuffix(query, `WHERE "ai_model".type = $1 ORDER BY "ai_model".key ASC LIMIT 2`) || len(args) != 1 {
+		t.Errorf("Unexpected first page query: %s %v", query, args)
+	}
+
+	var keys []string
+	var last interface{}
+	for page := 0; page < 4; page++ {
+		query, args, err := KeysetPaginate(aiModelBaseQuery, "ai_model", &Filter{"Type": "keyset_type"}, "ai_model", "Key", "ASC", last, 2)
+		if err != nil {
+			t.Fatalf("KeysetPaginate page %d failed: %v", page, err)
+		}
+		var models []AIModel
+		if err := SelectMany(ctx, &models, query, args...); err != nil {
+			t.Fatalf("Select page %d failed: %v", page, err)
+		}
+		if len(models) == 0 {
+			break
+		}
+		for _, m := range models {
+			keys = append(keys, m.Key)
+		}
+		last = models[len(models)-1].Key
+	}
+	if strings.Join(keys, ",") != "keyset_1,keyset_2,keyset_3,keyset_4,keyset_5" {
+		t.Errorf("Unexpected ascending keys: %v", keys)
+	}
+
+	query, args, err = KeysetPaginate(aiModelBaseQuery, "ai_model", nil, "ai_model", "Key", "DESC", "keyset_3", 10)
+	if err != nil {
+		t.Fatalf("KeysetPaginate DESC failed: %v", err)
+	}
+	var desc []AIModel
+	if err := SelectMany(ctx, &desc, query, args...); err != nil {
+		t.Fatalf("Select DESC failed: %v", err)
+	}
+	if len(desc) != 2 || desc[0].Key != "keyset_2" || desc[1].Key != "keyset_1" {
+		t.Errorf("Unexpected descending page: %+v", desc)
+	}
+
+	if _, _, err := KeysetPaginate(aiModelBaseQuery, "ai_model", nil, "ai_model", "Missing", "ASC", nil, 2); err == nil {
+		t.Error("Expected error for unknown sort field")
+	}
+	if _, _, err := KeysetPaginate(aiModelBaseQuery, "ai_model", nil, "ai_model", "Key", "sideways", nil, 2); err == nil {
+		t.Error("Expected error for invalid direction")
+	}
+	if _, _, err := KeysetPaginate(aiModelBaseQuery, "ai_model", nil, "ai_model", "Key", "ASC", KeysetCursor{Value: "keyset_1", Key: "x"}, 2); err == nil {
+		t.Error("Expected error for a KeysetCursor on a table without a primary key")
+	}
+
+	// Non-unique sort column: the primary key breaks ties across page boundaries
+	ClearModelCache("user_profile")
+	InitModelTagCache(UserProfile{}, "user_profile")
+	defer ClearModelCache("user_profile")
+	if err := RegisterPrimaryKey("user_profile", "uuid"); err != nil {
+		t.Fatalf("RegisterPrimaryKey failed: %v", err)
+	}
+
+	for i := 0; i < 6; i++ {
+		profile := UserProfile{UUID: GenNewUUID(""), Username: fmt.Sprintf("user_%d", i), UserExperience: i / 3}
+		if err := InsertObjectContext(ctx, &profile, "user_profile"); err != nil {
+			t.Fatalf("Insert profile error: %v", err)
+		}
+	}
+
+	selectFields, _ := GetSelectFields("user_profile", "")
+	baseQuery := `SELECT ` + strings.Join(selectFields, ", ") + ` FROM "user_profile"`
+	seen := make(map[string]bool)
+	var cursor interface{}
+	for page := 0; page < 5; page++ {
+		query, args, err := KeysetPaginate(baseQuery, "user_profile", nil, "user_profile", "UserExperience", "ASC", cursor, 2)
+		if err != nil {
+			t.Fatalf("KeysetPaginate profiles failed: %v", err)
+		}
+		var profiles []UserProfile
+		if err := SelectMany(ctx, &profiles, query, args...); err != nil {
+			t.Fatalf("Select profiles failed: %v", err)
+		}
+		if len(profiles) == 0 {
+			break
+		}
+		for _, p := range profiles {
+			if seen[p.UUID] {
+				t.Errorf("Profile %s returned twice", p.Username)
+			}
+			seen[p.UUID] = true
+		}
+		lastProfile := profiles[len(profiles)-1]
+		cursor = KeysetCursor{Value: lastProfile.UserExperience, Key: lastProfile.UUID}
+	}
+	if len(seen) != 6 {
+		t.Errorf("Expected all 6 profiles across pages, got %d", len(seen))
+	}
+}
+
+// TestJSONBFilters tests $jsoneq and $jsoncontains on the ai_model.settings JSONB column
+func TestJSONBFilters(t *testing.T) {
+	cleanDatabase(t)
+
+	settings := map[string]string{
+		"json_a": `{"model": "gpt", "tags": ["fast", "cheap"], "limits": {"tier": "pro"}}`,
+		"json_b": `{"model": "llama", "tags": ["open"], "limits": {"tier": "free"}}`,
+		"json_c": `{"model": "gpt", "it's": "quoted"}`,
+	}
+	for key, value := range settings {
+		value := value
+		model := AIModel{Key: key, Type: "json_type", Provider: "json_provider", Settings: &value}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+	if err := (&AIModel{Key: "json_none", Type: "json_type", Provider: "json_provider"}).Insert(); err != nil {
+		t.Fatalf("Insert error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		filters  *Filter
+		expected []string
+	}{
+		{"jsoneq", &Filter{"Settings[$jsoneq:model]": "gpt"}, []string{"json_a", "json_c"}},
+		{"jsoneq quoted key", &Filter{"Settings[$jsoneq:it's]": "quoted"}, []string{"json_c"}},
+		{"jsoncontains string", &Filter{"Settings[$jsoncontains]": `{"limits": {"tier": "pro"}}`}, []string{"json_a"}},
+		{"jsoncontains map", &Filter{"Settings[$jsoncontains]": map[string]interface{}{"model": "llama"}}, []string{"json_b"}},
+		{"jsoncontains key", &Filter{"Settings[$jsoncontains:tags]": []string{"cheap"}}, []string{"json_a"}},
+		{"combined", &Filter{"Settings[$jsoneq:model]": "gpt", "Settings[$jsoncontains:tags]": `["fast"]`}, []string{"json_a"}},
+	}
+
+	for _, tt := range tests {
+		query, args, err := FilterQuery(aiModelBaseQuery, "ai_model", tt.filters, &Sort{"Key": "ASC"}, "ai_model", 10, 1)
+		if err != nil {
+			t.Fatalf("%s: FilterQuery error: %v", tt.name, err)
+		}
+		var got []AIModel
+		if err := Db.Select(&got, query, args...); err != nil {
+			t.Fatalf("%s: Select error: %v\n%s", tt.name, err, query)
+		}
+		keys := make([]string, len(got))
+		for i, m := range got {
+			keys[i] = m.Key
+		}
+		if strings.Join(keys, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, keys)
+		}
+	}
+
+	for _, key := range []string{"Settings[$jsoneq]", "Settings[$jsoneq:]"} {
+		if _, _, err := FilterQuery(aiModelBaseQuery, "ai_model", &Filter{key: "gpt"}, nil, "ai_model", 10, 1); err == nil {
+			t.Errorf("Expected error for %s", key)
+		}
+	}
+}
@@ -1,5 +1,5 @@
 // fsql-lite: Lightweight ORM wrapper around pgxpool
-// No database/sql, no sqlx, no replica complexity - just direct pool access
+// No database/sql, no sqlx - just direct pool access (plus optional read replicas, see replica.go)
 package fsql
 
 import (
@@ -20,8 +20,46 @@ var (
 // idleInTxTimeout is set by InitDB and used by InitDBWithPool
 var idleInTxTimeout time.Duration
 
-// InitDBWithPool initializes the global database pool with explicit pool settings
+// PreparedStatementCacheCapacity is the per-connection statement cache size of prepared pools
+var PreparedStatementCacheCapacity = 512
+
+// InitDBWithPool initializes the global database pool with explicit pool settings.
+// Queries use the simple protocol (no prepared statements), which works behind PgBouncer
+// in transaction pooling mode.
 func InitDBWithPool(databaseURL string, maxCon int, minCon int) (*pgxpool.Pool, error) {
+	return initDBWithPool(databaseURL, maxCon, minCon, false)
+}
+
+// InitDBWithPoolPrepared is InitDBWithPool using the extended protocol with a per-connection
+// statement cache: each distinct query is prepared once per connection and then re-executed
+// by name, skipping the parse/plan work and sending arguments in binary. Use it only when
+// connecting directly to Postgres (or through PgBouncer in session mode) - with transaction
+// pooling, consecutive queries can land on server connections that never saw the prepare.
+// Schema changes can also invalidate cached statements, failing their next use once.
+func InitDBWithPoolPrepared(databaseURL string, maxCon int, minCon int) (*pgxpool.Pool, error) {
+	return initDBWithPool(databaseURL, maxCon, minCon, true)
+}
+
+// initDBWithPool creates the global pool, with prepared statements when prepared is set
+func initDBWithPool(databaseURL string, maxCon int, minCon int, prepared bool) (*pgxpool.Pool, error) {
+	pool, err := newPool(databaseURL, maxCon, minCon, prepared)
+	if err != nil {
+		return nil, err
+	}
+	DB = pool
+
+	// Test connection
+	if err := DB.Ping(context.Background()); err != nil {
+		return nil, fmt.Errorf("unable to ping database: %w", err)
+	}
+
+	DbInitialised = true
+
+	return DB, nil
+}
+
+// newPool creates a pool with fsql-lite's connection settings (shared by the primary and replicas)
+func newPool(databaseURL string, maxCon int, minCon int, prepared bool) (*pgxpool.Pool, error) {
 	poolConfig, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse database URL: %w", err)
@@ -31,31 +69,33 @@ func InitDBWithPool(databaseURL string, maxCon int, minCon int) (*pgxpool.Pool,
 	poolConfig.MaxConns = int32(maxCon)
 	poolConfig.MinConns = int32(minCon)
 
-	// Use simple protocol - no prepared statements (MUST be set BEFORE creating pool)
-	poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
-	poolConfig.ConnConfig.StatementCacheCapacity = 0
+	// Use simple protocol - no prepared statements (MUST be set BEFORE creating pool),
+	// unless prepared statements were asked for
+	if prepared {
+		poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeCacheStatement
+		poolConfig.ConnConfig.StatementCacheCapacity = PreparedStatementCacheCapacity
+	} else {
+		poolConfig.ConnConfig.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+		poolConfig.ConnConfig.StatementCacheCapacity = 0
+	}
+
+	setIdleInTxTimeout := idleInTxTimeout > 0
+	poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		registerArrayTypes(conn.TypeMap())
 
-	// Set idle_in_transaction_session_timeout on each new connection if configured
-	if idleInTxTimeout > 0 {
-		poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		// Set idle_in_transaction_session_timeout on each new connection if configured
+		if setIdleInTxTimeout {
 			_, err := conn.Exec(ctx, fmt.Sprintf("SET idle_in_transaction_session_timeout = '%dms'", idleInTxTimeout.Milliseconds()))
 			return err
 		}
+		return nil
 	}
 
-	DB, err = pgxpool.NewWithConfig(context.Background(), poolConfig)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
-
-	// Test connection
-	if err := DB.Ping(context.Background()); err != nil {
-		return nil, fmt.Errorf("unable to ping database: %w", err)
-	}
-
-	DbInitialised = true
-
-	return DB, nil
+	return pool, nil
 }
 
 // CloseDB closes the global database connection
@@ -127,9 +167,110 @@ func GetPoolPressure() PoolPressure {
 	}
 }
 
-// extractTableName attempts to extract table name from SQL query for logging
+// extractTableName returns the primary table of a query for logging and metrics:
+// the FROM table of a SELECT or DELETE, the INTO table of an INSERT, or the UPDATE target.
+// A FROM subquery (QueryBuilder.Build with base WHEREs, BuildFilterCount) is searched for its own
+// table, and a schema-qualified name returns the table part. It returns "unknown" when nothing matches.
 func extractTableName(query string) string {
-	// Same logic as original fsql
-	// TODO: Copy from fsql if needed for logging
+	if table := tableFromTokens(sqlTokens(query)); table != "" {
+		return table
+	}
 	return "unknown"
 }
+
+// tableFromTokens finds the primary table of the statement in tokens, or ""
+func tableFromTokens(tokens []sqlToken) string {
+	// Skip a leading WITH ... list to the main statement's verb
+	start := 0
+	if len(tokens) > 0 && tokens[0].upper == "WITH" {
+		start = -1
+		depth := 0
+		for i, tok := range tokens {
+			switch tok.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			if i > 0 && depth == 0 {
+				switch tok.upper {
+				case "SELECT", "INSERT", "UPDATE", "DELETE":
+					start = i
+				}
+			}
+			if start >= 0 {
+				break
+			}
+		}
+		if start < 0 {
+			return ""
+		}
+	}
+	if start >= len(tokens) {
+		return ""
+	}
+
+	rest := tokens[start+1:]
+	switch tokens[start].upper {
+	case "SELECT", "DELETE":
+		return tableAfterKeyword(rest, "FROM")
+	case "INSERT":
+		return tableAfterKeyword(rest, "INTO")
+	case "UPDATE":
+		return tableName(rest)
+	}
+	return ""
+}
+
+// tableAfterKeyword returns the table following the first keyword outside parentheses
+func tableAfterKeyword(tokens []sqlToken, keyword string) string {
+	depth := 0
+	for i, tok := range tokens {
+		switch tok.text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 && tok.upper == keyword {
+			return tableName(tokens[i+1:])
+		}
+	}
+	return ""
+}
+
+// tableName reads a (possibly schema-qualified) table name, or descends into a subquery
+func tableName(tokens []sqlToken) string {
+	if len(tokens) > 0 && tokens[0].upper == "ONLY" {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	if tokens[0].text == "(" {
+		// Subquery: use its own table, up to the matching parenthesis
+		depth := 0
+		for i, tok := range tokens {
+			switch tok.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+			}
+			if depth == 0 {
+				return tableFromTokens(tokens[1:i])
+			}
+		}
+		return tableFromTokens(tokens[1:])
+	}
+
+	if !tokens[0].quoted && tokens[0].upper == "" {
+		return ""
+	}
+	name := tokens[0].text
+	for i := 1; i+1 < len(tokens) && tokens[i].text == "." && (tokens[i+1].quoted || tokens[i+1].upper != ""); i += 2 {
+		name = tokens[i+1].text
+	}
+	return name
+}
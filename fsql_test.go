@@ -3,16 +3,20 @@ package fsql
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"math"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/coffyg/octypes"
+	"github.com/jackc/pgx/v5"
 )
 
 // JSONSettings implements sql.Scanner for JSONB testing
@@ -112,7 +116,10 @@ func TestMain(m *testing.M) {
 }
 
 func cleanDatabase(t *testing.T) {
-	_, err := Db.Exec(`TRUNCATE TABLE ai_model, website, realm, user_profile RESTART IDENTITY CASCADE`)
+	err := Truncate(context.Background(), []string{"ai_model", "website", "realm", "user_profile", "tagged_item"}, TruncateOptions{
+		Cascade:         true,
+		RestartIdentity: true,
+	})
 	if err != nil {
 		t.Fatalf("Failed to clean database: %v", err)
 	}
@@ -227,14 +234,6 @@ func TestListAIModel(t *testing.T) {
 	}
 }
 
-// Pagination struct matching original fsql pattern
-type Pagination struct {
-	ResultsPerPage int
-	PageNo         int
-	Count          int
-	PageMax        int
-}
-
 // ListAIModel lists AI models - matching original fsql pattern
 func ListAIModel(filters *Filter, sort *Sort, perPage int, page int) (*[]AIModel, *Pagination, error) {
 	if sort == nil || len(*sort) == 0 {
@@ -396,6 +395,61 @@ func TestQueryBuilderWhereAndJoin(t *testing.T) {
 	}
 }
 
+// TestQueryBuilderStructuredWhere tests conditions whose placeholders are numbered by Build
+func TestQueryBuilderStructuredWhere(t *testing.T) {
+	cleanDatabase(t)
+
+	realm1 := Realm{UUID: GenNewUUID(""), Name: "Realm One"}
+	realm2 := Realm{UUID: GenNewUUID(""), Name: "Realm Two"}
+	insertRealm(t, realm1)
+	insertRealm(t, realm2)
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "example.com", RealmUUID: realm1.UUID})
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "test.com", RealmUUID: realm2.UUID})
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "sample.com", RealmUUID: realm1.UUID})
+
+	query, args := SelectBase("website", "").
+		WhereIn("domain", "example.com", "test.com", "sample.com").
+		WhereNotNull("realm_uuid").
+		Left("realm", "r", "website.realm_uuid = r.uuid").
+		WhereEq("r.name", "Realm One").
+		WhereNotEq("Domain", "sample.com").
+		BuildWithArgs()
+	if !strings.Contains(query, `"website"."domain" IN ($1, $2, $3)`) || !strings.Contains(query, `"r"."name" = $4 AND "website"."domain" <> $5`) {
+		t.Errorf("Unexpected placeholders: %s", query)
+	}
+	if len(args) != 5 {
+		t.Fatalf("Expected 5 args, got %v", args)
+	}
+
+	websites := []Website{}
+	if err := Db.Select(&websites, query, args...); err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+	if len(websites) != 1 || websites[0].Domain != "example.com" || websites[0].Realm == nil || websites[0].Realm.Name != "Realm One" {
+		t.Errorf("Expected only example.com in Realm One, got %+v", websites)
+	}
+
+	// Empty IN matches nothing; nil compares with IS NULL
+	query, args = SelectBase("website", "").WhereIn("uuid").BuildWithArgs()
+	if err := Db.Select(&websites, query, args...); err != nil {
+		t.Fatalf("Failed to execute empty IN: %v", err)
+	}
+	if len(websites) != 0 {
+		t.Errorf("Expected no websites for an empty IN, got %d", len(websites))
+	}
+	query, args = SelectBase("website", "").WhereEq("realm_uuid", nil).BuildWithArgs()
+	if !strings.Contains(query, `"website"."realm_uuid" IS NULL`) || len(args) != 0 {
+		t.Errorf("Expected IS NULL without args, got %s %v", query, args)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for an unknown column")
+		}
+	}()
+	SelectBase("website", "").WhereEq("no_such_column", 1)
+}
+
 // Helper functions to insert Realm and Website
 func insertRealm(t *testing.T, realm Realm) {
 	query, args := GetInsertQuery("realm", map[string]interface{}{
@@ -1270,3 +1324,2019 @@ func TestNullInt64EdgeCases(t *testing.T) {
 	}
 	t.Logf("COUNT with matches: value=%d valid=%v", countMatch.Int64, countMatch.Valid)
 }
+
+// TestInsertUpdateObjectContext tests struct insert/update against the pool without a transaction
+func TestInsertUpdateObjectContext(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	ClearModelCache("user_profile")
+	InitModelTagCache(UserProfile{}, "user_profile")
+	defer ClearModelCache("user_profile")
+
+	profile := UserProfile{
+		UUID:           GenNewUUID(""),
+		Username:       "object_user",
+		UserExperience: 10,
+	}
+
+	if err := InsertObjectContext(ctx, &profile, "user_profile"); err != nil {
+		t.Fatalf("InsertObjectContext failed: %v", err)
+	}
+
+	var fetched UserProfile
+	query := "SELECT uuid, username, bio, user_experience, follower_count, following_count FROM user_profile WHERE uuid = $1"
+	if err := Db.Get(&fetched, query, profile.UUID); err != nil {
+		t.Fatalf("Failed to fetch profile: %v", err)
+	}
+	if fetched.Username != "object_user" || fetched.UserExperience != 10 {
+		t.Errorf("Unexpected profile after insert: %+v", fetched)
+	}
+
+	profile.Username = "object_user_updated"
+	profile.UserExperience = 42
+	if err := UpdateObjectContext(ctx, &profile, "user_profile", "uuid = $1", profile.UUID); err != nil {
+		t.Fatalf("UpdateObjectContext failed: %v", err)
+	}
+
+	if err := Db.Get(&fetched, query, profile.UUID); err != nil {
+		t.Fatalf("Failed to fetch profile: %v", err)
+	}
+	if fetched.Username != "object_user_updated" {
+		t.Errorf("Expected username 'object_user_updated', got '%s'", fetched.Username)
+	}
+	if fetched.UserExperience != 42 {
+		t.Errorf("Expected UserExperience 42, got %d", fetched.UserExperience)
+	}
+
+	// Every struct insert shares InsertObject's rules: zero fields with a dbInsertValue take the default
+	realms := []Realm{{UUID: GenNewUUID(""), Name: "Context Realm"}, {UUID: GenNewUUID(""), Name: "Tx Realm"}}
+	if err := InsertObjectContext(ctx, &realms[0], "realm"); err != nil {
+		t.Fatalf("InsertObjectContext failed for realm: %v", err)
+	}
+	if err := WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+		return InsertObjectWithTxContext(ctx, tx, &realms[1], "realm")
+	}); err != nil {
+		t.Fatalf("InsertObjectWithTxContext failed for realm: %v", err)
+	}
+	for _, realm := range realms {
+		var created Realm
+		if err := Db.Get(&created, realmBaseQuery+` WHERE "realm".uuid = $1`, realm.UUID); err != nil {
+			t.Fatalf("Failed to fetch realm: %v", err)
+		}
+		if created.Name != realm.Name || created.CreatedAt.IsZero() {
+			t.Errorf("Expected %s with a NOW() created_at, got %+v", realm.Name, created)
+		}
+	}
+}
+
+// TestTruncate tests truncating multiple tables with cascade
+func TestTruncate(t *testing.T) {
+	cleanDatabase(t)
+
+	realm := Realm{UUID: GenNewUUID(""), Name: "Truncate Realm"}
+	insertRealm(t, realm)
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "truncate.com", RealmUUID: realm.UUID})
+
+	// Truncating realm alone must cascade to website because of the foreign key
+	err := Truncate(context.Background(), []string{"realm"}, TruncateOptions{Cascade: true})
+	if err != nil {
+		t.Fatalf("Truncate failed: %v", err)
+	}
+
+	var count int
+	if err := Db.Get(&count, "SELECT COUNT(*) FROM website"); err != nil {
+		t.Fatalf("Failed to count websites: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected 0 websites after cascade, got %d", count)
+	}
+
+	if err := Truncate(context.Background(), []string{"realm", ""}, TruncateOptions{}); err == nil {
+		t.Error("Expected error for empty table name")
+	}
+	if err := Truncate(context.Background(), nil, TruncateOptions{}); err == nil {
+		t.Error("Expected error for empty table list")
+	}
+}
+
+// TestModelMetadata tests reading back registered model metadata
+func TestModelMetadata(t *testing.T) {
+	meta, ok := ModelMetadata("ai_model")
+	if !ok {
+		t.Fatal("Expected ai_model metadata to be registered")
+	}
+
+	if len(meta.SelectColumns) != 8 {
+		t.Errorf("Expected 8 select columns, got %d: %v", len(meta.SelectColumns), meta.SelectColumns)
+	}
+	if len(meta.InsertColumns) != 8 {
+		t.Errorf("Expected 8 insert columns, got %d: %v", len(meta.InsertColumns), meta.InsertColumns)
+	}
+	if len(meta.UpdateColumns) != 7 {
+		t.Errorf("Expected 7 update columns, got %d: %v", len(meta.UpdateColumns), meta.UpdateColumns)
+	}
+	for _, col := range meta.UpdateColumns {
+		if col == "uuid" {
+			t.Error("uuid should not be an update column")
+		}
+	}
+	if meta.FieldColumns["DefaultNegativePrompt"] != "default_negative_prompt" {
+		t.Errorf("Unexpected column for DefaultNegativePrompt: %s", meta.FieldColumns["DefaultNegativePrompt"])
+	}
+	if meta.InsertDefaults["name"] != "NULL" {
+		t.Errorf("Expected NULL insert default for name, got %q", meta.InsertDefaults["name"])
+	}
+
+	// Mutating the copy must not affect the registered model
+	meta.FieldColumns["Key"] = "changed"
+	again, _ := ModelMetadata("ai_model")
+	if again.FieldColumns["Key"] != "key" {
+		t.Error("ModelMetadata should return a copy")
+	}
+
+	website, ok := ModelMetadata("website")
+	if !ok {
+		t.Fatal("Expected website metadata to be registered")
+	}
+	if website.LinkedFields["Realm"] != "r" {
+		t.Errorf("Expected linked field Realm -> r, got %v", website.LinkedFields)
+	}
+
+	if _, ok := ModelMetadata("does_not_exist"); ok {
+		t.Error("Expected no metadata for unregistered table")
+	}
+}
+
+// RealmWithWebsiteCount is a realm row plus a computed aggregate column
+type RealmWithWebsiteCount struct {
+	Realm
+	WebsiteCount int `db:"website_count"`
+}
+
+func TestScanAggregateColumn(t *testing.T) {
+	cleanDatabase(t)
+
+	realmOne := Realm{UUID: GenNewUUID(""), Name: "Realm One"}
+	realmTwo := Realm{UUID: GenNewUUID(""), Name: "Realm Two"}
+	insertRealm(t, realmOne)
+	insertRealm(t, realmTwo)
+	for i := 0; i < 3; i++ {
+		insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: fmt.Sprintf("one-%d.com", i), RealmUUID: realmOne.UUID})
+	}
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "two.com", RealmUUID: realmTwo.UUID})
+
+	query := `SELECT realm.*, COUNT(website.uuid) AS website_count
+		FROM realm LEFT JOIN website ON website.realm_uuid = realm.uuid
+		GROUP BY realm.uuid ORDER BY realm.name`
+
+	var rows []RealmWithWebsiteCount
+	if err := Db.Select(&rows, query); err != nil {
+		t.Fatalf("Failed to select aggregate rows: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+	if rows[0].UUID != realmOne.UUID || rows[0].WebsiteCount != 3 {
+		t.Errorf("Expected %s with 3 websites, got %s with %d", realmOne.UUID, rows[0].UUID, rows[0].WebsiteCount)
+	}
+	if rows[1].Name != "Realm Two" || rows[1].WebsiteCount != 1 {
+		t.Errorf("Expected Realm Two with 1 website, got %s with %d", rows[1].Name, rows[1].WebsiteCount)
+	}
+	if rows[0].CreatedAt.IsZero() {
+		t.Error("Expected model fields to be scanned alongside the aggregate")
+	}
+
+	// Single-row scan of the same type with the same columns
+	var single RealmWithWebsiteCount
+	if err := Db.Get(&single, query+" LIMIT 1"); err != nil {
+		t.Fatalf("Failed to get aggregate row: %v", err)
+	}
+	if single.WebsiteCount != 3 {
+		t.Errorf("Expected 3 websites, got %d", single.WebsiteCount)
+	}
+
+	// Plain query on the same type leaves the computed field untouched
+	var plain []RealmWithWebsiteCount
+	if err := Db.Select(&plain, `SELECT realm.* FROM realm ORDER BY name`); err != nil {
+		t.Fatalf("Failed to select plain rows: %v", err)
+	}
+	if len(plain) != 2 || plain[0].WebsiteCount != 0 {
+		t.Errorf("Expected 2 rows without website_count, got %+v", plain)
+	}
+}
+
+func TestBuildCountWithArgs(t *testing.T) {
+	cleanDatabase(t)
+
+	realmOne := Realm{UUID: GenNewUUID(""), Name: "Realm One"}
+	realmTwo := Realm{UUID: GenNewUUID(""), Name: "Realm Two"}
+	insertRealm(t, realmOne)
+	insertRealm(t, realmTwo)
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "a.com", RealmUUID: realmOne.UUID})
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "b.com", RealmUUID: realmOne.UUID})
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "c.org", RealmUUID: realmOne.UUID})
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "d.com", RealmUUID: realmTwo.UUID})
+
+	qb := SelectBase("website", "").
+		WhereArgs(`"website".domain LIKE $1`, "%.com").
+		Left("realm", "r", "website.realm_uuid = r.uuid").
+		WhereArgs("r.name = $1", "Realm One")
+
+	query := qb.Build()
+	if !strings.Contains(query, "r.name = $2") {
+		t.Errorf("Expected second WhereArgs placeholder shifted to $2, got: %s", query)
+	}
+
+	var websites []Website
+	if err := Db.Select(&websites, query, qb.Args()...); err != nil {
+		t.Fatalf("Failed to select websites: %v", err)
+	}
+	if len(websites) != 2 {
+		t.Errorf("Expected 2 websites, got %d", len(websites))
+	}
+
+	countQuery, countArgs := qb.BuildCountWithArgs()
+	count, err := GetFilterCount(countQuery, countArgs)
+	if err != nil {
+		t.Fatalf("Failed to count websites: %v", err)
+	}
+	if count != len(websites) {
+		t.Errorf("Expected count %d to match selected rows, got %d", len(websites), count)
+	}
+
+	// Builder args lead the args of appended filters
+	realmQB := SelectBase("realm", "").WhereArgs(`"realm".name LIKE $1`, "Realm%")
+	filtered, args, err := realmQB.FilterQuery(&Filter{"Name[$ne]": "Realm Two"}, nil, 10, 1)
+	if err != nil {
+		t.Fatalf("FilterQuery error: %v", err)
+	}
+	if len(args) != 2 || args[0] != "Realm%" || args[1] != "Realm Two" {
+		t.Errorf("Unexpected args: %v", args)
+	}
+	var realms []Realm
+	if err := Db.Select(&realms, filtered, args...); err != nil {
+		t.Fatalf("Failed to select realms: %v", err)
+	}
+	if len(realms) != 1 || realms[0].Name != "Realm One" {
+		t.Errorf("Expected only Realm One, got %+v", realms)
+	}
+	count, err = GetFilterCount(BuildFilterCount(filtered), args)
+	if err != nil {
+		t.Fatalf("Failed to count realms: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected count 1, got %d", count)
+	}
+}
+
+// RealmWithPK declares its key column with dbMode "pk"
+type RealmWithPK struct {
+	UUID string `db:"uuid" dbMode:"i,pk"`
+	Name string `db:"name" dbMode:"i,u"`
+}
+
+func TestInsertUpdateDefault(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	// Tag-based registration
+	InitModelTagCache(RealmWithPK{}, "realm_pk_tag")
+	if pk, ok := PrimaryKey("realm_pk_tag"); !ok || pk != "uuid" {
+		t.Errorf("Expected pk uuid from tag, got %q (%v)", pk, ok)
+	}
+
+	// Explicit registration
+	if err := RegisterPrimaryKey("realm", "uuid"); err != nil {
+		t.Fatalf("RegisterPrimaryKey failed: %v", err)
+	}
+	if err := RegisterPrimaryKey("realm", "missing"); err == nil {
+		t.Error("Expected error for unknown column")
+	}
+	if err := RegisterPrimaryKey("not_registered", "uuid"); err == nil {
+		t.Error("Expected error for unregistered table")
+	}
+	if meta, _ := ModelMetadata("realm"); meta.PrimaryKey != "uuid" {
+		t.Errorf("Expected metadata pk uuid, got %q", meta.PrimaryKey)
+	}
+
+	realmUUID := GenNewUUID("")
+	values := map[string]interface{}{"uuid": realmUUID, "name": "Default Realm"}
+	if err := InsertDefault(ctx, "realm", values); err != nil {
+		t.Fatalf("InsertDefault failed: %v", err)
+	}
+	if values["uuid"] == nil {
+		t.Error("Expected returned uuid stored in values")
+	}
+
+	if err := UpdateDefault(ctx, "realm", map[string]interface{}{"uuid": realmUUID, "name": "Renamed Realm"}); err != nil {
+		t.Fatalf("UpdateDefault failed: %v", err)
+	}
+
+	var realm Realm
+	if err := Db.Get(&realm, realmBaseQuery+` WHERE "realm".uuid = $1`, realmUUID); err != nil {
+		t.Fatalf("Failed to fetch realm: %v", err)
+	}
+	if realm.Name != "Renamed Realm" {
+		t.Errorf("Expected Renamed Realm, got %s", realm.Name)
+	}
+
+	if err := InsertDefault(ctx, "ai_model", map[string]interface{}{"key": "k"}); err == nil {
+		t.Error("Expected error for table without primary key")
+	}
+}
+
+// TestStructToMaps tests building insert/update value maps from dbMode tags
+func TestStructToMaps(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	name := "Mapped Model"
+	model := AIModel{UUID: GenNewUUID(""), Key: "mapped_key", Name: &name, Type: "mapped_type", Provider: "mapped_provider"}
+	values, err := StructToInsertMap(&model)
+	if err != nil {
+		t.Fatalf("StructToInsertMap failed: %v", err)
+	}
+	if values["uuid"] != model.UUID || values["key"] != "mapped_key" || values["name"] != &name {
+		t.Errorf("Unexpected insert map: %v", values)
+	}
+	// Zero fields with a dbInsertValue default are left to the default
+	if _, ok := values["description"]; ok {
+		t.Errorf("Expected description to be left to its default: %v", values)
+	}
+
+	query, args := GetInsertQuery("ai_model", values, "uuid")
+	var id string
+	if err := DB.QueryRow(ctx, query, args...).Scan(&id); err != nil || id != model.UUID {
+		t.Fatalf("Insert from map failed: %s (%v)", id, err)
+	}
+
+	// Realm's created_at default (NOW()) applies when the field is zero
+	realm := Realm{UUID: GenNewUUID(""), Name: "Mapped Realm"}
+	realmValues, err := StructToInsertMap(realm)
+	if err != nil {
+		t.Fatalf("StructToInsertMap failed: %v", err)
+	}
+	if _, ok := realmValues["created_at"]; ok {
+		t.Errorf("Expected zero created_at to be left out: %v", realmValues)
+	}
+
+	model.Type = "remapped_type"
+	updateValues, err := StructToUpdateMap(model)
+	if err != nil {
+		t.Fatalf("StructToUpdateMap failed: %v", err)
+	}
+	if _, ok := updateValues["uuid"]; ok {
+		t.Errorf("Expected only update fields without a pk tag: %v", updateValues)
+	}
+	updateValues["uuid"] = model.UUID
+	query, args = GetUpdateQuery("ai_model", updateValues, "uuid")
+	if _, err := DB.Exec(ctx, query, args...); err != nil {
+		t.Fatalf("Update from map failed: %v", err)
+	}
+
+	var modelType string
+	if err := Db.Get(&modelType, "SELECT type FROM ai_model WHERE uuid = $1", model.UUID); err != nil || modelType != "remapped_type" {
+		t.Errorf("Expected remapped_type, got %s (%v)", modelType, err)
+	}
+
+	if _, err := StructToInsertMap((*AIModel)(nil)); err == nil {
+		t.Error("Expected error for a nil object")
+	}
+}
+
+// TestDiffUpdateMap tests detecting changed update fields between two struct values
+func TestDiffUpdateMap(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	name := "Original"
+	before := AIModel{UUID: GenNewUUID(""), Key: "diff_key", Name: &name, Type: "diff_type", Provider: "diff_provider"}
+	if err := InsertObject(ctx, &before, "ai_model", "uuid"); err != nil {
+		t.Fatalf("InsertObject failed: %v", err)
+	}
+
+	sameName := "Original"
+	description := "Added"
+	after := before
+	after.Name = &sameName // different pointer, same value: unchanged
+	after.Description = &description
+	after.Provider = "diff_provider_2"
+	after.UUID = "not-an-update-field"
+
+	changes := DiffUpdateMap(&before, after)
+	if len(changes) != 2 || changes["description"] != &description || changes["provider"] != "diff_provider_2" {
+		t.Fatalf("Unexpected changes: %v", changes)
+	}
+
+	// non-nil -> nil is a change too
+	cleared := after
+	cleared.Name = nil
+	if changes := DiffUpdateMap(after, cleared); len(changes) != 1 || changes["name"] != (*string)(nil) {
+		t.Errorf("Expected name to be cleared, got %v", changes)
+	}
+	if changes := DiffUpdateMap(before, before); len(changes) != 0 {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+
+	changes["uuid"] = before.UUID
+	query, args := GetUpdateQuery("ai_model", changes, "uuid")
+	if _, err := DB.Exec(ctx, query, args...); err != nil {
+		t.Fatalf("Update of changed fields failed: %v", err)
+	}
+	var provider string
+	if err := Db.Get(&provider, "SELECT provider FROM ai_model WHERE uuid = $1", before.UUID); err != nil || provider != "diff_provider_2" {
+		t.Errorf("Expected diff_provider_2, got %s (%v)", provider, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for different struct types")
+		}
+	}()
+	DiffUpdateMap(before, Realm{})
+}
+
+// TestInsertObject tests inserting a struct and scanning RETURNING back into it
+func TestInsertObject(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	realm := Realm{UUID: GenNewUUID(""), Name: "Object Realm"}
+	if err := InsertObject(ctx, &realm, "realm", "created_at"); err != nil {
+		t.Fatalf("InsertObject failed: %v", err)
+	}
+	if realm.CreatedAt.IsZero() || time.Since(realm.CreatedAt) > time.Minute {
+		t.Errorf("Expected created_at to be scanned back, got %v", realm.CreatedAt)
+	}
+
+	// Without returning, a struct value is enough
+	model := AIModel{UUID: GenNewUUID(""), Key: "object_key", Type: "object_type", Provider: "object_provider"}
+	if err := InsertObject(ctx, model, "ai_model", ""); err != nil {
+		t.Fatalf("InsertObject without returning failed: %v", err)
+	}
+	var key string
+	if err := Db.Get(&key, "SELECT key FROM ai_model WHERE uuid = $1", model.UUID); err != nil || key != "object_key" {
+		t.Errorf("Expected object_key, got %s (%v)", key, err)
+	}
+
+	if err := InsertObject(ctx, realm, "realm", "uuid"); err == nil {
+		t.Error("Expected error when returning into a non-pointer")
+	}
+	if err := InsertObject(ctx, &realm, "realm", "missing"); err == nil {
+		t.Error("Expected error for a returning column without a field")
+	}
+	if err := InsertObject(ctx, &realm, "not_registered", ""); err == nil {
+		t.Error("Expected error for an unregistered table")
+	}
+}
+
+// TestUpdateObject tests updating a struct's "u" fields keyed by one of its columns
+func TestUpdateObject(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	model := AIModel{UUID: GenNewUUID(""), Key: "update_object", Type: "before", Provider: "provider"}
+	if err := InsertObject(ctx, &model, "ai_model", "uuid"); err != nil {
+		t.Fatalf("InsertObject failed: %v", err)
+	}
+
+	settings := `{"max_tokens": 10}`
+	model.Type = "after"
+	model.Settings = &settings
+	if err := UpdateObject(ctx, &model, "ai_model", "uuid"); err != nil {
+		t.Fatalf("UpdateObject failed: %v", err)
+	}
+
+	var loaded AIModel
+	if err := Db.Get(&loaded, aiModelBaseQuery+` WHERE "ai_model".uuid = $1`, model.UUID); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if loaded.Type != "after" || loaded.Settings == nil {
+		t.Errorf("Expected the updated fields, got %+v", loaded)
+	}
+
+	// Map Valuer fields get the ::jsonb cast
+	partial := struct {
+		UUID     string        `db:"uuid" dbMode:"i"`
+		Settings jsonbSettings `db:"settings" dbMode:"u"`
+	}{UUID: model.UUID, Settings: jsonbSettings{"depth": 2}}
+	if err := UpdateObject(ctx, &partial, "ai_model", "uuid"); err != nil {
+		t.Fatalf("UpdateObject with jsonb failed: %v", err)
+	}
+	var depth int
+	if err := Db.Get(&depth, "SELECT (settings->>'depth')::int FROM ai_model WHERE uuid = $1", model.UUID); err != nil || depth != 2 {
+		t.Errorf("Expected depth 2, got %d (%v)", depth, err)
+	}
+
+	if err := UpdateObject(ctx, &model, "ai_model", "missing"); err == nil {
+		t.Error("Expected error for a where column without a field")
+	}
+}
+
+// TestGetUpdateQuerySafe tests that invalid updates return errors instead of panicking
+func TestGetUpdateQuerySafe(t *testing.T) {
+	query, args, err := GetUpdateQuerySafe("realm", map[string]interface{}{"uuid": "u1", "name": "n"}, "uuid")
+	if err != nil {
+		t.Fatalf("GetUpdateQuerySafe failed: %v", err)
+	}
+	if !strings.Contains(query, `WHERE "realm"."uuid" = $`) || len(args) != 2 || args[len(args)-1] != "u1" {
+		t.Errorf("Unexpected update query: %s %v", query, args)
+	}
+
+	cases := []struct {
+		name      string
+		values    map[string]interface{}
+		returning string
+	}{
+		{"missing key", map[string]interface{}{"name": "n"}, "uuid"},
+		{"empty returning", map[string]interface{}{"uuid": "u1", "name": "n"}, ""},
+		{"no update fields", map[string]interface{}{"uuid": "u1"}, "uuid"},
+	}
+	for _, tc := range cases {
+		if _, _, err := GetUpdateQuerySafe("realm", tc.values, tc.returning); err == nil {
+			t.Errorf("%s: expected error", tc.name)
+		}
+	}
+
+	// Update surfaces the error instead of panicking
+	if err := Update(context.Background(), "realm", map[string]interface{}{"name": "n"}, "uuid"); err == nil {
+		t.Error("Expected Update error for a missing key")
+	}
+
+	// The legacy GetUpdateQuery still builds a query with no update fields (the server rejects it)
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				t.Errorf("GetUpdateQuery panicked without update fields: %v", r)
+			}
+		}()
+		query, args := GetUpdateQuery("realm", map[string]interface{}{"uuid": "u1"}, "uuid")
+		if !strings.HasPrefix(query, `UPDATE "realm" SET  WHERE "realm"."uuid" = $1`) || len(args) != 1 {
+			t.Errorf("Unexpected legacy update query: %s %v", query, args)
+		}
+	}()
+}
+
+// TestGetUpdateQueryWhere tests separate WHERE and RETURNING columns, and updates without RETURNING
+func TestGetUpdateQueryWhere(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	realm := Realm{UUID: GenNewUUID(""), Name: "Where Realm"}
+	insertRealm(t, realm)
+
+	// No returning column: plain UPDATE for Exec
+	query, args, err := GetUpdateQueryWhere("realm", map[string]interface{}{"uuid": realm.UUID, "name": "Exec Realm"}, "uuid", "")
+	if err != nil {
+		t.Fatalf("GetUpdateQueryWhere failed: %v", err)
+	}
+	if strings.Contains(query, "RETURNING") {
+		t.Errorf("Expected no RETURNING clause: %s", query)
+	}
+	tag, err := DB.Exec(ctx, query, args...)
+	if err != nil || tag.RowsAffected() != 1 {
+		t.Fatalf("Exec failed: %v (%d rows)", err, tag.RowsAffected())
+	}
+
+	// Match on uuid, return another column
+	query, args, err = GetUpdateQueryWhere("realm", map[string]interface{}{"uuid": realm.UUID, "name": "Returned Realm"}, "uuid", "name")
+	if err != nil {
+		t.Fatalf("GetUpdateQueryWhere failed: %v", err)
+	}
+	var name string
+	if err := DB.QueryRow(ctx, query, args...).Scan(&name); err != nil || name != "Returned Realm" {
+		t.Errorf("Expected Returned Realm, got %q (%v)", name, err)
+	}
+
+	if _, _, err := GetUpdateQueryWhere("realm", map[string]interface{}{"name": "n"}, "uuid", ""); err == nil {
+		t.Error("Expected error for a missing where column")
+	}
+}
+
+// TestCompositeKeyUpdateDelete tests updating and deleting rows identified by two columns
+func TestCompositeKeyUpdateDelete(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	realms := []Realm{{UUID: GenNewUUID(""), Name: "Realm A"}, {UUID: GenNewUUID(""), Name: "Realm B"}}
+	for _, realm := range realms {
+		insertRealm(t, realm)
+		insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "shared.com", RealmUUID: realm.UUID})
+	}
+
+	stamp := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	keys := []string{"realm_uuid", "domain"}
+	query, args, err := GetUpdateQueryKeys("website", map[string]interface{}{
+		"realm_uuid": realms[0].UUID,
+		"domain":     "shared.com",
+		"updated_at": stamp,
+	}, keys, "uuid")
+	if err != nil {
+		t.Fatalf("GetUpdateQueryKeys failed: %v", err)
+	}
+	if !strings.Contains(query, `WHERE "website"."realm_uuid" = $`) || !strings.Contains(query, ` AND "website"."domain" = $`) {
+		t.Errorf("Unexpected composite where: %s", query)
+	}
+	var updatedUUID string
+	if err := DB.QueryRow(ctx, query, args...).Scan(&updatedUUID); err != nil {
+		t.Fatalf("Composite update failed: %v", err)
+	}
+
+	var stamped int
+	if err := Db.Get(&stamped, "SELECT COUNT(*) FROM website WHERE updated_at = $1", stamp); err != nil || stamped != 1 {
+		t.Errorf("Expected exactly 1 updated website, got %d (%v)", stamped, err)
+	}
+
+	n, err := DeleteByKeys(ctx, "website", keys, map[string]interface{}{"realm_uuid": realms[1].UUID, "domain": "shared.com"})
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 deleted website, got %d (%v)", n, err)
+	}
+	var remaining string
+	if err := Db.Get(&remaining, "SELECT uuid FROM website"); err != nil || remaining != updatedUUID {
+		t.Errorf("Expected the updated website to remain, got %s (%v)", remaining, err)
+	}
+
+	if _, err := DeleteByKeys(ctx, "website", keys, map[string]interface{}{"domain": "shared.com"}); err == nil {
+		t.Error("Expected error for a missing key value")
+	}
+}
+
+// reservedWordModel has columns named after SQL reserved words
+type reservedWordModel struct {
+	UUID  string `db:"uuid" dbMode:"i"`
+	Order int    `db:"order" dbMode:"i,u"`
+	User  string `db:"user" dbMode:"i,u"`
+}
+
+// TestReservedWordColumns tests that insert and update queries quote their columns
+func TestReservedWordColumns(t *testing.T) {
+	ctx := context.Background()
+	if _, err := SafeExec(`CREATE TABLE IF NOT EXISTS reserved_words (uuid UUID PRIMARY KEY, "order" INT, "user" TEXT)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer SafeExec(`DROP TABLE IF EXISTS reserved_words`)
+	InitModelTagCache(reservedWordModel{}, "reserved_words")
+	defer ClearModelCache("reserved_words")
+
+	id := GenNewUUID("")
+	query, args := GetInsertQuery("reserved_words", map[string]interface{}{"uuid": id, "order": 1, "user": "ann"}, "uuid")
+	if !strings.HasPrefix(query, `INSERT INTO "reserved_words" ("uuid","order","user") VALUES`) {
+		t.Errorf("Unexpected insert query: %s", query)
+	}
+	if _, err := DB.Exec(ctx, query, args...); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	query, args, err := GetUpdateQuerySafe("reserved_words", map[string]interface{}{"uuid": id, "order": 2, "user": "bob"}, "uuid")
+	if err != nil {
+		t.Fatalf("GetUpdateQuerySafe failed: %v", err)
+	}
+	if !strings.Contains(query, `SET "order" = $1, "user" = $2`) {
+		t.Errorf("Unexpected update query: %s", query)
+	}
+	var returned string
+	if err := DB.QueryRow(ctx, query, args...).Scan(&returned); err != nil || returned != id {
+		t.Fatalf("Update failed: %q (%v)", returned, err)
+	}
+
+	var row reservedWordModel
+	if err := Db.Get(&row, SelectBase("reserved_words", "").Build()); err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if row.Order != 2 || row.User != "bob" {
+		t.Errorf("Expected the updated row, got %+v", row)
+	}
+}
+
+// rawDefaultModel generates its key in the database through a raw SQL insert default
+type rawDefaultModel struct {
+	UUID string `db:"uuid" dbMode:"i" dbInsertValue:"sql:gen_random_uuid()"`
+	Name string `db:"name" dbMode:"i,u"`
+}
+
+// TestRawSQLInsertDefault tests that "sql:" dbInsertValues are inlined rather than bound
+func TestRawSQLInsertDefault(t *testing.T) {
+	ctx := context.Background()
+	if _, err := SafeExec(`CREATE TABLE IF NOT EXISTS raw_defaults (uuid UUID PRIMARY KEY, name TEXT)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer SafeExec(`DROP TABLE IF EXISTS raw_defaults`)
+	InitModelTagCache(rawDefaultModel{}, "raw_defaults")
+	defer ClearModelCache("raw_defaults")
+
+	query, args := GetInsertQuery("raw_defaults", map[string]interface{}{"name": "generated"}, "uuid")
+	if !strings.Contains(query, `VALUES (gen_random_uuid(),$1)`) {
+		t.Errorf("Expected the raw default to be inlined: %s", query)
+	}
+	if len(args) != 1 || args[0] != "generated" {
+		t.Errorf("Expected only the name to be bound, got %v", args)
+	}
+
+	var id string
+	if err := DB.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if len(id) != 36 {
+		t.Errorf("Expected a generated UUID, got %q", id)
+	}
+
+	if _, err := CopyInsertObjects(ctx, "raw_defaults", []rawDefaultModel{{Name: "copied"}}); err == nil {
+		t.Error("Expected COPY to reject an unset field with a raw SQL default")
+	}
+}
+
+// TestInsertUpdateReturning tests scanning RETURNING values into typed results
+func TestInsertUpdateReturning(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	realmUUID := GenNewUUID("")
+	id, err := InsertReturning[string](ctx, "realm", map[string]interface{}{
+		"uuid": realmUUID,
+		"name": "Typed Realm",
+	}, "uuid")
+	if err != nil {
+		t.Fatalf("InsertReturning failed: %v", err)
+	}
+	if id != realmUUID {
+		t.Errorf("Expected %s, got %s", realmUUID, id)
+	}
+
+	createdAt, err := InsertReturning[time.Time](ctx, "realm", map[string]interface{}{
+		"uuid": GenNewUUID(""),
+		"name": "Timed Realm",
+	}, "created_at")
+	if err != nil {
+		t.Fatalf("InsertReturning time failed: %v", err)
+	}
+	if time.Since(createdAt) > time.Minute {
+		t.Errorf("Expected a fresh created_at, got %v", createdAt)
+	}
+
+	updated, err := UpdateReturning[string](ctx, "realm", map[string]interface{}{
+		"uuid": realmUUID,
+		"name": "Renamed Typed Realm",
+	}, "uuid")
+	if err != nil || updated != realmUUID {
+		t.Fatalf("UpdateReturning failed: %s (%v)", updated, err)
+	}
+
+	_, err = UpdateReturning[string](ctx, "realm", map[string]interface{}{
+		"uuid": GenNewUUID(""),
+		"name": "Missing",
+	}, "uuid")
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a missing row, got %v", err)
+	}
+
+	if _, err := InsertReturning[string](ctx, "realm", map[string]interface{}{"name": "x"}, ""); err == nil {
+		t.Error("Expected error without a returning column")
+	}
+}
+
+func TestInsertOrGet(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	realmUUID := GenNewUUID("")
+	created, existed, err := InsertOrGet[Realm](ctx, "realm", map[string]interface{}{
+		"uuid": realmUUID,
+		"name": "Original Realm",
+	}, []string{"uuid"})
+	if err != nil {
+		t.Fatalf("InsertOrGet failed: %v", err)
+	}
+	if existed {
+		t.Error("Expected first insert to report existed=false")
+	}
+	if created.UUID != realmUUID || created.Name != "Original Realm" {
+		t.Errorf("Unexpected inserted realm: %+v", created)
+	}
+
+	// Conflicting insert returns the existing row untouched
+	existing, existed, err := InsertOrGet[Realm](ctx, "realm", map[string]interface{}{
+		"uuid": realmUUID,
+		"name": "Replacement Realm",
+	}, []string{"uuid"})
+	if err != nil {
+		t.Fatalf("InsertOrGet on conflict failed: %v", err)
+	}
+	if !existed {
+		t.Error("Expected conflicting insert to report existed=true")
+	}
+	if existing.UUID != realmUUID || existing.Name != "Original Realm" {
+		t.Errorf("Expected the original realm, got %+v", existing)
+	}
+	if !existing.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("Expected created_at %v, got %v", created.CreatedAt, existing.CreatedAt)
+	}
+
+	var count int
+	if err := Db.Get(&count, "SELECT COUNT(*) FROM realm"); err != nil {
+		t.Fatalf("Failed to count realms: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 realm, got %d", count)
+	}
+
+	if _, _, err := InsertOrGet[Realm](ctx, "realm", map[string]interface{}{"name": "x"}, []string{"uuid"}); err == nil {
+		t.Error("Expected error when conflict column is missing from values")
+	}
+}
+
+// AIModelWithDisplayName scans the computed display_name column
+type AIModelWithDisplayName struct {
+	UUID        string  `db:"uuid"`
+	Key         string  `db:"key"`
+	Name        *string `db:"name"`
+	DisplayName string  `db:"display_name" dbMode:"s"`
+}
+
+func TestRegisterComputedColumn(t *testing.T) {
+	cleanDatabase(t)
+
+	if err := RegisterComputedColumn("ai_model", "display_name", `COALESCE("ai_model".name, "ai_model".key)`); err != nil {
+		t.Fatalf("RegisterComputedColumn failed: %v", err)
+	}
+
+	fields, names := GetSelectFields("ai_model", "")
+	if fields[len(fields)-1] != `COALESCE("ai_model".name, "ai_model".key) AS "display_name"` || names[len(names)-1] != "display_name" {
+		t.Errorf("Expected computed column last in select list, got %v", fields)
+	}
+
+	named := "Named Model"
+	withName := AIModel{Key: "named_key", Name: &named, Type: "t", Provider: "p"}
+	withoutName := AIModel{Key: "unnamed_key", Type: "t", Provider: "p"}
+	if err := withName.Insert(); err != nil {
+		t.Fatalf("Failed to insert model: %v", err)
+	}
+	if err := withoutName.Insert(); err != nil {
+		t.Fatalf("Failed to insert model: %v", err)
+	}
+
+	query := SelectBase("ai_model", "").Build() + ` ORDER BY "ai_model".key`
+	var models []AIModelWithDisplayName
+	if err := Db.Select(&models, query); err != nil {
+		t.Fatalf("Failed to select models: %v", err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("Expected 2 models, got %d", len(models))
+	}
+	if models[0].DisplayName != "Named Model" {
+		t.Errorf("Expected display name from name, got %q", models[0].DisplayName)
+	}
+	if models[1].DisplayName != "unnamed_key" {
+		t.Errorf("Expected display name from key, got %q", models[1].DisplayName)
+	}
+
+	// Aliased select lists don't get computed columns
+	aliased, _ := GetSelectFields("ai_model", "m")
+	for _, f := range aliased {
+		if strings.Contains(f, "display_name") {
+			t.Errorf("Expected no computed column in aliased select list, got %s", f)
+		}
+	}
+
+	if err := RegisterComputedColumn("ai_model", "bad.name", "1"); err == nil {
+		t.Error("Expected error for dotted computed column name")
+	}
+}
+
+func TestInsertMap(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	values := map[string]interface{}{"name": "Map Realm"}
+	id, err := InsertMap(ctx, "realm", values)
+	if err != nil {
+		t.Fatalf("InsertMap failed: %v", err)
+	}
+	if id == "" {
+		t.Fatal("Expected a generated uuid")
+	}
+	if _, ok := values["uuid"]; ok {
+		t.Error("Expected caller's map to be left unmodified")
+	}
+
+	var realm Realm
+	if err := Db.Get(&realm, realmBaseQuery+` WHERE "realm".uuid = $1`, id); err != nil {
+		t.Fatalf("Failed to fetch inserted realm: %v", err)
+	}
+	if realm.Name != "Map Realm" {
+		t.Errorf("Expected Map Realm, got %s", realm.Name)
+	}
+
+	// An explicit uuid is kept
+	explicit := GenNewUUID("")
+	id, err = InsertMap(ctx, "realm", map[string]interface{}{"uuid": explicit, "name": "Explicit Realm"})
+	if err != nil {
+		t.Fatalf("InsertMap with uuid failed: %v", err)
+	}
+	if id != explicit {
+		t.Errorf("Expected uuid %s, got %s", explicit, id)
+	}
+}
+
+// WebsiteWithOwner maps the "r" join alias onto a differently named field
+type WebsiteWithOwner struct {
+	UUID      string `db:"uuid"`
+	Domain    string `db:"domain"`
+	RealmUUID string `db:"realm_uuid"`
+	Owner     *Realm `db:"owner" dbPrefix:"r"`
+}
+
+// WebsiteWithRealmName maps a single aliased column explicitly
+type WebsiteWithRealmName struct {
+	UUID      string `db:"uuid"`
+	Domain    string `db:"domain"`
+	RealmName string `db:"r.name" dbPrefix:"r"`
+}
+
+func TestScanDbPrefix(t *testing.T) {
+	cleanDatabase(t)
+
+	realm := Realm{UUID: GenNewUUID(""), Name: "Prefixed Realm"}
+	insertRealm(t, realm)
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "prefix.com", RealmUUID: realm.UUID})
+
+	var owners []WebsiteWithOwner
+	if err := Db.Select(&owners, websiteBaseQuery); err != nil {
+		t.Fatalf("Failed to select websites: %v", err)
+	}
+	if len(owners) != 1 {
+		t.Fatalf("Expected 1 website, got %d", len(owners))
+	}
+	if owners[0].Owner == nil || owners[0].Owner.Name != "Prefixed Realm" || owners[0].Owner.UUID != realm.UUID {
+		t.Errorf("Expected Owner mapped from r.* columns, got %+v", owners[0].Owner)
+	}
+
+	var named WebsiteWithRealmName
+	if err := Db.Get(&named, websiteBaseQuery+` LIMIT 1`); err != nil {
+		t.Fatalf("Failed to get website: %v", err)
+	}
+	if named.RealmName != "Prefixed Realm" || named.Domain != "prefix.com" {
+		t.Errorf("Expected RealmName mapped from r.name, got %+v", named)
+	}
+}
+
+// RealmNameOnly is a narrower model registered for realm after clearing the cache
+type RealmNameOnly struct {
+	UUID string `db:"uuid" dbMode:"i"`
+	Name string `db:"name" dbMode:"i,u"`
+}
+
+func TestClearModelCache(t *testing.T) {
+	cleanDatabase(t)
+
+	// Restore the shared registration for later tests
+	defer func() {
+		ClearModelCache("realm")
+		InitModelTagCache(Realm{}, "realm")
+	}()
+
+	if meta, _ := ModelMetadata("realm"); len(meta.SelectColumns) != 4 {
+		t.Fatalf("Expected 4 realm columns before clearing, got %v", meta.SelectColumns)
+	}
+
+	// Re-registering without clearing keeps the old fields
+	InitModelTagCache(RealmNameOnly{}, "realm")
+	if meta, _ := ModelMetadata("realm"); len(meta.SelectColumns) != 4 {
+		t.Errorf("Expected re-registration to be ignored, got %v", meta.SelectColumns)
+	}
+
+	ClearModelCache("realm")
+	if _, ok := ModelMetadata("realm"); ok {
+		t.Fatal("Expected realm to be unregistered after ClearModelCache")
+	}
+
+	InitModelTagCache(RealmNameOnly{}, "realm")
+	meta, ok := ModelMetadata("realm")
+	if !ok {
+		t.Fatal("Expected realm to be registered again")
+	}
+	if len(meta.SelectColumns) != 2 || meta.SelectColumns[0] != "uuid" || meta.SelectColumns[1] != "name" {
+		t.Errorf("Expected new fields [uuid name], got %v", meta.SelectColumns)
+	}
+
+	query := SelectBase("realm", "").Build()
+	if strings.Contains(query, "created_at") {
+		t.Errorf("Expected built query to use the new fields, got %s", query)
+	}
+
+	insertRealm(t, Realm{UUID: GenNewUUID(""), Name: "Cleared Realm"})
+	var realms []RealmNameOnly
+	if err := Db.Select(&realms, query); err != nil {
+		t.Fatalf("Failed to select with new model: %v", err)
+	}
+	if len(realms) != 1 || realms[0].Name != "Cleared Realm" {
+		t.Errorf("Unexpected realms: %+v", realms)
+	}
+
+	// ClearAllModelCaches drops every table
+	ClearAllModelCaches()
+	for _, table := range []string{"realm", "website", "ai_model"} {
+		if _, ok := ModelMetadata(table); ok {
+			t.Errorf("Expected %s to be unregistered after ClearAllModelCaches", table)
+		}
+	}
+	InitModelTagCache(AIModel{}, "ai_model")
+	InitModelTagCache(Website{}, "website")
+}
+
+// TestQueryBuilderPage tests scanning a page and counting the total in one call
+func TestQueryBuilderPage(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		insertRealm(t, Realm{UUID: GenNewUUID(""), Name: fmt.Sprintf("Page Realm %d", i)})
+	}
+
+	seen := make(map[string]bool)
+	for page := 1; page <= 3; page++ {
+		var realms []Realm
+		pagination, err := SelectBase("realm", "").Page(ctx, &realms, 2, page)
+		if err != nil {
+			t.Fatalf("Page %d failed: %v", page, err)
+		}
+		if pagination.Count != 5 || pagination.PageMax != 3 || pagination.PageNo != page || pagination.ResultsPerPage != 2 {
+			t.Errorf("Page %d: unexpected pagination %+v", page, pagination)
+		}
+		expected := 2
+		if page == 3 {
+			expected = 1
+		}
+		if len(realms) != expected {
+			t.Errorf("Page %d: expected %d realms, got %d", page, expected, len(realms))
+		}
+		for _, r := range realms {
+			if seen[r.UUID] {
+				t.Errorf("Realm %s returned on more than one page", r.UUID)
+			}
+			seen[r.UUID] = true
+		}
+	}
+	if len(seen) != 5 {
+		t.Errorf("Expected 5 distinct realms across pages, got %d", len(seen))
+	}
+
+	// Where args are shared by the page and the count
+	var realms []Realm
+	pagination, err := SelectBase("realm", "").WhereArgs(`name = $1`, "Page Realm 3").Page(ctx, &realms, 10, 1)
+	if err != nil {
+		t.Fatalf("Filtered page failed: %v", err)
+	}
+	if pagination.Count != 1 || pagination.PageMax != 1 || len(realms) != 1 || realms[0].Name != "Page Realm 3" {
+		t.Errorf("Unexpected filtered page: %+v %+v", pagination, realms)
+	}
+}
+
+// TestWildcardBaseJoin tests a wildcard base table scanning alongside aliased joined columns
+func TestWildcardBaseJoin(t *testing.T) {
+	cleanDatabase(t)
+
+	realm := Realm{UUID: GenNewUUID(""), Name: "Wildcard Realm"}
+	insertRealm(t, realm)
+	website := Website{UUID: GenNewUUID(""), Domain: "wildcard.com", RealmUUID: realm.UUID}
+	insertWebsite(t, website)
+
+	query := SelectBase("website", "").
+		WildcardBase().
+		Left("realm", "r", "website.realm_uuid = r.uuid").
+		Build()
+	if !strings.Contains(query, `"website".*`) || !strings.Contains(query, `"r.name"`) {
+		t.Fatalf("Expected wildcard base and aliased join columns, got %s", query)
+	}
+
+	var websites []Website
+	if err := Db.Select(&websites, query); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(websites) != 1 {
+		t.Fatalf("Expected 1 website, got %d", len(websites))
+	}
+
+	got := websites[0]
+	if got.UUID != website.UUID || got.Domain != "wildcard.com" || got.RealmUUID != realm.UUID || got.CreatedAt.IsZero() {
+		t.Errorf("Unexpected base columns: %+v", got)
+	}
+	if got.Realm == nil || got.Realm.UUID != realm.UUID || got.Realm.Name != realm.Name {
+		t.Errorf("Expected linked realm %+v, got %+v", realm, got.Realm)
+	}
+}
+
+// TestQueryBuilderDistinctColumns tests DISTINCT and explicit column projection
+func TestQueryBuilderDistinctColumns(t *testing.T) {
+	cleanDatabase(t)
+
+	for i, modelType := range []string{"chat", "chat", "image"} {
+		model := AIModel{Key: fmt.Sprintf("distinct_%d", i), Type: modelType, Provider: "distinct_provider"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	query := SelectBase("ai_model", "").Distinct().Columns("type").Build()
+	if !strings.HasPrefix(query, `SELECT DISTINCT "ai_model"."type" FROM "ai_model"`) {
+		t.Fatalf("Unexpected query: %s", query)
+	}
+
+	// Scanning into the full struct leaves unselected fields zero
+	var models []AIModel
+	if err := Db.Select(&models, query+` ORDER BY "ai_model"."type"`); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(models) != 2 || models[0].Type != "chat" || models[1].Type != "image" || models[0].Key != "" {
+		t.Errorf("Expected 2 distinct types, got %+v", models)
+	}
+
+	// Go field names resolve to columns; a base WHERE subquery keeps every field
+	query = SelectBase("ai_model", "").Columns("Key", "provider").WhereArgs(`"ai_model".type = $1`, "image").Build()
+	if !strings.HasPrefix(query, `SELECT "ai_model"."key", "ai_model"."provider" FROM (SELECT`) {
+		t.Fatalf("Unexpected query: %s", query)
+	}
+	models = nil
+	if err := Db.Select(&models, query, "image"); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(models) != 1 || models[0].Key != "distinct_2" || models[0].Provider != "distinct_provider" || models[0].UUID != "" {
+		t.Errorf("Unexpected projected rows: %+v", models)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for an unknown column")
+		}
+	}()
+	SelectBase("ai_model", "").Columns("missing")
+}
+
+// TestQueryBuilderAggregate tests selecting a single aggregate into a scalar
+func TestQueryBuilderAggregate(t *testing.T) {
+	cleanDatabase(t)
+
+	for i, modelType := range []string{"chat", "chat", "image"} {
+		model := AIModel{Key: fmt.Sprintf("agg_%d", i), Type: modelType, Provider: "agg_provider"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	qb := SelectBase("ai_model", "").Aggregate("count", "Key").WhereArgs(`"ai_model".type = $1`, "chat")
+	query := qb.Build()
+	if !strings.HasPrefix(query, `SELECT COUNT("ai_model"."key") AS "count" FROM (SELECT`) {
+		t.Fatalf("Unexpected query: %s", query)
+	}
+	var count sql.NullInt64
+	if err := SafeGet(&count, query, qb.Args()...); err != nil || count.Int64 != 2 {
+		t.Errorf("Expected count 2, got %+v (%v)", count, err)
+	}
+
+	if err := SafeGet(&count, SelectBase("ai_model", "").Aggregate("COUNT", "*").Build()); err != nil || count.Int64 != 3 {
+		t.Errorf("Expected COUNT(*) 3, got %+v (%v)", count, err)
+	}
+
+	var maxKey sql.NullString
+	if err := SafeGet(&maxKey, SelectBase("ai_model", "").Aggregate("MAX", "key").Build()); err != nil || maxKey.String != "agg_2" {
+		t.Errorf("Expected MAX agg_2, got %+v (%v)", maxKey, err)
+	}
+
+	// Aggregates over no rows are NULL
+	qb = SelectBase("ai_model", "").Aggregate("MIN", "key").WhereArgs(`"ai_model".type = $1`, "missing")
+	if err := SafeGet(&maxKey, qb.Build(), qb.Args()...); err != nil || maxKey.Valid {
+		t.Errorf("Expected NULL MIN over no rows, got %+v (%v)", maxKey, err)
+	}
+
+	for _, bad := range [][2]string{{"MEDIAN", "key"}, {"SUM", "*"}, {"SUM", "missing"}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected panic for Aggregate(%q, %q)", bad[0], bad[1])
+				}
+			}()
+			SelectBase("ai_model", "").Aggregate(bad[0], bad[1])
+		}()
+	}
+}
+
+// TestQueryBuilderDistinctOn tests fetching the most recent website per realm
+func TestQueryBuilderDistinctOn(t *testing.T) {
+	cleanDatabase(t)
+
+	realm1 := Realm{UUID: GenNewUUID(""), Name: "Realm One"}
+	realm2 := Realm{UUID: GenNewUUID(""), Name: "Realm Two"}
+	insertRealm(t, realm1)
+	insertRealm(t, realm2)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, w := range []Website{
+		{UUID: GenNewUUID(""), Domain: "old-one.com", RealmUUID: realm1.UUID},
+		{UUID: GenNewUUID(""), Domain: "new-one.com", RealmUUID: realm1.UUID},
+		{UUID: GenNewUUID(""), Domain: "old-two.com", RealmUUID: realm2.UUID},
+		{UUID: GenNewUUID(""), Domain: "new-two.com", RealmUUID: realm2.UUID},
+	} {
+		insertWebsite(t, w)
+		if _, err := SafeExec(`UPDATE website SET created_at = $1 WHERE uuid = $2`, base.Add(time.Duration(i)*time.Hour), w.UUID); err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+	}
+
+	query := SelectBase("website", "").
+		DistinctOn("realm_uuid").
+		OrderBy(`"website"."realm_uuid", "website"."created_at" DESC`).
+		Build()
+	if !strings.HasPrefix(query, `SELECT DISTINCT ON ("website"."realm_uuid") `) {
+		t.Errorf("Unexpected query: %s", query)
+	}
+
+	var latest []Website
+	if err := Db.Select(&latest, query); err != nil {
+		t.Fatalf("Failed to execute query: %v", err)
+	}
+	domains := map[string]string{}
+	for _, w := range latest {
+		domains[w.RealmUUID] = w.Domain
+	}
+	if len(latest) != 2 || domains[realm1.UUID] != "new-one.com" || domains[realm2.UUID] != "new-two.com" {
+		t.Errorf("Expected the newest website per realm, got %v", domains)
+	}
+
+	// The DISTINCT ON columns must lead the ORDER BY, including the one FilterQuery appends
+	if err := SelectBase("website", "").DistinctOn("realm_uuid").OrderBy(`"website"."created_at" DESC`).Validate(); err == nil {
+		t.Error("Expected an error for an ORDER BY not led by the DISTINCT ON columns")
+	}
+	distinct := SelectBase("website", "").DistinctOn("realm_uuid")
+	if err := distinct.Validate(); err != nil {
+		t.Errorf("Expected no error without an ORDER BY, got %v", err)
+	}
+	if _, _, err := distinct.FilterQuery(nil, &Sort{"CreatedAt": "DESC"}, 0, 0); err == nil {
+		t.Error("Expected FilterQuery to reject a sort not led by the DISTINCT ON columns")
+	}
+	if _, _, err := distinct.FilterQuery(nil, SortList{{Field: "RealmUUID", Direction: "ASC"}, {Field: "CreatedAt", Direction: "DESC"}}, 0, 0); err != nil {
+		t.Errorf("Expected FilterQuery to accept a sort led by the DISTINCT ON columns, got %v", err)
+	}
+}
+
+// TestQueryBuilderLimitOffsetOrderBy tests fluent pagination on the base builder
+func TestQueryBuilderLimitOffsetOrderBy(t *testing.T) {
+	cleanDatabase(t)
+
+	for i := 0; i < 5; i++ {
+		model := AIModel{Key: fmt.Sprintf("page_%d", i), Type: "page_type", Provider: "page_provider"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	qb := SelectBase("ai_model", "").
+		WhereArgs(`"ai_model".type = $1`, "page_type").
+		OrderBy(`"ai_model".key DESC`).
+		Limit(2).
+		Offset(1)
+	query := qb.Build()
+	if !strings.HasSuffix(query, ` ORDER BY "ai_model".key DESC LIMIT 2 OFFSET 1`) {
+		t.Fatalf("Unexpected query: %s", query)
+	}
+
+	var models []AIModel
+	if err := Db.Select(&models, query, qb.Args()...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(models) != 2 || models[0].Key != "page_3" || models[1].Key != "page_2" {
+		t.Errorf("Unexpected page: %+v", models)
+	}
+
+	// The count ignores ORDER BY/LIMIT/OFFSET
+	count, err := GetFilterCount(BuildFilterCount(query), qb.Args())
+	if err != nil || count != 5 {
+		t.Errorf("Expected count 5, got %d (%v)", count, err)
+	}
+}
+
+// TestQueryBuilderWith tests CTEs as the FROM target and as a joined table
+func TestQueryBuilderWith(t *testing.T) {
+	cleanDatabase(t)
+
+	for i, provider := range []string{"p1", "p1", "p2"} {
+		model := AIModel{Key: fmt.Sprintf("cte_%d", i), Type: "cte_type", Provider: provider}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	// Unregistered CTE as the base table, joined to a second CTE
+	query := SelectBase("busy", "").
+		With("counts", `SELECT provider, COUNT(*) AS n FROM ai_model GROUP BY provider`).
+		With("busy", `SELECT provider FROM counts WHERE n > 1`).
+		Columns("provider").
+		Build()
+	if !strings.HasPrefix(query, `WITH "counts" AS (SELECT provider, COUNT(*) AS n FROM ai_model GROUP BY provider), "busy" AS (`) {
+		t.Fatalf("Unexpected query: %s", query)
+	}
+	var providers []string
+	if err := Db.Select(&providers, query); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(providers) != 1 || providers[0] != "p1" {
+		t.Errorf("Expected [p1], got %v", providers)
+	}
+
+	// A registered table's fields still resolve when joined to a CTE
+	query = SelectBase("ai_model", "").
+		With("busy", `SELECT provider FROM ai_model GROUP BY provider HAVING COUNT(*) > 1`).
+		Join("busy", "b", `b.provider = "ai_model".provider`).
+		Build()
+	var models []AIModel
+	if err := Db.Select(&models, query); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(models) != 2 || models[0].Provider != "p1" {
+		t.Errorf("Expected the 2 p1 models, got %+v", models)
+	}
+
+	// Count works on a CTE query
+	count, err := GetFilterCount(BuildFilterCount(query), nil)
+	if err != nil || count != 2 {
+		t.Errorf("Expected count 2, got %d (%v)", count, err)
+	}
+}
+
+// TestQueryBuilderUnion tests combining two filtered builders with UNION and UNION ALL
+func TestQueryBuilderUnion(t *testing.T) {
+	cleanDatabase(t)
+
+	for i, modelType := range []string{"chat", "image", "audio", "chat"} {
+		model := AIModel{Key: fmt.Sprintf("union_%d", i), Type: modelType, Provider: "union_provider"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	qb := SelectBase("ai_model", "").WhereArgs(`"ai_model".type = $1`, "chat").
+		Union(SelectBase("ai_model", "").WhereArgs(`"ai_model".type = $1`, "image"))
+	query, args := qb.Build(), qb.Args()
+	if !strings.HasPrefix(query, "(SELECT") || !strings.Contains(query, ") UNION (SELECT") || !strings.Contains(query, "type = $2") {
+		t.Fatalf("Unexpected query: %s", query)
+	}
+	if len(args) != 2 {
+		t.Fatalf("Expected 2 args, got %v", args)
+	}
+
+	var models []AIModel
+	if err := Db.Select(&models, query+" ORDER BY key", args...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(models) != 3 || models[0].Key != "union_0" || models[1].Key != "union_1" || models[2].Key != "union_3" {
+		t.Errorf("Unexpected union rows: %+v", models)
+	}
+
+	// UNION ALL keeps duplicates; Columns narrows both sides to the same list
+	qb = SelectBase("ai_model", "").Columns("Type").WhereArgs(`"ai_model".type = $1`, "chat").
+		UnionAll(SelectBase("ai_model", "").Columns("type").WhereArgs(`"ai_model".type = $1`, "chat"))
+	var types []string
+	if err := Db.Select(&types, qb.Build(), qb.Args()...); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(types) != 4 {
+		t.Errorf("Expected 4 rows with UNION ALL, got %v", types)
+	}
+
+	mismatched := SelectBase("ai_model", "").Union(SelectBase("ai_model", "").Columns("key"))
+	if err := mismatched.Validate(); err == nil {
+		t.Error("Expected an error for mismatched union columns")
+	}
+	if _, _, err := mismatched.FilterQuery(nil, nil, 10, 1); err == nil {
+		t.Error("Expected FilterQuery to return the union column mismatch")
+	}
+}
+
+// TestSoftDelete tests soft-deleting rows and excluding them from QueryBuilder reads
+func TestSoftDelete(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	realm := Realm{UUID: GenNewUUID(""), Name: "Soft Realm"}
+	insertRealm(t, realm)
+	for _, domain := range []string{"live.com", "gone.com", "also-live.com"} {
+		insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: domain, RealmUUID: realm.UUID})
+	}
+
+	n, err := SoftDelete(ctx, "website", "deleted_at", "domain = $1", "gone.com")
+	if err != nil {
+		t.Fatalf("SoftDelete failed: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 row soft-deleted, got %d", n)
+	}
+	// Already deleted rows are left alone
+	if n, err := SoftDelete(ctx, "website", "deleted_at", "domain = $1", "gone.com"); err != nil || n != 0 {
+		t.Errorf("Expected a second soft delete to affect 0 rows, got %d (%v)", n, err)
+	}
+	if _, err := SoftDelete(ctx, "website", "deleted_at", " "); err == nil {
+		t.Error("Expected error for an empty where clause")
+	}
+
+	// With a join: the condition filters base rows inside the subquery
+	qb := SelectBase("website", "").
+		ExcludeDeleted("deleted_at").
+		Left("realm", "r", "website.realm_uuid = r.uuid")
+	query := qb.Build()
+	if !strings.Contains(query, `FROM (SELECT`) || !strings.Contains(query, `"website"."deleted_at" IS NULL) AS "website"`) {
+		t.Fatalf("Expected the soft-delete condition in the base subquery, got %s", query)
+	}
+	var websites []Website
+	if err := Db.Select(&websites, query); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(websites) != 2 {
+		t.Errorf("Expected 2 live websites, got %d", len(websites))
+	}
+	for _, w := range websites {
+		if w.Domain == "gone.com" {
+			t.Error("Soft-deleted website returned")
+		}
+		if w.Realm == nil || w.Realm.Name != "Soft Realm" {
+			t.Errorf("Expected joined realm on %s", w.Domain)
+		}
+	}
+
+	// Without a join, combined with WhereArgs and FilterQuery
+	filtered, args, err := SelectBase("website", "").
+		WhereArgs(`"website".realm_uuid = $1`, realm.UUID).
+		ExcludeDeleted("deleted_at").
+		FilterQuery(&Filter{"Domain[$suffix]": "%live.com"}, nil, 10, 1)
+	if err != nil {
+		t.Fatalf("FilterQuery failed: %v", err)
+	}
+	websites = nil
+	if err := Db.Select(&websites, filtered, args...); err != nil {
+		t.Fatalf("Filtered select error: %v (%s)", err, filtered)
+	}
+	if len(websites) != 2 {
+		t.Errorf("Expected 2 filtered live websites, got %d", len(websites))
+	}
+}
+
+// TestDelete tests Delete and DeleteByField return the number of removed rows
+func TestDelete(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	name := "Named"
+	for i := 0; i < 5; i++ {
+		model := AIModel{Key: fmt.Sprintf("delete_key_%d", i), Type: "delete_type", Provider: []string{"a", "b"}[i%2]}
+		if i == 0 {
+			model.Name = &name
+		}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	n, err := Delete(ctx, "ai_model", "provider = $1 AND key != $2", "a", "delete_key_0")
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 rows deleted, got %d", n)
+	}
+	if n, err := Delete(ctx, "ai_model", "provider = $1 AND key != $2", "a", "delete_key_0"); err != nil || n != 0 {
+		t.Errorf("Expected nothing left to delete, got %d (%v)", n, err)
+	}
+	if _, err := Delete(ctx, "ai_model", ""); err == nil {
+		t.Error("Expected error for an empty where clause")
+	}
+
+	// Go field name, column name and nil values
+	if n, err := DeleteByField(ctx, "ai_model", "Provider", "b"); err != nil || n != 2 {
+		t.Errorf("Expected 2 rows deleted by field, got %d (%v)", n, err)
+	}
+	if n, err := DeleteByField(ctx, "ai_model", "name", nil); err != nil || n != 0 {
+		t.Errorf("Expected no unnamed rows left, got %d (%v)", n, err)
+	}
+	if n, err := DeleteByField(ctx, "ai_model", "key", "delete_key_0"); err != nil || n != 1 {
+		t.Errorf("Expected 1 row deleted by column, got %d (%v)", n, err)
+	}
+
+	var count int
+	if err := Db.Get(&count, `SELECT COUNT(*) FROM ai_model`); err != nil {
+		t.Fatalf("Count failed: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected all rows deleted, %d left", count)
+	}
+}
+
+// TestUpdateVersioned tests optimistic locking with concurrent writers on the same version
+func TestUpdateVersioned(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	realm := Realm{UUID: GenNewUUID(""), Name: "Versioned Realm"}
+	insertRealm(t, realm)
+
+	query, args := GetUpdateQueryVersioned("realm", map[string]interface{}{"uuid": realm.UUID, "name": "x", "version": 99}, "uuid", "version", 1)
+	if !strings.Contains(query, `"version" = "realm"."version" + 1 WHERE "realm"."uuid" = $2 AND "realm"."version" = $3`) || len(args) != 3 || args[2] != 1 {
+		t.Fatalf("Unexpected versioned query: %s %v", query, args)
+	}
+
+	// Both writers read version 1; exactly one may win
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values := map[string]interface{}{"uuid": realm.UUID, "name": fmt.Sprintf("Writer %d", i)}
+			errs[i] = UpdateVersioned(ctx, "realm", values, "uuid", "version", 1)
+		}(i)
+	}
+	wg.Wait()
+
+	conflicts, wins := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			wins++
+		case errors.Is(err, ErrVersionConflict):
+			conflicts++
+		default:
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if wins != 1 || conflicts != 1 {
+		t.Fatalf("Expected 1 win and 1 conflict, got %d wins and %d conflicts", wins, conflicts)
+	}
+
+	var version int
+	if err := SafeGet(&version, `SELECT version FROM realm WHERE uuid = $1`, realm.UUID); err != nil {
+		t.Fatalf("Failed to read version: %v", err)
+	}
+	if version != 2 {
+		t.Errorf("Expected version 2, got %d", version)
+	}
+
+	// The loser retries with the current version
+	values := map[string]interface{}{"uuid": realm.UUID, "name": "Retried"}
+	if err := UpdateVersioned(ctx, "realm", values, "uuid", "version", version); err != nil {
+		t.Fatalf("Retry failed: %v", err)
+	}
+	if values["version"] != 3 {
+		t.Errorf("Expected the new version in values, got %v", values["version"])
+	}
+
+	// A missing key column is an error, not a panic
+	if err := UpdateVersioned(ctx, "realm", map[string]interface{}{"name": "No key"}, "uuid", "version", 3); err == nil || errors.Is(err, ErrVersionConflict) {
+		t.Errorf("Expected a missing key error, got %v", err)
+	}
+}
+
+// TestGetInsertQueryReturning tests rendering several RETURNING columns
+func TestGetInsertQueryReturning(t *testing.T) {
+	cleanDatabase(t)
+
+	values := map[string]interface{}{"uuid": GenNewUUID(""), "name": "Returning Realm"}
+
+	query, _ := GetInsertQueryReturning("realm", values)
+	if strings.Contains(query, "RETURNING") {
+		t.Errorf("Expected no RETURNING clause without columns, got %s", query)
+	}
+
+	query, _ = GetInsertQuery("realm", values, "uuid")
+	if !strings.HasSuffix(query, ` RETURNING "realm".uuid`) {
+		t.Errorf("Expected GetInsertQuery to emit returning verbatim, got %s", query)
+	}
+
+	query, args := GetInsertQueryReturning("realm", values, "uuid", "created_at", "updated_at")
+	if !strings.HasSuffix(query, ` RETURNING "realm"."uuid", "realm"."created_at", "realm"."updated_at"`) {
+		t.Errorf("Unexpected multi-column RETURNING: %s", query)
+	}
+
+	var uuid string
+	var createdAt, updatedAt time.Time
+	if err := Db.QueryRow(query, args...).Scan(&uuid, &createdAt, &updatedAt); err != nil {
+		t.Fatalf("Insert with RETURNING failed: %v", err)
+	}
+	if uuid != values["uuid"] || createdAt.IsZero() || updatedAt.IsZero() {
+		t.Errorf("Unexpected returned values: %s %v %v", uuid, createdAt, updatedAt)
+	}
+}
+
+// TestGetUpsertQuery tests ON CONFLICT DO UPDATE and DO NOTHING upserts
+func TestGetUpsertQuery(t *testing.T) {
+	cleanDatabase(t)
+
+	realmUUID := GenNewUUID("")
+	upsert := func(name string, updateCols []string) (string, error) {
+		query, args := GetUpsertQuery("realm", map[string]interface{}{
+			"uuid": realmUUID,
+			"name": name,
+		}, []string{"uuid"}, updateCols, "name")
+		var returned string
+		err := Db.QueryRow(query, args...).Scan(&returned)
+		return returned, err
+	}
+
+	if name, err := upsert("First Name", []string{"name"}); err != nil || name != "First Name" {
+		t.Fatalf("Initial upsert: expected First Name, got %q (%v)", name, err)
+	}
+	if name, err := upsert("Synced Name", []string{"name"}); err != nil || name != "Synced Name" {
+		t.Fatalf("Conflicting upsert: expected Synced Name, got %q (%v)", name, err)
+	}
+
+	// DO NOTHING leaves the row alone and returns no row
+	query, _ := GetUpsertQuery("realm", map[string]interface{}{"uuid": realmUUID, "name": "Ignored"}, []string{"uuid"}, nil, "name")
+	if !strings.Contains(query, `ON CONFLICT ("uuid") DO NOTHING`) {
+		t.Errorf("Expected DO NOTHING clause, got %s", query)
+	}
+	if _, err := upsert("Ignored", nil); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("Expected no row from DO NOTHING, got %v", err)
+	}
+
+	var realms []Realm
+	if err := Db.Select(&realms, realmBaseQuery); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(realms) != 1 || realms[0].Name != "Synced Name" {
+		t.Errorf("Expected one realm named Synced Name, got %+v", realms)
+	}
+}
+
+// TestInsertColumns tests inserting from parallel column/value slices
+func TestInsertColumns(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	settings := jsonbSettings{"label": "Slice model"}
+
+	id, err := InsertColumns(ctx, "ai_model",
+		[]string{"uuid", "key", "type", "provider", "settings"},
+		[]interface{}{GenNewUUID(""), "slice_key", "slice_type", "slice_provider", settings},
+		"uuid")
+	if err != nil {
+		t.Fatalf("InsertColumns failed: %v", err)
+	}
+	if id == nil {
+		t.Fatal("Expected a returned uuid")
+	}
+
+	var model AIModel
+	if err := Db.Get(&model, `SELECT * FROM ai_model WHERE key = $1`, "slice_key"); err != nil {
+		t.Fatalf("Failed to read back row: %v", err)
+	}
+	if model.Type != "slice_type" || model.Provider != "slice_provider" || model.Settings == nil || !strings.Contains(*model.Settings, "Slice model") {
+		t.Errorf("Unexpected row: %+v", model)
+	}
+
+	// No returning column: exec only
+	if id, err := InsertColumns(ctx, "ai_model", []string{"key", "type", "provider"}, []interface{}{"slice_key_2", "t", "p"}, ""); err != nil || id != nil {
+		t.Errorf("Expected nil result without RETURNING, got %v (%v)", id, err)
+	}
+
+	if _, err := InsertColumns(ctx, "ai_model", []string{"key", "type"}, []interface{}{"k"}, ""); err == nil {
+		t.Error("Expected error for mismatched columns and values")
+	}
+}
+
+// jsonbSettings is a map Valuer that isJSONBType casts to ::jsonb
+type jsonbSettings map[string]interface{}
+
+// Value implements driver.Valuer
+func (s jsonbSettings) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// TestDumpRegisteredModels tests the registration summary of the shared test models
+func TestDumpRegisteredModels(t *testing.T) {
+	summaries := DumpRegisteredModels()
+
+	byTable := make(map[string]ModelSummary, len(summaries))
+	for i, s := range summaries {
+		if i > 0 && summaries[i-1].Table >= s.Table {
+			t.Errorf("Expected summaries sorted by table, got %s after %s", s.Table, summaries[i-1].Table)
+		}
+		byTable[s.Table] = s
+	}
+
+	tests := []struct {
+		table                                 string
+		modelType                             string
+		selectCount, insertCount, updateCount int
+		defaults                              int
+		linked                                map[string]string
+	}{
+		{"ai_model", "fsql.AIModel", 8, 8, 7, 4, nil},
+		{"realm", "fsql.Realm", 4, 4, 2, 2, nil},
+		{"website", "fsql.Website", 5, 5, 2, 2, map[string]string{"Realm": "r"}},
+	}
+
+	for _, tt := range tests {
+		s, ok := byTable[tt.table]
+		if !ok {
+			t.Errorf("Expected %s to be registered", tt.table)
+			continue
+		}
+		if s.ModelType != tt.modelType {
+			t.Errorf("%s: expected type %s, got %s", tt.table, tt.modelType, s.ModelType)
+		}
+		if s.SelectCount != tt.selectCount || s.InsertCount != tt.insertCount || s.UpdateCount != tt.updateCount {
+			t.Errorf("%s: expected select/insert/update %d/%d/%d, got %d/%d/%d", tt.table,
+				tt.selectCount, tt.insertCount, tt.updateCount, s.SelectCount, s.InsertCount, s.UpdateCount)
+		}
+		if len(s.InsertDefaults) != tt.defaults {
+			t.Errorf("%s: expected %d insert defaults, got %v", tt.table, tt.defaults, s.InsertDefaults)
+		}
+		if len(s.LinkedFields) != len(tt.linked) {
+			t.Errorf("%s: expected linked fields %v, got %v", tt.table, tt.linked, s.LinkedFields)
+		}
+		for field, alias := range tt.linked {
+			if s.LinkedFields[field] != alias {
+				t.Errorf("%s: expected %s linked as %s, got %v", tt.table, field, alias, s.LinkedFields)
+			}
+		}
+	}
+
+	if byTable["realm"].InsertDefaults["created_at"] != "NOW()" {
+		t.Errorf("Expected realm created_at default NOW(), got %v", byTable["realm"].InsertDefaults)
+	}
+}
+
+// UserProfileTyped tags its uuid column so unnest arrays are cast to uuid[]
+type UserProfileTyped struct {
+	UUID           string  `db:"uuid" dbMode:"i" dbType:"uuid"`
+	Username       string  `db:"username" dbMode:"i,u"`
+	Bio            *string `db:"bio" dbMode:"i,u"`
+	UserExperience int     `db:"user_experience" dbMode:"i,u"`
+}
+
+// TestBatchUpdateUnnest tests updating numeric and text columns through typed unnest arrays
+func TestBatchUpdateUnnest(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	ClearModelCache("user_profile")
+	InitModelTagCache(UserProfileTyped{}, "user_profile")
+	defer ClearModelCache("user_profile")
+
+	ids := make([]string, 4)
+	for i := range ids {
+		ids[i] = GenNewUUID("")
+		if _, err := SafeExec(`INSERT INTO user_profile (uuid, username, user_experience) VALUES ($1, $2, $3)`,
+			ids[i], fmt.Sprintf("user_%d", i), i); err != nil {
+			t.Fatalf("Failed to insert profile: %v", err)
+		}
+	}
+
+	bio := `quoted "bio", with \ and commas`
+	rows := []map[string]interface{}{
+		{"uuid": ids[0], "username": "renamed_0", "bio": &bio, "user_experience": 100},
+		{"uuid": ids[1], "username": "renamed_1", "bio": nil, "user_experience": 200},
+		{"uuid": ids[2], "username": "NULL", "bio": nil, "user_experience": int64(300)},
+	}
+
+	updated, err := BatchUpdateUnnest(ctx, "user_profile", []string{"username", "bio", "user_experience"}, "uuid", rows)
+	if err != nil {
+		t.Fatalf("BatchUpdateUnnest failed: %v", err)
+	}
+	if updated != 3 {
+		t.Errorf("Expected 3 rows updated, got %d", updated)
+	}
+
+	var profiles []UserProfileTyped
+	if err := Db.Select(&profiles, `SELECT uuid, username, bio, user_experience FROM user_profile ORDER BY user_experience`); err != nil {
+		t.Fatalf("Failed to read profiles: %v", err)
+	}
+	if len(profiles) != 4 {
+		t.Fatalf("Expected 4 profiles, got %d", len(profiles))
+	}
+
+	// The untouched row keeps its values and sorts first
+	if profiles[0].UUID != ids[3] || profiles[0].Username != "user_3" || profiles[0].UserExperience != 3 {
+		t.Errorf("Expected untouched profile, got %+v", profiles[0])
+	}
+	if profiles[1].Username != "renamed_0" || profiles[1].Bio == nil || *profiles[1].Bio != bio || profiles[1].UserExperience != 100 {
+		t.Errorf("Unexpected first update: %+v", profiles[1])
+	}
+	if profiles[2].Username != "renamed_1" || profiles[2].Bio != nil || profiles[2].UserExperience != 200 {
+		t.Errorf("Unexpected second update: %+v", profiles[2])
+	}
+	// A "NULL" string is data, not SQL NULL
+	if profiles[3].Username != "NULL" || profiles[3].UserExperience != 300 {
+		t.Errorf("Unexpected third update: %+v", profiles[3])
+	}
+
+	if _, err := BatchUpdateUnnest(ctx, "user_profile", []string{"username"}, "uuid", []map[string]interface{}{{"uuid": ids[0]}}); err == nil {
+		t.Error("Expected error for a row missing an update field")
+	}
+}
+
+// websiteRealmRow scans outer joins of website and realm, where either side can be NULL
+type websiteRealmRow struct {
+	UUID      *string `db:"uuid"`
+	Domain    *string `db:"domain"`
+	RealmUUID *string `db:"realm_uuid"`
+	Realm     *Realm  `db:"r"`
+}
+
+// TestOuterJoins tests RIGHT and FULL OUTER joins with all-NULL linked columns
+func TestOuterJoins(t *testing.T) {
+	cleanDatabase(t)
+
+	linked := Realm{UUID: GenNewUUID(""), Name: "Linked Realm"}
+	lonely := Realm{UUID: GenNewUUID(""), Name: "Lonely Realm"}
+	insertRealm(t, linked)
+	insertRealm(t, lonely)
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "linked.com", RealmUUID: linked.UUID})
+	if _, err := SafeExec(`INSERT INTO website (uuid, domain) VALUES ($1, $2)`, GenNewUUID(""), "orphan.com"); err != nil {
+		t.Fatalf("Failed to insert orphan website: %v", err)
+	}
+
+	qb := SelectBase("website", "").FullOuter("realm", "r", "website.realm_uuid = r.uuid")
+	query := qb.Build()
+	if !strings.Contains(query, `FULL OUTER JOIN "realm" AS r ON`) {
+		t.Fatalf("Expected a FULL OUTER JOIN, got %s", query)
+	}
+
+	var rows []websiteRealmRow
+	if err := Db.Select(&rows, query+` ORDER BY "website".domain, r.name`); err != nil {
+		t.Fatalf("FULL OUTER JOIN select failed: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(rows))
+	}
+	if rows[0].Domain == nil || *rows[0].Domain != "linked.com" || rows[0].Realm == nil || rows[0].Realm.Name != "Linked Realm" {
+		t.Errorf("Unexpected matched row: %+v", rows[0])
+	}
+	// A website without realm: every linked column is NULL, including NOT NULL ones
+	if rows[1].Domain == nil || *rows[1].Domain != "orphan.com" || rows[1].RealmUUID != nil {
+		t.Errorf("Unexpected orphan row: %+v", rows[1])
+	}
+	if rows[1].Realm != nil {
+		t.Errorf("Expected no realm for the orphan website, got %+v", rows[1].Realm)
+	}
+	// A realm without websites: base columns are NULL
+	if rows[2].UUID != nil || rows[2].Domain != nil || rows[2].Realm == nil || rows[2].Realm.Name != "Lonely Realm" {
+		t.Errorf("Unexpected realm-only row: %+v", rows[2])
+	}
+
+	// Registered models tolerate NULL linked columns too (NOT NULL realm.name included)
+	var website Website
+	if err := Db.Get(&website, `SELECT 'w' AS uuid, 'miss.com' AS domain, NULL::uuid AS "r.uuid", NULL::text AS "r.name", NULL::timestamptz AS "r.created_at"`); err != nil {
+		t.Fatalf("Scanning NULL linked columns failed: %v", err)
+	}
+	if website.Domain != "miss.com" || website.Realm != nil {
+		t.Errorf("Unexpected website: %+v", website)
+	}
+
+	rightQuery := SelectBase("website", "").Right("realm", "r", "website.realm_uuid = r.uuid").Build()
+	if !strings.Contains(rightQuery, `RIGHT JOIN "realm" AS r ON`) {
+		t.Fatalf("Expected a RIGHT JOIN, got %s", rightQuery)
+	}
+	var right []websiteRealmRow
+	if err := Db.Select(&right, rightQuery+` ORDER BY r.name`); err != nil {
+		t.Fatalf("RIGHT JOIN select failed: %v", err)
+	}
+	if len(right) != 2 || right[0].Domain == nil || *right[0].Domain != "linked.com" || right[1].UUID != nil || right[1].Realm == nil || right[1].Realm.Name != "Lonely Realm" {
+		t.Errorf("Unexpected RIGHT JOIN rows: %+v", right)
+	}
+}
+
+// TestLinkedNilOnJoinMiss tests that linked pointers stay nil when all their columns are NULL
+func TestLinkedNilOnJoinMiss(t *testing.T) {
+	cleanDatabase(t)
+
+	realm := Realm{UUID: GenNewUUID(""), Name: "Present Realm"}
+	insertRealm(t, realm)
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "present.com", RealmUUID: realm.UUID})
+	if _, err := SafeExec(`INSERT INTO website (uuid, domain) VALUES ($1, $2)`, GenNewUUID(""), "missing.com"); err != nil {
+		t.Fatalf("Failed to insert website without realm: %v", err)
+	}
+
+	var rows []*websiteRealmRow
+	if err := Db.Select(&rows, websiteBaseQuery+` ORDER BY "website".domain`); err != nil {
+		t.Fatalf("LEFT JOIN select failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+	if rows[0].Realm != nil {
+		t.Errorf("Expected a nil realm for a LEFT JOIN miss, got %+v", rows[0].Realm)
+	}
+	if rows[1].Realm == nil || rows[1].Realm.UUID != realm.UUID || rows[1].Realm.CreatedAt.IsZero() {
+		t.Errorf("Expected the joined realm, got %+v", rows[1].Realm)
+	}
+
+	// Single-row scans reset a previously set pointer too
+	website := Website{Realm: &Realm{Name: "stale"}}
+	if err := Db.Get(&website, `SELECT 'w' AS uuid, 'miss.com' AS domain, NULL::uuid AS "r.uuid", NULL::text AS "r.name", NULL::timestamptz AS "r.created_at"`); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if website.Realm != nil {
+		t.Errorf("Expected a nil realm, got %+v", website.Realm)
+	}
+
+	// A partially NULL linked row is kept
+	if err := Db.Get(&website, `SELECT 'w' AS uuid, 'partial.com' AS domain, NULL::uuid AS "r.uuid", 'Named' AS "r.name"`); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if website.Realm == nil || website.Realm.Name != "Named" || website.Realm.UUID != "" {
+		t.Errorf("Expected a realm with only its name set, got %+v", website.Realm)
+	}
+}
+
+// TestInsertSelectQuery tests INSERT ... SELECT built from the destination model
+func TestInsertSelectQuery(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	insertRealm(t, Realm{UUID: GenNewUUID(""), Name: "Source A"})
+	insertRealm(t, Realm{UUID: GenNewUUID(""), Name: "Source B"})
+
+	query := GetInsertSelectQuery("realm", []string{"UUID", "name"},
+		`SELECT uuid_generate_v4(), name || ' copy' FROM realm WHERE name LIKE $1`)
+	if query != `INSERT INTO "realm" ("uuid", "name") SELECT uuid_generate_v4(), name || ' copy' FROM realm WHERE name LIKE $1` {
+		t.Errorf("Unexpected query: %s", query)
+	}
+
+	err := WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+		tag, err := tx.Exec(query, "Source%")
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() != 2 {
+			return fmt.Errorf("expected 2 rows copied, got %d", tag.RowsAffected())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("INSERT ... SELECT failed: %v", err)
+	}
+
+	var copies []Realm
+	if err := Db.Select(&copies, realmBaseQuery+` WHERE name LIKE '% copy' ORDER BY name`); err != nil {
+		t.Fatalf("Select error: %v", err)
+	}
+	if len(copies) != 2 || copies[0].Name != "Source A copy" || copies[0].CreatedAt.IsZero() {
+		t.Errorf("Unexpected copies: %+v", copies)
+	}
+
+	// Columns from the struct's insert tags: uuid, created_at, updated_at, name
+	objectQuery := GetInsertSelectQueryObject(Realm{}, "realm", `SELECT uuid_generate_v4(), created_at, NOW(), name FROM realm`)
+	if !strings.HasPrefix(objectQuery, `INSERT INTO "realm" ("uuid", "created_at", "updated_at", "name") SELECT`) {
+		t.Errorf("Unexpected object query: %s", objectQuery)
+	}
+
+	for _, cols := range [][]string{{"version"}, {"missing"}, nil} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected a panic for columns %v", cols)
+				}
+			}()
+			GetInsertSelectQuery("realm", cols, `SELECT 1`)
+		}()
+	}
+}
+
+// TestCountSimpleAndExists tests single-table COUNT and EXISTS helpers
+func TestCountSimpleAndExists(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		model := AIModel{Key: fmt.Sprintf("exists_key_%d", i), Type: "exists_type", Provider: []string{"a", "b", "b"}[i]}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	if count, err := CountSimple(ctx, "ai_model", "provider = $1", "b"); err != nil || count != 2 {
+		t.Errorf("Expected 2 rows, got %d (%v)", count, err)
+	}
+	if count, err := CountSimple(ctx, "ai_model", ""); err != nil || count != 3 {
+		t.Errorf("Expected 3 rows without a where clause, got %d (%v)", count, err)
+	}
+
+	if exists, err := Exists(ctx, "ai_model", "key = $1", "exists_key_1"); err != nil || !exists {
+		t.Errorf("Expected the row to exist (%v)", err)
+	}
+	if exists, err := Exists(ctx, "ai_model", "key = $1", "missing"); err != nil || exists {
+		t.Errorf("Expected no row (%v)", err)
+	}
+	if _, err := Exists(ctx, "missing_table", ""); err == nil {
+		t.Error("Expected error for a missing table")
+	}
+
+	query := BuildExistsQuery(aiModelBaseQuery + ` WHERE "ai_model".provider = $1`)
+	if !strings.HasPrefix(query, "SELECT EXISTS(SELECT 1 FROM (") {
+		t.Errorf("Unexpected exists query: %s", query)
+	}
+	var exists bool
+	if err := Db.Get(&exists, query, "a"); err != nil || !exists {
+		t.Errorf("Expected BuildExistsQuery to find a row, got %v (%v)", exists, err)
+	}
+	if err := Db.Get(&exists, query, "c"); err != nil || exists {
+		t.Errorf("Expected BuildExistsQuery to find nothing, got %v (%v)", exists, err)
+	}
+}
@@ -25,7 +25,8 @@ CREATE TABLE realm (
     uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    name TEXT NOT NULL
+    name TEXT NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1
 );
 
 CREATE TABLE website (
@@ -33,7 +34,15 @@ CREATE TABLE website (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     domain TEXT NOT NULL,
-    realm_uuid UUID REFERENCES realm(uuid)
+    realm_uuid UUID REFERENCES realm(uuid),
+    deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE tagged_item (
+    uuid UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tags TEXT[],
+    scores BIGINT[],
+    related UUID[]
 );
 
 CREATE TABLE user_profile (
@@ -0,0 +1,59 @@
+// health.go - Structured health status for readiness probes
+package fsql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// HealthCheckTimeout bounds the ping done by HealthCheck
+	HealthCheckTimeout = 2 * time.Second
+
+	// HealthConnectionThreshold is the share of the pool's max connections in use (percent)
+	// above which HealthCheck reports AboveConnectionThreshold (0 = never)
+	HealthConnectionThreshold float64 = 90
+)
+
+// HealthStatus describes the primary database's health
+type HealthStatus struct {
+	Healthy                  bool          // Ping succeeded within HealthCheckTimeout
+	PingLatency              time.Duration // Round trip of the ping (time spent until failure otherwise)
+	MaxConns                 int32         // Configured pool size
+	AboveConnectionThreshold bool          // ActiveConns exceeds HealthConnectionThreshold percent of MaxConns
+	PoolPressure
+}
+
+// HealthCheck pings the primary and reports pool pressure, for readiness endpoints.
+// The ping is bounded by HealthCheckTimeout (or ctx, if sooner), so it never blocks indefinitely.
+// A failed ping returns the status alongside the error.
+func HealthCheck(ctx context.Context) (HealthStatus, error) {
+	pool := DB
+	if pool == nil {
+		return HealthStatus{}, errors.New("database not initialized")
+	}
+
+	status := HealthStatus{
+		MaxConns:     pool.Stat().MaxConns(),
+		PoolPressure: GetPoolPressure(),
+	}
+	if HealthConnectionThreshold > 0 && status.MaxConns > 0 {
+		usage := float64(status.ActiveConns) / float64(status.MaxConns) * 100
+		status.AboveConnectionThreshold = usage > HealthConnectionThreshold
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := pool.Ping(pingCtx)
+	status.PingLatency = time.Since(start)
+	if err != nil {
+		return status, fmt.Errorf("health check ping failed: %w", err)
+	}
+
+	status.Healthy = true
+	return status, nil
+}
@@ -0,0 +1,86 @@
+// health_test.go
+package fsql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TestHealthCheck tests the structured health status and its connection threshold
+func TestHealthCheck(t *testing.T) {
+	ctx := context.Background()
+
+	status, err := HealthCheck(ctx)
+	if err != nil {
+		t.Fatalf("HealthCheck failed: %v", err)
+	}
+	if !status.Healthy || status.PingLatency <= 0 || status.PingLatency > HealthCheckTimeout {
+		t.Errorf("Expected a healthy status with a ping latency, got %+v", status)
+	}
+	if status.MaxConns <= 0 || status.TotalConns <= 0 {
+		t.Errorf("Expected pool stats, got %+v", status)
+	}
+
+	// Holding a connection while any use counts as above the threshold
+	defer func(threshold float64) { HealthConnectionThreshold = threshold }(HealthConnectionThreshold)
+	HealthConnectionThreshold = 0.0001
+	conn, err := DB.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	status, err = HealthCheck(ctx)
+	conn.Release()
+	if err != nil || !status.AboveConnectionThreshold || status.ActiveConns < 1 {
+		t.Errorf("Expected to be above the connection threshold, got %+v (%v)", status, err)
+	}
+
+	// An already expired context fails fast instead of blocking
+	expired, cancel := context.WithTimeout(ctx, time.Nanosecond)
+	defer cancel()
+	<-expired.Done()
+	status, err = HealthCheck(expired)
+	if err == nil || status.Healthy {
+		t.Errorf("Expected an unhealthy status for an expired context, got %+v", status)
+	}
+}
+
+// TestPreparedPool tests that a prepared pool caches statements per connection
+func TestPreparedPool(t *testing.T) {
+	ctx := context.Background()
+
+	pool, err := newPool(DB.Config().ConnConfig.ConnString(), 1, 1, true)
+	if err != nil {
+		t.Fatalf("newPool failed: %v", err)
+	}
+	defer pool.Close()
+
+	if mode := pool.Config().ConnConfig.DefaultQueryExecMode; mode != pgx.QueryExecModeCacheStatement {
+		t.Fatalf("Expected cached statements, got %v", mode)
+	}
+	if mode := DB.Config().ConnConfig.DefaultQueryExecMode; mode != pgx.QueryExecModeSimpleProtocol {
+		t.Errorf("Expected the default pool to keep the simple protocol, got %v", mode)
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	defer conn.Release()
+
+	for i := 1; i <= 2; i++ {
+		var n int
+		if err := conn.QueryRow(ctx, "SELECT $1::int + 1", i).Scan(&n); err != nil || n != i+1 {
+			t.Fatalf("Prepared query failed: %d (%v)", n, err)
+		}
+	}
+	var prepared int
+	if err := conn.QueryRow(ctx, "SELECT count(*) FROM pg_prepared_statements WHERE statement = 'SELECT $1::int + 1'").Scan(&prepared); err != nil {
+		t.Fatalf("Failed to read pg_prepared_statements: %v", err)
+	}
+	if prepared != 1 {
+		t.Errorf("Expected the query prepared once, got %d", prepared)
+	}
+}
@@ -173,8 +173,31 @@ func (c *JoinResultCache) Set(key string, value interface{}) {
 	c.expiration[key] = time.Now().Add(c.ttl)
 }
 
+// joinHintsEnabled toggles pg_hint_plan comment injection (0 = off, 1 = on)
+var joinHintsEnabled int32
+
+// SetJoinHintsEnabled enables or disables appending planner hint comments to join queries.
+// Hints only have an effect with the pg_hint_plan extension, so they are off by default.
+func SetJoinHintsEnabled(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&joinHintsEnabled, v)
+}
+
+// JoinHintsEnabled reports whether join planner hints are enabled
+func JoinHintsEnabled() bool {
+	return atomic.LoadInt32(&joinHintsEnabled) == 1
+}
+
 // OptimizeJoinQuery optimizes a join query for better performance
+// The query is returned unchanged unless hints are enabled with SetJoinHintsEnabled
 func OptimizeJoinQuery(query string) string {
+	if !JoinHintsEnabled() {
+		return query
+	}
+
 	isSimpleJoin := strings.Contains(query, "JOIN") && !strings.Contains(query, "OUTER JOIN")
 
 	if isSimpleJoin {
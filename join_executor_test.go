@@ -0,0 +1,47 @@
+package fsql
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestJoinHintsDisabledByDefault tests that join queries are not rewritten unless hints are enabled
+func TestJoinHintsDisabledByDefault(t *testing.T) {
+	query := websiteBaseQuery
+
+	if JoinHintsEnabled() {
+		t.Fatal("Expected join hints to be disabled by default")
+	}
+	if got := OptimizeJoinQuery(query); got != query {
+		t.Errorf("Expected query unchanged, got %s", got)
+	}
+
+	SetJoinHintsEnabled(true)
+	defer SetJoinHintsEnabled(false)
+
+	if got := OptimizeJoinQuery(query); !strings.HasSuffix(got, "/*+ HASH_JOIN */") {
+		t.Errorf("Expected hint comment when enabled, got %s", got)
+	}
+}
+
+// TestExecuteJoinQueryWithoutHints tests ExecuteJoinQuery runs the query as given
+func TestExecuteJoinQueryWithoutHints(t *testing.T) {
+	cleanDatabase(t)
+
+	realm := Realm{UUID: GenNewUUID(""), Name: "Join Realm"}
+	insertRealm(t, realm)
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "join.com", RealmUUID: realm.UUID})
+
+	var websites []Website
+	err := ExecuteJoinQuery(context.Background(), websiteBaseQuery, nil, &websites)
+	if err != nil {
+		t.Fatalf("ExecuteJoinQuery failed: %v", err)
+	}
+	if len(websites) != 1 {
+		t.Fatalf("Expected 1 website, got %d", len(websites))
+	}
+	if websites[0].Realm == nil || websites[0].Realm.Name != "Join Realm" {
+		t.Errorf("Expected linked realm to be scanned, got %+v", websites[0].Realm)
+	}
+}
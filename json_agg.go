@@ -0,0 +1,125 @@
+// json_agg.go - Loading one-to-many children as a json_agg column
+package fsql
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/jmoiron/sqlx/reflectx"
+)
+
+// JSONAggColumn wraps childQuery in a correlated json_agg subquery selected as alias:
+//
+//	(SELECT COALESCE(json_agg("alias"), '[]') FROM (childQuery) AS "alias") AS "alias"
+//
+// childQuery refers to the parent row by its table name (e.g. WHERE "website".realm_uuid =
+// "realm".uuid), and its output columns become the JSON keys. Scan it into a slice field
+// tagged db:"alias" dbMode:"agg", whose elements are filled by their db tags.
+func JSONAggColumn(childQuery string, alias string) (string, error) {
+	quoted, err := QuoteIdentifier(alias)
+	if err != nil {
+		return "", fmt.Errorf("invalid json_agg alias: %w", err)
+	}
+	return fmt.Sprintf(`(SELECT COALESCE(json_agg(%s), '[]') FROM (%s) AS %s) AS %s`, quoted, childQuery, quoted, quoted), nil
+}
+
+// JSONAgg adds childQuery's rows to the select list as a JSON array column named alias
+// (see JSONAggColumn), loading the children of every parent row in the same query.
+// childQuery can't take args of its own; an invalid alias panics.
+func (qb *QueryBuilder) JSONAgg(alias string, childQuery string) *QueryBuilder {
+	column, err := JSONAggColumn(childQuery, alias)
+	if err != nil {
+		panic(err.Error())
+	}
+	qb.AggColumns = append(qb.AggColumns, column)
+	return qb
+}
+
+// jsonAggScanner decodes a json_agg array into a slice field, mapping each object's keys
+// onto the element's db tags (nested "alias.column" keys reach linked structs)
+type jsonAggScanner struct {
+	field reflect.Value
+}
+
+func (s *jsonAggScanner) Scan(src interface{}) error {
+	if src == nil {
+		s.field.Set(reflect.Zero(s.field.Type()))
+		return nil
+	}
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("json_agg column: expected JSON, got %T", src)
+	}
+
+	sliceType := s.field.Type()
+	if sliceType.Kind() != reflect.Slice {
+		return fmt.Errorf("json_agg column: field must be a slice, got %s", sliceType)
+	}
+	elemType := sliceType.Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	if elemType.Kind() != reflect.Struct {
+		return fmt.Errorf("json_agg column: slice elements must be structs, got %s", sliceType.Elem())
+	}
+
+	var objects []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &objects); err != nil {
+		return fmt.Errorf("json_agg column: %w", err)
+	}
+
+	tm := mapper.TypeMap(elemType)
+	slice := reflect.MakeSlice(sliceType, 0, len(objects))
+	for _, object := range objects {
+		elem := reflect.New(elemType)
+		for key, raw := range object {
+			fi := tm.GetByPath(key)
+			if fi == nil {
+				continue
+			}
+			if err := decodeJSONAggValue(reflectx.FieldByIndexes(elem.Elem(), fi.Index), raw); err != nil {
+				return fmt.Errorf("json_agg column: key %s: %w", key, err)
+			}
+		}
+		if isPtr {
+			slice = reflect.Append(slice, elem)
+		} else {
+			slice = reflect.Append(slice, elem.Elem())
+		}
+	}
+	s.field.Set(slice)
+	return nil
+}
+
+// decodeJSONAggValue sets field from one JSON value. Registered decoders and sql.Scanners get
+// the value as encoding/json decodes it (objects and arrays as raw JSON []byte, like jsonb
+// columns); everything else is unmarshalled directly.
+func decodeJSONAggValue(field reflect.Value, raw json.RawMessage) error {
+	if bytes.Equal(raw, []byte("null")) {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+
+	var value interface{} = []byte(raw)
+	if raw[0] != '{' && raw[0] != '[' {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+	}
+
+	if decode, ok := typeDecoderFor(field.Type()); ok {
+		return (&decoderScanner{decode: decode, field: field}).Scan(value)
+	}
+	if field.Kind() == reflect.Ptr && field.Type().Implements(scannerType) {
+		field.Set(reflect.New(field.Type().Elem()))
+		return field.Interface().(sql.Scanner).Scan(value)
+	}
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+	return json.Unmarshal(raw, field.Addr().Interface())
+}
@@ -0,0 +1,125 @@
+// jsonb.go - Partial updates of JSONB columns
+package fsql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// jsonbDeleteMarker is the type of JSONBDelete
+type jsonbDeleteMarker struct{}
+
+// JSONBDelete is a patch value that removes the key at its path instead of setting it
+var JSONBDelete = jsonbDeleteMarker{}
+
+// UpdateJSONBField sets (or removes, with JSONBDelete) a single path inside a JSONB column
+// path is dot-separated for nested keys, e.g. "notifications.email"
+func UpdateJSONBField(ctx context.Context, table, column, keyColumn string, key interface{}, path string, value interface{}) error {
+	return UpdateJSONBFields(ctx, table, column, keyColumn, key, map[string]interface{}{path: value})
+}
+
+// UpdateJSONBFields applies several path updates to a JSONB column in one UPDATE statement,
+// so either all patches land or none do. Keys untouched by the patches are preserved.
+// Patch keys are dot-separated paths; a JSONBDelete value removes the key.
+// Nested paths are created only if their parent object already exists (jsonb_set semantics).
+func UpdateJSONBFields(ctx context.Context, table, column, keyColumn string, key interface{}, patches map[string]interface{}) error {
+	query, args, err := buildJSONBPatchQuery(table, column, keyColumn, key, patches)
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("jsonb update failed: %w", err)
+	}
+
+	return nil
+}
+
+// buildJSONBPatchQuery builds the UPDATE for UpdateJSONBFields
+func buildJSONBPatchQuery(table, column, keyColumn string, key interface{}, patches map[string]interface{}) (string, []interface{}, error) {
+	if len(patches) == 0 {
+		return "", nil, errors.New("no jsonb patches given")
+	}
+
+	quotedTable, err := QuoteIdentifier(table)
+	if err != nil {
+		return "", nil, err
+	}
+	quotedColumn, err := QuoteIdentifier(column)
+	if err != nil {
+		return "", nil, err
+	}
+	quotedKey, err := QuoteIdentifier(keyColumn)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Sort paths so the generated SQL is stable
+	paths := make([]string, 0, len(patches))
+	for path := range patches {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	args := make([]interface{}, 0, len(patches)*2+1)
+	expr := fmt.Sprintf(`COALESCE(%s, '{}'::jsonb)`, quotedColumn)
+
+	for _, path := range paths {
+		pathLiteral, err := jsonbPathLiteral(path)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, pathLiteral)
+		pathIdx := len(args)
+
+		value := patches[path]
+		if _, ok := value.(jsonbDeleteMarker); ok {
+			expr = fmt.Sprintf(`(%s #- $%d::text[])`, expr, pathIdx)
+			continue
+		}
+
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to encode jsonb value for %s: %w", path, err)
+		}
+		args = append(args, string(encoded))
+		expr = fmt.Sprintf(`jsonb_set(%s, $%d::text[], $%d::jsonb, true)`, expr, pathIdx, len(args))
+	}
+
+	args = append(args, key)
+	query := fmt.Sprintf(`UPDATE %s SET %s = %s WHERE %s = $%d`, quotedTable, quotedColumn, expr, quotedKey, len(args))
+
+	return query, args, nil
+}
+
+// jsonbPathLiteral converts "a.b.c" to the Postgres text[] literal {"a","b","c"}
+func jsonbPathLiteral(path string) (string, error) {
+	parts := strings.Split(path, ".")
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, part := range parts {
+		if part == "" {
+			return "", fmt.Errorf("invalid jsonb path: %q", path)
+		}
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('"')
+		for _, r := range part {
+			if r == '"' || r == '\\' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteRune(r)
+		}
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+
+	return sb.String(), nil
+}
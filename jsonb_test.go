@@ -0,0 +1,146 @@
+// jsonb_test.go
+package fsql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestUpdateJSONBFields tests updating several JSON keys atomically
+func TestUpdateJSONBFields(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	settings := `{"theme": "dark", "lang": "en", "notifications": {"email": true, "sms": false}, "legacy": 1}`
+	model := AIModel{Key: "jsonb_key", Type: "test", Provider: "test", Settings: &settings}
+	if err := model.Insert(); err != nil {
+		t.Fatalf("Failed to insert model: %v", err)
+	}
+
+	err := UpdateJSONBFields(ctx, "ai_model", "settings", "uuid", model.UUID, map[string]interface{}{
+		"theme":               "light",
+		"notifications.email": false,
+		"legacy":              JSONBDelete,
+	})
+	if err != nil {
+		t.Fatalf("UpdateJSONBFields failed: %v", err)
+	}
+
+	var raw string
+	if err := Db.Get(&raw, `SELECT settings::text FROM ai_model WHERE uuid = $1`, model.UUID); err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &got); err != nil {
+		t.Fatalf("Failed to decode settings: %v", err)
+	}
+
+	if got["theme"] != "light" {
+		t.Errorf("Expected theme light, got %v", got["theme"])
+	}
+	if got["lang"] != "en" {
+		t.Errorf("Expected untouched lang en, got %v", got["lang"])
+	}
+	if _, ok := got["legacy"]; ok {
+		t.Errorf("Expected legacy to be removed, got %v", got["legacy"])
+	}
+	notifications, _ := got["notifications"].(map[string]interface{})
+	if notifications["email"] != false || notifications["sms"] != false {
+		t.Errorf("Expected email=false and untouched sms=false, got %v", notifications)
+	}
+
+	// Single-path helper on a NULL column starts from an empty object
+	other := AIModel{Key: "jsonb_null", Type: "test", Provider: "test"}
+	if err := other.Insert(); err != nil {
+		t.Fatalf("Failed to insert model: %v", err)
+	}
+	if err := UpdateJSONBField(ctx, "ai_model", "settings", "uuid", other.UUID, "theme", "dark"); err != nil {
+		t.Fatalf("UpdateJSONBField failed: %v", err)
+	}
+	var theme string
+	if err := Db.Get(&theme, `SELECT settings->>'theme' FROM ai_model WHERE uuid = $1`, other.UUID); err != nil {
+		t.Fatalf("Failed to read theme: %v", err)
+	}
+	if theme != "dark" {
+		t.Errorf("Expected theme dark, got %q", theme)
+	}
+}
+
+// TestBuildJSONBPatchQuery tests the generated SQL and argument order
+func TestBuildJSONBPatchQuery(t *testing.T) {
+	query, args, err := buildJSONBPatchQuery("ai_model", "settings", "uuid", "id-1", map[string]interface{}{
+		"b.c": JSONBDelete,
+		"a":   1,
+	})
+	if err != nil {
+		t.Fatalf("buildJSONBPatchQuery failed: %v", err)
+	}
+
+	expected := `UPDATE "ai_model" SET "settings" = (jsonb_set(COALESCE("settings", '{}'::jsonb), $1::text[], $2::jsonb, true) #- $3::text[]) WHERE "uuid" = $4`
+	if query != expected {
+		t.Errorf("Unexpected query:\n got: %s\nwant: %s", query, expected)
+	}
+	if len(args) != 4 || args[0] != `{"a"}` || args[1] != "1" || args[2] != `{"b","c"}` || args[3] != "id-1" {
+		t.Errorf("Unexpected args: %v", args)
+	}
+
+	if _, _, err := buildJSONBPatchQuery("ai_model", "settings", "uuid", "id-1", nil); err == nil {
+		t.Error("Expected error for empty patches")
+	}
+	if _, _, err := buildJSONBPatchQuery("ai_model", "settings", "uuid", "id-1", map[string]interface{}{"a..b": 1}); err == nil {
+		t.Error("Expected error for empty path segment")
+	}
+}
+
+// typedColumnsModel declares its column types instead of relying on JSONB detection
+type typedColumnsModel struct {
+	UUID string                 `db:"uuid" dbMode:"i" dbType:"uuid"`
+	Meta map[string]interface{} `db:"meta" dbMode:"i,u" dbType:"jsonb"`
+	Note *string                `db:"note" dbMode:"i,u" dbType:"jsonb"`
+}
+
+// TestDbTypeCasts tests that insert and update queries cast values per their dbType tags
+func TestDbTypeCasts(t *testing.T) {
+	ctx := context.Background()
+	if _, err := SafeExec(`CREATE TABLE IF NOT EXISTS typed_columns (uuid UUID PRIMARY KEY, meta JSONB, note JSONB)`); err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	defer SafeExec(`DROP TABLE IF EXISTS typed_columns`)
+	InitModelTagCache(typedColumnsModel{}, "typed_columns")
+	defer ClearModelCache("typed_columns")
+
+	id := GenNewUUID("")
+	var note *string
+	query, args := GetInsertQuery("typed_columns", map[string]interface{}{"uuid": id, "meta": map[string]interface{}{"plan": "free"}, "note": note}, "uuid")
+	if query != `INSERT INTO "typed_columns" ("uuid","meta","note") VALUES ($1::uuid,$2::jsonb,$3::jsonb) RETURNING "typed_columns".uuid` {
+		t.Errorf("Unexpected insert query: %s", query)
+	}
+	if args[1] != `{"plan":"free"}` || args[2] != nil {
+		t.Errorf("Expected marshalled JSON and NULL args, got %v", args)
+	}
+	if _, err := DB.Exec(ctx, query, args...); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+
+	query, args, err := GetUpdateQuerySafe("typed_columns", map[string]interface{}{"uuid": id, "meta": []byte(`{"plan": "pro"}`)}, "uuid")
+	if err != nil {
+		t.Fatalf("GetUpdateQuerySafe failed: %v", err)
+	}
+	if !strings.Contains(query, `SET "meta" = $1::jsonb`) {
+		t.Errorf("Unexpected update query: %s", query)
+	}
+	if _, err := DB.Exec(ctx, query, args...); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	var plan string
+	if err := Db.Get(&plan, `SELECT meta->>'plan' FROM typed_columns WHERE uuid = $1`, id); err != nil {
+		t.Fatalf("Failed to read meta: %v", err)
+	}
+	if plan != "pro" {
+		t.Errorf("Expected plan pro, got %q", plan)
+	}
+}
@@ -0,0 +1,93 @@
+// listen.go - LISTEN/NOTIFY subscriptions on a dedicated connection
+package fsql
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Notification is a NOTIFY received by Listen
+type Notification struct {
+	Channel string
+	Payload string
+	PID     uint32 // backend PID of the notifying session
+}
+
+// ListenReconnectDelay is how long Listen waits between attempts to re-establish a dropped connection
+var ListenReconnectDelay = time.Second
+
+// Listen subscribes to channel on a dedicated pool connection and streams its notifications
+// until ctx is cancelled, when it UNLISTENs, releases the connection and closes the returned
+// channel. If the connection drops, Listen re-acquires one and LISTENs again every
+// ListenReconnectDelay; notifications sent while disconnected are lost, so treat a reconnect
+// as a cue to resync (e.g. flush the whole cache). Receive promptly: a slow reader holds
+// back later notifications.
+func Listen(ctx context.Context, channel string) (<-chan Notification, error) {
+	quoted, err := QuoteIdentifier(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := listenConn(ctx, quoted)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Notification)
+	go listenLoop(ctx, conn, quoted, out)
+	return out, nil
+}
+
+// listenLoop forwards notifications from conn to out, reconnecting when conn drops
+func listenLoop(ctx context.Context, conn *Conn, quoted string, out chan<- Notification) {
+	defer close(out)
+	for {
+		n, err := conn.Raw().WaitForNotification(ctx)
+		if err == nil {
+			select {
+			case out <- Notification{Channel: n.Channel, Payload: n.Payload, PID: n.PID}:
+				continue
+			case <-ctx.Done():
+			}
+		}
+		releaseListenConn(conn, quoted)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// The connection dropped: keep retrying until LISTEN is re-established or ctx ends
+		for {
+			select {
+			case <-time.After(ListenReconnectDelay):
+			case <-ctx.Done():
+				return
+			}
+			if conn, err = listenConn(ctx, quoted); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// listenConn acquires a dedicated connection and LISTENs on the quoted channel
+func listenConn(ctx context.Context, quoted string) (*Conn, error) {
+	conn, err := AcquireConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "LISTEN "+quoted); err != nil {
+		conn.Release()
+		return nil, fmt.Errorf("listen failed: %w", err)
+	}
+	return conn, nil
+}
+
+// releaseListenConn UNLISTENs and returns conn to the pool. LISTEN survives Release, so it
+// has to be cleared before the session goes back (a dead connection is simply discarded).
+func releaseListenConn(conn *Conn, quoted string) {
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn.ExecContext(cleanupCtx, "UNLISTEN "+quoted)
+	conn.Release()
+}
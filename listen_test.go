@@ -0,0 +1,76 @@
+// listen_test.go
+package fsql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestListen tests receiving NOTIFYs, reconnecting after the session is killed, and shutdown on cancel
+func TestListen(t *testing.T) {
+	cleanDatabase(t)
+
+	oldDelay := ListenReconnectDelay
+	ListenReconnectDelay = 50 * time.Millisecond
+	defer func() { ListenReconnectDelay = oldDelay }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := Listen(ctx, ""); err == nil {
+		t.Error("Expected an error for an empty channel name")
+	}
+
+	notifications, err := Listen(ctx, "fsql_listen_test")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	receive := func() (Notification, bool) {
+		select {
+		case n, ok := <-notifications:
+			return n, ok
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for a notification")
+			return Notification{}, false
+		}
+	}
+
+	if _, err := SafeExec("SELECT pg_notify('fsql_listen_test', 'hello')"); err != nil {
+		t.Fatalf("NOTIFY failed: %v", err)
+	}
+	n, ok := receive()
+	if !ok || n.Channel != "fsql_listen_test" || n.Payload != "hello" || n.PID == 0 {
+		t.Fatalf("Unexpected notification: %+v (open: %v)", n, ok)
+	}
+
+	// Kill the listening session; Listen should re-LISTEN on a fresh connection
+	if _, err := SafeExec(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		WHERE query = 'LISTEN "fsql_listen_test"' AND pid <> pg_backend_pid()`); err != nil {
+		t.Fatalf("Failed to terminate the listener: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := SafeExec("SELECT pg_notify('fsql_listen_test', 'again')"); err != nil {
+			t.Fatalf("NOTIFY failed: %v", err)
+		}
+		select {
+		case n, ok := <-notifications:
+			if !ok || n.Payload != "again" {
+				t.Fatalf("Unexpected notification after reconnect: %+v (open: %v)", n, ok)
+			}
+		case <-time.After(100 * time.Millisecond):
+			if time.Now().After(deadline) {
+				t.Fatal("Listen did not reconnect")
+			}
+			continue
+		}
+		break
+	}
+
+	cancel()
+	for range notifications {
+		// drain anything sent before the cancel until the channel closes
+	}
+}
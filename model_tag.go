@@ -2,9 +2,12 @@
 package fsql
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"sync"
+	"time"
 )
 
 // ModelTagCache is a cache for model field information
@@ -105,4 +108,107 @@ func getModelTagCache(modelType reflect.Type) (*ModelTagCache, error) {
 	
 	// Initialize and retrieve from cache
 	return InitModelTagCacheForType(modelType)
-}
\ No newline at end of file
+}
+
+// HasMode reports whether the field's dbMode tag lists mode (e.g. "i", "u", "pk")
+func (f ModelField) HasMode(mode string) bool {
+	for _, m := range strings.Split(f.Mode, ",") {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// writable reports whether the field is a real column (not skipped, a linked struct or a json_agg slice)
+func (f ModelField) writable() bool {
+	return !f.HasMode("s") && !f.HasMode("l") && !f.HasMode("link") && !f.HasMode("agg")
+}
+
+// StructToInsertMap returns the "i" mode fields of obj (a struct or pointer to one) keyed by
+// column, ready for GetInsertQuery. A zero field with a dbInsertValue default is left out so
+// the insert applies the default (e.g. NOW() for created_at).
+func StructToInsertMap(obj interface{}) (map[string]interface{}, error) {
+	return structToMap(obj, func(f ModelField, v reflect.Value) bool {
+		return f.HasMode("i") && (f.InsertValue == "" || !v.IsZero())
+	})
+}
+
+// StructToUpdateMap returns the "u" mode fields of obj keyed by column, plus its "pk" field
+// so the map can be passed straight to GetUpdateQuery with the primary key as returning column
+func StructToUpdateMap(obj interface{}) (map[string]interface{}, error) {
+	return structToMap(obj, func(f ModelField, v reflect.Value) bool {
+		return f.HasMode("u") || f.HasMode("pk")
+	})
+}
+
+// structToMap collects the writable fields of obj accepted by include, keyed by column
+func structToMap(obj interface{}, include func(ModelField, reflect.Value) bool) (map[string]interface{}, error) {
+	val := reflect.ValueOf(obj)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return nil, errors.New("object is nil")
+		}
+		val = val.Elem()
+	}
+
+	tagCache, err := getModelTagCache(val.Type())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model tag cache: %w", err)
+	}
+
+	values := make(map[string]interface{}, len(tagCache.Fields))
+	for _, field := range tagCache.Fields {
+		if !field.writable() {
+			continue
+		}
+		fieldVal := val.FieldByName(field.Name)
+		if include(field, fieldVal) {
+			values[field.DbName] = fieldVal.Interface()
+		}
+	}
+	return values, nil
+}
+
+// DiffUpdateMap returns the "u" mode fields whose value differs between old and new (two values
+// of the same struct type, or pointers to them), keyed by column and holding new's value. Pointer
+// fields differ when exactly one is nil or their targets differ; times are compared with Equal.
+// Add the key column and pass the map to GetUpdateQuery to write only what changed.
+// It panics if old and new are not the same struct type.
+func DiffUpdateMap(old, new interface{}) map[string]interface{} {
+	oldVal, newVal := reflect.Indirect(reflect.ValueOf(old)), reflect.Indirect(reflect.ValueOf(new))
+	if !oldVal.IsValid() || !newVal.IsValid() || oldVal.Type() != newVal.Type() || oldVal.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("DiffUpdateMap needs two values of the same struct type, got %T and %T", old, new))
+	}
+
+	tagCache, err := getModelTagCache(oldVal.Type())
+	if err != nil {
+		panic(err.Error())
+	}
+
+	changes := make(map[string]interface{})
+	for _, field := range tagCache.Fields {
+		if !field.HasMode("u") || !field.writable() {
+			continue
+		}
+		oldField, newField := oldVal.FieldByName(field.Name), newVal.FieldByName(field.Name)
+		if !fieldValuesEqual(oldField, newField) {
+			changes[field.DbName] = newField.Interface()
+		}
+	}
+	return changes
+}
+
+// fieldValuesEqual compares two values of the same type, following pointers
+func fieldValuesEqual(a, b reflect.Value) bool {
+	for a.Kind() == reflect.Ptr {
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() == b.IsNil()
+		}
+		a, b = a.Elem(), b.Elem()
+	}
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
@@ -1,9 +1,14 @@
 package fsql
 
 import (
+	"context"
 	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/google/uuid"
@@ -20,15 +25,47 @@ type QueryStep interface{}
 
 type WhereStep struct {
 	Condition string
+	Args      []interface{} // Bound args for $1..$n in Condition (numbered per step)
 }
 
 type JoinStep struct {
 	Join
 }
 
+// CTE is a common table expression rendered as WITH "Name" AS (Query)
+type CTE struct {
+	Name  string
+	Query string
+}
+
 type QueryBuilder struct {
 	Table string
 	Steps []QueryStep
+	Raw   string // Hand-written base query used instead of the generated SELECT
+	// Wildcard selects "table".* for the base table instead of listing its columns;
+	// joined tables keep their aliased columns
+	Wildcard bool
+	// DistinctRows makes Build emit SELECT DISTINCT
+	DistinctRows bool
+	// DistinctOnColumns makes Build emit SELECT DISTINCT ON (...) over these quoted selectors
+	DistinctOnColumns []string
+	// SelectColumns replaces the generated select list (quoted "table"."column" selectors,
+	// or an aliased aggregate set by Aggregate)
+	SelectColumns []string
+	// AggColumns are json_agg child columns appended to the select list (see JSONAgg)
+	AggColumns []string
+	// DeletedColumn excludes base rows where this soft-delete column is set (quoted)
+	DeletedColumn string
+	// CTEs are prepended as a WITH list, in order
+	CTEs []CTE
+	// Unions are combined after this builder's query, in order
+	Unions []UnionPart
+}
+
+// UnionPart is a query combined with UNION (or UNION ALL when All is set)
+type UnionPart struct {
+	Query *QueryBuilder
+	All   bool
 }
 
 // isJSONBType checks if a value should be cast to JSONB in SQL queries.
@@ -81,42 +118,44 @@ func isJSONBType(val interface{}) bool {
 }
 
 func GetInsertQuery(tableName string, valuesMap map[string]interface{}, returning string) (string, []interface{}) {
+	query, queryValues := insertQuery(tableName, valuesMap)
+	if returning != "" {
+		// returning is emitted as given, so expressions and pre-quoted lists keep working
+		query += fmt.Sprintf(` RETURNING "%s".%s`, tableName, returning)
+	}
+	return query, queryValues
+}
+
+// GetInsertQueryReturning is GetInsertQuery with any number of RETURNING columns
+// (none omits the clause). Columns are quoted; "*" returns every column.
+func GetInsertQueryReturning(tableName string, valuesMap map[string]interface{}, returning ...string) (string, []interface{}) {
+	query, queryValues := insertQuery(tableName, valuesMap)
+	return query + returningClause(tableName, returning), queryValues
+}
+
+// insertQuery builds the INSERT without a RETURNING clause
+func insertQuery(tableName string, valuesMap map[string]interface{}) (string, []interface{}) {
 	_, fields := GetInsertFields(tableName)
 	defaultValues := GetInsertValues(tableName)
+	modelInfo, _ := getModelInfo(tableName)
 
+	columns := make([]string, 0, len(fields))
 	placeholders := []string{}
 	queryValues := []interface{}{}
 	counter := 1
 	for _, field := range fields {
 		if val, ok := valuesMap[field]; ok {
-			// If value is provided in valuesMap, use it
-			// Add ::jsonb cast for JSONB types (needed for PgBouncer transaction pooling)
-			if isJSONBType(val) {
-				placeholders = append(placeholders, fmt.Sprintf("$%d::jsonb", counter))
-				// Get JSON from Value() method to preserve correct field names
-				if valuer, ok := val.(driver.Valuer); ok {
-					driverVal, err := valuer.Value()
-					if err == nil && driverVal != nil {
-						if jsonBytes, ok := driverVal.([]byte); ok {
-							queryValues = append(queryValues, string(jsonBytes))
-						} else {
-							queryValues = append(queryValues, val)
-						}
-					} else {
-						queryValues = append(queryValues, val)
-					}
-				} else {
-					queryValues = append(queryValues, val)
-				}
-			} else {
-				placeholders = append(placeholders, fmt.Sprintf("$%d", counter))
-				queryValues = append(queryValues, val)
-			}
+			columns = append(columns, modelInfo.quotedFields[field])
+			// If value is provided in valuesMap, use it, cast per the column's dbType
+			placeholder, arg := typedPlaceholder(modelInfo, field, val, counter)
+			placeholders = append(placeholders, placeholder)
+			queryValues = append(queryValues, arg)
 			counter++
 		} else if defVal, ok := defaultValues[field]; ok {
+			columns = append(columns, modelInfo.quotedFields[field])
 			// Else use the default value from tags
-			if defVal == "NOW()" || defVal == "NULL" || defVal == "true" || defVal == "false" || defVal == "DEFAULT" {
-				placeholders = append(placeholders, defVal)
+			if expr, ok := insertDefaultSQL(defVal); ok {
+				placeholders = append(placeholders, expr)
 			} else {
 				placeholders = append(placeholders, fmt.Sprintf("$%d", counter))
 				queryValues = append(queryValues, defVal)
@@ -125,60 +164,324 @@ func GetInsertQuery(tableName string, valuesMap map[string]interface{}, returnin
 		}
 	}
 
-	query := fmt.Sprintf(`INSERT INTO "%s" (%s) VALUES (%s)`, tableName, strings.Join(fields, ","), strings.Join(placeholders, ","))
-	if len(returning) > 0 {
-		query += fmt.Sprintf(` RETURNING "%s".%s`, tableName, returning)
-	}
+	// Quoted columns so reserved words (order, user, ...) work; only those given a value are listed
+	query := fmt.Sprintf(`INSERT INTO "%s" (%s) VALUES (%s)`, tableName, strings.Join(columns, ","), strings.Join(placeholders, ","))
 	return query, queryValues
 }
 
-func GetUpdateQuery(tableName string, valuesMap map[string]interface{}, returning string) (string, []interface{}) {
-	_, fields := GetUpdateFields(tableName)
-	setClauses := []string{}
-	queryValues := []interface{}{}
-	counter := 1
+// RawSQLInsertPrefix marks a dbInsertValue as a raw SQL expression that GetInsertQuery inlines
+// instead of binding, e.g. dbInsertValue:"sql:gen_random_uuid()"
+const RawSQLInsertPrefix = "sql:"
 
-	for _, field := range fields {
-		if value, exists := valuesMap[field]; exists {
-			// Add ::jsonb cast for JSONB types (needed for PgBouncer transaction pooling)
-			var setClause string
-			if isJSONBType(value) {
-				setClause = fmt.Sprintf(`%s = $%d::jsonb`, field, counter)
-				// Get JSON from Value() method to preserve correct field names
-				if valuer, ok := value.(driver.Valuer); ok {
-					driverVal, err := valuer.Value()
-					if err == nil && driverVal != nil {
-						if jsonBytes, ok := driverVal.([]byte); ok {
-							queryValues = append(queryValues, string(jsonBytes))
-						} else {
-							queryValues = append(queryValues, value)
-						}
-					} else {
-						queryValues = append(queryValues, value)
-					}
-				} else {
-					queryValues = append(queryValues, value)
-				}
-			} else {
-				setClause = fmt.Sprintf(`%s = $%d`, field, counter)
-				queryValues = append(queryValues, value)
+// insertDefaultSQL returns the SQL to inline for a dbInsertValue: the keywords NOW(), NULL,
+// true, false and DEFAULT as-is, or the expression after RawSQLInsertPrefix. Any other value
+// is a literal to bind as a parameter.
+func insertDefaultSQL(defVal string) (string, bool) {
+	switch defVal {
+	case "NOW()", "NULL", "true", "false", "DEFAULT":
+		return defVal, true
+	}
+	if expr, ok := strings.CutPrefix(defVal, RawSQLInsertPrefix); ok {
+		return expr, true
+	}
+	return "", false
+}
+
+// GetInsertSelectQuery builds INSERT INTO "destTable" ("col", ...) selectQuery for backfills
+// and copying rows between tables. cols are Go field names or columns of destTable's model
+// and must be insert fields (dbMode "i"); selectQuery must return them in the same order.
+// It panics on an unregistered table or a column that isn't an insert field.
+func GetInsertSelectQuery(destTable string, cols []string, selectQuery string) string {
+	modelInfo, ok := getModelInfo(destTable)
+	if !ok {
+		panic("table name not initialized: " + destTable)
+	}
+	if len(cols) == 0 {
+		panic("insert select requires at least one column for table " + destTable)
+	}
+
+	quotedCols := make([]string, 0, len(cols))
+	for _, col := range cols {
+		if dbField, ok := modelInfo.dbTagMap[col]; ok {
+			col = dbField
+		}
+		if _, ok := modelInfo.dbFieldsInsertMap[col]; !ok {
+			panic(fmt.Sprintf("column %s is not an insert field of table %s", col, destTable))
+		}
+		quotedCols = append(quotedCols, modelInfo.quotedFields[col])
+	}
+
+	return fmt.Sprintf(`INSERT INTO %s (%s) %s`, modelInfo.quotedTableName, strings.Join(quotedCols, ", "), selectQuery)
+}
+
+// GetInsertSelectQueryObject is GetInsertSelectQuery with the columns taken from the dbMode "i"
+// tags of object's struct type, in field order
+func GetInsertSelectQueryObject(object interface{}, destTable string, selectQuery string) string {
+	modelType := getModelType(object)
+
+	var cols []string
+	for i := 0; i < modelType.NumField(); i++ {
+		field := modelType.Field(i)
+		dbTagValue := field.Tag.Get("db")
+		if dbTagValue == "" || dbTagValue == "-" {
+			continue
+		}
+		modes := strings.Split(field.Tag.Get("dbMode"), ",")
+		if slices.Contains(modes, "i") && !slices.Contains(modes, "s") && !slices.Contains(modes, "l") && !slices.Contains(modes, "link") {
+			cols = append(cols, dbTagValue)
+		}
+	}
+	return GetInsertSelectQuery(destTable, cols, selectQuery)
+}
+
+// jsonbArg returns the arg bound to a ::jsonb placeholder for a value isJSONBType accepted.
+// The JSON comes from the Value() method to preserve correct field names.
+func jsonbArg(val interface{}) interface{} {
+	if valuer, ok := val.(driver.Valuer); ok {
+		driverVal, err := valuer.Value()
+		if err == nil && driverVal != nil {
+			if jsonBytes, ok := driverVal.([]byte); ok {
+				return string(jsonBytes)
 			}
+		}
+	}
+	return val
+}
 
-			setClauses = append(setClauses, setClause)
-			counter++
+// typedPlaceholder returns the $n placeholder for a value of column field and the arg bound to
+// it. A dbType tag casts explicitly ($n::uuid, $n::jsonb); without one, values isJSONBType
+// accepts get ::jsonb (needed for PgBouncer transaction pooling) and the rest are left uncast.
+func typedPlaceholder(modelInfo *modelInfo, field string, val interface{}, n int) (string, interface{}) {
+	if pgType, ok := modelInfo.dbTypes[field]; ok {
+		placeholder := "$" + strconv.Itoa(n) + "::" + pgType
+		if pgType == "jsonb" || pgType == "json" {
+			return placeholder, jsonColumnArg(val)
+		}
+		return placeholder, val
+	}
+	if isJSONBType(val) {
+		return fmt.Sprintf("$%d::jsonb", n), jsonbArg(val)
+	}
+	return fmt.Sprintf("$%d", n), val
+}
+
+// jsonColumnArg returns the arg for a column tagged dbType:"jsonb": JSON text is sent as-is,
+// Valuers as their JSON, nil as NULL, and anything else is marshalled with encoding/json
+func jsonColumnArg(val interface{}) interface{} {
+	if val == nil {
+		return nil
+	}
+	if rv := reflect.ValueOf(val); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil
+	}
+	switch v := val.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case driver.Valuer:
+		return jsonbArg(v)
+	}
+	encoded, err := json.Marshal(val)
+	if err != nil {
+		return val
+	}
+	return string(encoded)
+}
+
+// GetUpsertQuery builds INSERT ... ON CONFLICT (conflictCols) DO UPDATE SET col = EXCLUDED.col
+// for each of updateCols, or DO NOTHING when updateCols is empty. Values are bound exactly as
+// GetInsertQuery binds them (JSONB values get their ::jsonb cast), so EXCLUDED carries the
+// already-cast values. With DO NOTHING, a conflicting row returns no RETURNING row.
+func GetUpsertQuery(tableName string, valuesMap map[string]interface{}, conflictCols []string, updateCols []string, returning string) (string, []interface{}) {
+	query, queryValues := GetInsertQueryReturning(tableName, valuesMap)
+
+	var sb strings.Builder
+	sb.WriteString(query)
+	sb.WriteString(" ON CONFLICT (")
+	for i, col := range conflictCols {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(`"` + quotesReplacer.Replace(col) + `"`)
+	}
+	sb.WriteByte(')')
+
+	if len(updateCols) == 0 {
+		sb.WriteString(" DO NOTHING")
+	} else {
+		sb.WriteString(" DO UPDATE SET ")
+		for i, col := range updateCols {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			quoted := `"` + quotesReplacer.Replace(col) + `"`
+			sb.WriteString(quoted)
+			sb.WriteString(" = EXCLUDED.")
+			sb.WriteString(quoted)
+		}
+	}
+
+	if returning != "" {
+		sb.WriteString(returningClause(tableName, []string{returning}))
+	}
+
+	return sb.String(), queryValues
+}
+
+// returningClause renders ` RETURNING "table"."a", "table"."b"`, or "" for no columns
+func returningClause(tableName string, columns []string) string {
+	if len(columns) == 0 {
+		return ""
+	}
+
+	quotedTable := `"` + quotesReplacer.Replace(tableName) + `"`
+	var sb strings.Builder
+	sb.WriteString(" RETURNING ")
+	for i, col := range columns {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(quotedTable)
+		sb.WriteByte('.')
+		if col == "*" {
+			sb.WriteByte('*')
+			continue
+		}
+		sb.WriteByte('"')
+		sb.WriteString(quotesReplacer.Replace(col))
+		sb.WriteByte('"')
+	}
+	return sb.String()
+}
+
+// GetUpdateQuery builds an UPDATE of valuesMap's update fields for the row whose returning
+// column equals valuesMap[returning], returning that column. It panics when the key is missing
+// from valuesMap; with no update fields the query is still built (and rejected by the server).
+//
+// Deprecated: use GetUpdateQuerySafe, which returns an error instead of panicking.
+func GetUpdateQuery(tableName string, valuesMap map[string]interface{}, returning string) (string, []interface{}) {
+	keyValue, keyExists := valuesMap[returning]
+	if !keyExists {
+		panic(fmt.Sprintf("UUID not found in valuesMap: %v", valuesMap))
+	}
+
+	setClauses, queryValues := updateSetClauses(tableName, valuesMap, "")
+	quotedKey := quotesReplacer.Replace(returning)
+	query := fmt.Sprintf(`UPDATE "%s" SET %s WHERE "%s"."%s" = $%d RETURNING "%s"."%s"`,
+		tableName, strings.Join(setClauses, ", "), tableName, quotedKey, len(queryValues)+1, tableName, quotedKey)
+	queryValues = append(queryValues, keyValue)
+
+	return query, queryValues
+}
+
+// GetUpdateQuerySafe builds an UPDATE of valuesMap's update fields for the row whose returning
+// column equals valuesMap[returning], returning that column. It returns an error when returning
+// is empty or missing from valuesMap, or valuesMap sets no update field.
+func GetUpdateQuerySafe(tableName string, valuesMap map[string]interface{}, returning string) (string, []interface{}, error) {
+	return GetUpdateQueryWhere(tableName, valuesMap, returning, returning)
+}
+
+// GetUpdateQueryWhere builds an UPDATE of valuesMap's update fields for the row whose whereCol
+// equals valuesMap[whereCol]. RETURNING returningCol is appended only when returningCol is set,
+// so the query can run with Exec. It returns an error when whereCol is empty or missing from
+// valuesMap, or valuesMap sets no update field.
+func GetUpdateQueryWhere(tableName string, valuesMap map[string]interface{}, whereCol string, returningCol string) (string, []interface{}, error) {
+	return GetUpdateQueryKeys(tableName, valuesMap, []string{whereCol}, returningCol)
+}
+
+// GetUpdateQueryKeys is GetUpdateQueryWhere for a composite key: the row is matched on every
+// column of whereKeys (WHERE "t"."k1" = $a AND "t"."k2" = $b), each valued from valuesMap
+func GetUpdateQueryKeys(tableName string, valuesMap map[string]interface{}, whereKeys []string, returningCol string) (string, []interface{}, error) {
+	setClauses, queryValues := updateSetClauses(tableName, valuesMap, "")
+	if len(setClauses) == 0 {
+		return "", nil, fmt.Errorf("no update fields for table %s in values", tableName)
+	}
+
+	where, keyValues, err := keyConditions(tableName, whereKeys, valuesMap, len(queryValues)+1)
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := fmt.Sprintf(`UPDATE "%s" SET %s WHERE %s`, tableName, strings.Join(setClauses, ", "), where)
+	if returningCol != "" {
+		query += fmt.Sprintf(` RETURNING "%s"."%s"`, tableName, quotesReplacer.Replace(returningCol))
+	}
+	queryValues = append(queryValues, keyValues...)
+
+	return query, queryValues, nil
+}
+
+// keyConditions renders "t"."k1" = $n AND "t"."k2" = $n+1 for keys, with their values taken
+// from valuesMap. Every key must be non-empty and present in valuesMap.
+func keyConditions(tableName string, keys []string, valuesMap map[string]interface{}, firstArg int) (string, []interface{}, error) {
+	if len(keys) == 0 {
+		return "", nil, errors.New("a key column is required")
+	}
+
+	var sb strings.Builder
+	args := make([]interface{}, 0, len(keys))
+	for i, key := range keys {
+		if key == "" {
+			return "", nil, errors.New("a key column is required")
+		}
+		value, ok := valuesMap[key]
+		if !ok {
+			return "", nil, fmt.Errorf("key column %s not found in values", key)
+		}
+		if i > 0 {
+			sb.WriteString(" AND ")
 		}
+		fmt.Fprintf(&sb, `"%s"."%s" = $%d`, tableName, quotesReplacer.Replace(key), firstArg+i)
+		args = append(args, value)
 	}
+	return sb.String(), args, nil
+}
+
+// GetUpdateQueryVersioned is GetUpdateQuery with an optimistic-locking guard: the row is only
+// updated while versionCol still equals expectedVersion, and versionCol is incremented.
+// Zero rows updated means another writer got there first; UpdateVersioned reports it as ErrVersionConflict.
+func GetUpdateQueryVersioned(tableName string, valuesMap map[string]interface{}, returning string, versionCol string, expectedVersion int) (string, []interface{}) {
+	setClauses, queryValues := updateSetClauses(tableName, valuesMap, versionCol)
+	quotedVersion := `"` + quotesReplacer.Replace(versionCol) + `"`
+	setClauses = append(setClauses, fmt.Sprintf(`%s = "%s".%s + 1`, quotedVersion, tableName, quotedVersion))
+	counter := len(queryValues) + 1
+	quotedReturning := quotesReplacer.Replace(returning)
 
-	query := fmt.Sprintf(`UPDATE "%s" SET %s WHERE "%s"."%s" = $%d RETURNING "%s".%s`, tableName, strings.Join(setClauses, ", "), tableName, returning, counter, tableName, returning)
+	query := fmt.Sprintf(`UPDATE "%s" SET %s WHERE "%s"."%s" = $%d AND "%s".%s = $%d RETURNING "%s"."%s"`,
+		tableName, strings.Join(setClauses, ", "), tableName, quotedReturning, counter, tableName, quotedVersion, counter+1, tableName, quotedReturning)
 	uuidValue, uuidExists := valuesMap[returning]
 	if !uuidExists {
 		panic(fmt.Sprintf("UUID not found in valuesMap: %v", valuesMap))
 	}
-	queryValues = append(queryValues, uuidValue)
+	queryValues = append(queryValues, uuidValue, expectedVersion)
 
 	return query, queryValues
 }
 
+// updateSetClauses renders `"col" = $N` for each update field present in valuesMap (skipping skip)
+func updateSetClauses(tableName string, valuesMap map[string]interface{}, skip string) ([]string, []interface{}) {
+	_, fields := GetUpdateFields(tableName)
+	modelInfo, _ := getModelInfo(tableName)
+	setClauses := []string{}
+	queryValues := []interface{}{}
+	counter := 1
+
+	for _, field := range fields {
+		if skip != "" && field == skip {
+			continue
+		}
+		if value, exists := valuesMap[field]; exists {
+			placeholder, arg := typedPlaceholder(modelInfo, field, value, counter)
+			setClauses = append(setClauses, modelInfo.quotedFields[field]+" = "+placeholder)
+			queryValues = append(queryValues, arg)
+			counter++
+		}
+	}
+
+	return setClauses, queryValues
+}
+
 func SelectBase(table string, alias string) *QueryBuilder {
 	return &QueryBuilder{
 		Table: table,
@@ -186,11 +489,505 @@ func SelectBase(table string, alias string) *QueryBuilder {
 	}
 }
 
+// FromRaw creates a QueryBuilder around a hand-written base query.
+// The table must be registered with InitModelTagCache so filters and sorts
+// can be resolved against its dbTagMap. The raw query should not contain a
+// top-level WHERE/ORDER BY/LIMIT since FilterQuery appends those.
+// Join/Where steps are ignored when building a raw query.
+func FromRaw(sql string, table string) *QueryBuilder {
+	return &QueryBuilder{
+		Table: table,
+		Steps: []QueryStep{},
+		Raw:   sql,
+	}
+}
+
+// FilterQuery appends filters, sort and pagination to the built query
+// using the builder's table for field resolution
+// Filter placeholders are numbered after the builder's own WhereArgs args, which come first in the result
+func (qb *QueryBuilder) FilterQuery(filters *Filter, sort SortOrder, perPage int, page int) (string, []interface{}, error) {
+	if err := qb.Validate(); err != nil {
+		return "", nil, err
+	}
+	if len(qb.DistinctOnColumns) > 0 && qb.Raw == "" {
+		// The sort becomes the ORDER BY, so it must be led by the DISTINCT ON columns too
+		sortClause, err := qb.SortCondition(sort)
+		if err != nil {
+			return "", nil, err
+		}
+		if sortClause != "" {
+			if err := qb.checkDistinctOnOrder([]string{strings.TrimPrefix(sortClause, " ORDER BY ")}); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	return filterQueryWithArgs(qb.Build(), qb.Table, filters, sort, qb.Table, perPage, page, qb.Args())
+}
+
+// SortCondition returns the ORDER BY clause for sort resolved against the builder's table
+func (qb *QueryBuilder) SortCondition(sort SortOrder) (string, error) {
+	return GetSortCondition(sort, qb.Table)
+}
+
+// OrderBy appends a raw ORDER BY clause (e.g. `"users".created_at DESC`); repeated calls
+// are joined with commas. Don't pass user input: see OrderByField.
+// A builder with ORDER BY/LIMIT/OFFSET can't be extended by FilterQuery or Page.
+func (qb *QueryBuilder) OrderBy(clause string) *QueryBuilder {
+	qb.Steps = append(qb.Steps, orderByStep{clause})
+	return qb
+}
+
+// Limit makes Build emit LIMIT n (the last call wins)
+func (qb *QueryBuilder) Limit(n int) *QueryBuilder {
+	qb.Steps = append(qb.Steps, limitStep{int64(n)})
+	return qb
+}
+
+// Offset makes Build emit OFFSET n (the last call wins)
+func (qb *QueryBuilder) Offset(n int) *QueryBuilder {
+	qb.Steps = append(qb.Steps, offsetStep{int64(n)})
+	return qb
+}
+
 func (qb *QueryBuilder) Where(condition string) *QueryBuilder {
 	qb.Steps = append(qb.Steps, WhereStep{Condition: condition})
 	return qb
 }
 
+// WhereArgs adds a condition with bound args. Placeholders in condition are numbered
+// from $1 for this call only; Build shifts them after the args of earlier steps.
+// Don't mix with plain Where conditions that use their own $N placeholders.
+func (qb *QueryBuilder) WhereArgs(condition string, args ...interface{}) *QueryBuilder {
+	qb.Steps = append(qb.Steps, WhereStep{Condition: condition, Args: args})
+	return qb
+}
+
+// WhereEq adds column = value with value bound (IS NULL for a nil value). column is a db column
+// or Go field name of the base table, or "alias.column" for a joined table.
+func (qb *QueryBuilder) WhereEq(column string, value interface{}) *QueryBuilder {
+	return qb.whereCompare(column, "=", "IS NULL", value)
+}
+
+// WhereNotEq adds column <> value with value bound (IS NOT NULL for a nil value); see WhereEq
+func (qb *QueryBuilder) WhereNotEq(column string, value interface{}) *QueryBuilder {
+	return qb.whereCompare(column, "<>", "IS NOT NULL", value)
+}
+
+// WhereIn adds column IN ($1, ..., $n) with every value bound; see WhereEq for column.
+// No values matches no rows.
+func (qb *QueryBuilder) WhereIn(column string, values ...interface{}) *QueryBuilder {
+	return qb.whereList(column, "IN", "FALSE", values)
+}
+
+// WhereNotIn adds column NOT IN ($1, ..., $n) with every value bound; see WhereEq for column.
+// No values matches every row.
+func (qb *QueryBuilder) WhereNotIn(column string, values ...interface{}) *QueryBuilder {
+	return qb.whereList(column, "NOT IN", "TRUE", values)
+}
+
+// WhereNull adds column IS NULL; see WhereEq for column
+func (qb *QueryBuilder) WhereNull(column string) *QueryBuilder {
+	return qb.Where(qb.whereColumn(column) + " IS NULL")
+}
+
+// WhereNotNull adds column IS NOT NULL; see WhereEq for column
+func (qb *QueryBuilder) WhereNotNull(column string) *QueryBuilder {
+	return qb.Where(qb.whereColumn(column) + " IS NOT NULL")
+}
+
+// whereCompare adds column op $1, or column nullCheck when value is nil
+func (qb *QueryBuilder) whereCompare(column string, op string, nullCheck string, value interface{}) *QueryBuilder {
+	selector := qb.whereColumn(column)
+	if value == nil {
+		return qb.Where(selector + " " + nullCheck)
+	}
+	return qb.WhereArgs(selector+" "+op+" $1", value)
+}
+
+// whereList adds column op ($1, ..., $n), or the constant condition empty for no values
+func (qb *QueryBuilder) whereList(column string, op string, empty string, values []interface{}) *QueryBuilder {
+	selector := qb.whereColumn(column)
+	if len(values) == 0 {
+		return qb.Where(empty)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(selector)
+	sb.WriteString(" ")
+	sb.WriteString(op)
+	sb.WriteString(" (")
+	for i := range values {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString("$")
+		sb.WriteString(strconv.Itoa(i + 1))
+	}
+	sb.WriteString(")")
+	return qb.WhereArgs(sb.String(), values...)
+}
+
+// whereColumn resolves a WhereEq column: "alias.column" is quoted as a joined table's column,
+// anything else is resolved against the base table like Columns. Invalid names panic.
+func (qb *QueryBuilder) whereColumn(column string) string {
+	alias, col, joined := strings.Cut(column, ".")
+	if !joined {
+		return qb.columnSelector(column)
+	}
+	quotedAlias, err := QuoteIdentifier(alias)
+	if err != nil {
+		panic(fmt.Sprintf("invalid where column %s: %v", column, err))
+	}
+	quotedCol, err := QuoteIdentifier(col)
+	if err != nil {
+		panic(fmt.Sprintf("invalid where column %s: %v", column, err))
+	}
+	return quotedAlias + "." + quotedCol
+}
+
+// Args returns the bound args of all WhereArgs steps, in placeholder order
+// (followed by those of the unioned builders)
+func (qb *QueryBuilder) Args() []interface{} {
+	args := qb.ownArgs()
+	for _, union := range qb.Unions {
+		args = append(args, union.Query.Args()...)
+	}
+	return args
+}
+
+// ownArgs returns the bound args of the builder's own WhereArgs steps
+func (qb *QueryBuilder) ownArgs() []interface{} {
+	if qb.Raw != "" {
+		return nil
+	}
+
+	var args []interface{}
+	for _, step := range qb.Steps {
+		if s, ok := step.(WhereStep); ok {
+			args = append(args, s.Args...)
+		}
+	}
+	return args
+}
+
+// BuildWithArgs returns the built query along with its bound args (from WhereArgs, WhereEq,
+// WhereIn and the other structured conditions), ready to execute
+func (qb *QueryBuilder) BuildWithArgs() (string, []interface{}) {
+	return qb.Build(), qb.Args()
+}
+
+// BuildCountWithArgs returns the COUNT query for the built query along with its bound args
+func (qb *QueryBuilder) BuildCountWithArgs() (string, []interface{}) {
+	return BuildFilterCount(qb.Build()), qb.Args()
+}
+
+// Pagination describes one page of a paginated result (same shape as fsql's)
+type Pagination struct {
+	ResultsPerPage int
+	PageNo         int
+	Count          int
+	PageMax        int
+}
+
+// Page scans page (1-based) of the built query into dest and counts all matching rows.
+// The builder has no ORDER BY, so use FilterQuery with a Sort when row order matters.
+func (qb *QueryBuilder) Page(ctx context.Context, dest interface{}, perPage int, page int) (Pagination, error) {
+	if page < 1 {
+		page = 1
+	}
+	pagination := Pagination{ResultsPerPage: perPage, PageNo: page}
+	if err := qb.Validate(); err != nil {
+		return pagination, err
+	}
+
+	query := qb.Build()
+	args := qb.Args()
+
+	var sb strings.Builder
+	sb.WriteString(query)
+	writePagination(&sb, perPage, page)
+
+	if err := SelectMany(ctx, dest, sb.String(), args...); err != nil {
+		return pagination, fmt.Errorf("page query failed: %w", err)
+	}
+
+	if err := DB.QueryRow(ctx, BuildFilterCount(query), args...).Scan(&pagination.Count); err != nil {
+		return pagination, fmt.Errorf("count query failed: %w", err)
+	}
+
+	if perPage > 0 {
+		pagination.PageMax = (pagination.Count + perPage - 1) / perPage
+	} else if pagination.Count > 0 {
+		pagination.PageMax = 1
+	}
+
+	return pagination, nil
+}
+
+// WildcardBase makes Build select "table".* for the base table. The base columns come back
+// under their own names, so they scan like the explicit list; columns without a matching
+// field are ignored by the scanner. Joined tables are still listed as "alias.col".
+func (qb *QueryBuilder) WildcardBase() *QueryBuilder {
+	qb.Wildcard = true
+	return qb
+}
+
+// wildcardSelectFields returns "table".* followed by the table's computed columns
+func wildcardSelectFields(table string) []string {
+	modelInfo, ok := getModelInfo(table)
+	if !ok {
+		panic("table name not initialized: " + table)
+	}
+
+	fields := make([]string, 0, len(modelInfo.computedColumns)+1)
+	fields = append(fields, modelInfo.quotedTableName+".*")
+	for _, c := range modelInfo.computedColumns {
+		quoted, _ := QuoteIdentifier(c.name) // validated when registered
+		fields = append(fields, c.expression+" AS "+quoted)
+	}
+	return fields
+}
+
+// With adds a common table expression: Build prepends WITH name AS (subquery), ... in call order.
+// The builder's table (or a joined table) can be a CTE name; an unregistered CTE has no model,
+// so Build selects "name".* from it unless Columns names its columns.
+// Placeholders in subquery are not renumbered, so don't combine them with WhereArgs.
+func (qb *QueryBuilder) With(name string, subquery string) *QueryBuilder {
+	if _, err := QuoteIdentifier(name); err != nil {
+		panic(fmt.Sprintf("invalid CTE name: %v", err))
+	}
+	qb.CTEs = append(qb.CTEs, CTE{Name: name, Query: subquery})
+	return qb
+}
+
+// isModellessCTE reports whether table is one of the builder's CTEs without a registered model
+func (qb *QueryBuilder) isModellessCTE(table string) bool {
+	for _, cte := range qb.CTEs {
+		if cte.Name == table {
+			_, ok := getModelInfo(table)
+			return !ok
+		}
+	}
+	return false
+}
+
+// withClause renders the WITH list (empty without CTEs)
+func (qb *QueryBuilder) withClause() string {
+	if len(qb.CTEs) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("WITH ")
+	for i, cte := range qb.CTEs {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		quoted, _ := QuoteIdentifier(cte.Name) // validated by With
+		sb.WriteString(quoted)
+		sb.WriteString(" AS (")
+		sb.WriteString(cte.Query)
+		sb.WriteString(")")
+	}
+	sb.WriteString(" ")
+	return sb.String()
+}
+
+// Distinct makes Build select DISTINCT rows
+func (qb *QueryBuilder) Distinct() *QueryBuilder {
+	qb.DistinctRows = true
+	return qb
+}
+
+// DistinctOn makes Build select one row per distinct cols of the base table, with
+// SELECT DISTINCT ON ("table"."col", ...). Columns are resolved like Columns. Postgres keeps the
+// first row of each group, so add an ORDER BY led by these columns to pick which one (e.g. the
+// latest per group); Validate and FilterQuery return an error if the ORDER BY doesn't start with them.
+func (qb *QueryBuilder) DistinctOn(cols ...string) *QueryBuilder {
+	if len(cols) == 0 {
+		panic("distinct on requires at least one column")
+	}
+	selectors := make([]string, 0, len(cols))
+	for _, col := range cols {
+		selectors = append(selectors, qb.columnSelector(col))
+	}
+	qb.DistinctOnColumns = selectors
+	return qb
+}
+
+// Validate checks what Build can't reject without panicking: unions must select the same
+// columns, and the ORDER BY of a DistinctOn builder (and of its unions) must start with the
+// DISTINCT ON columns. FilterQuery and Page return its error.
+func (qb *QueryBuilder) Validate() error {
+	if len(qb.DistinctOnColumns) > 0 && qb.Raw == "" {
+		if err := qb.checkDistinctOnOrder(qb.orderByClauses()); err != nil {
+			return err
+		}
+	}
+	columns, known := qb.outputColumns()
+	for _, union := range qb.Unions {
+		if known {
+			if otherColumns, ok := union.Query.outputColumns(); ok && !slices.Equal(columns, otherColumns) {
+				return fmt.Errorf("union column mismatch: %v vs %v", columns, otherColumns)
+			}
+		}
+		if err := union.Query.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderByClauses returns the builder's own OrderBy clauses
+func (qb *QueryBuilder) orderByClauses() []string {
+	var orderBy []string
+	for _, step := range qb.Steps {
+		if s, ok := step.(orderByStep); ok {
+			orderBy = append(orderBy, s.Clause)
+		}
+	}
+	return orderBy
+}
+
+// checkDistinctOnOrder returns an error unless the leading ORDER BY items are the DISTINCT ON
+// columns (in any order, as Postgres requires). Quoting, case and ASC/DESC/NULLS are ignored,
+// and an unqualified column matches the base table's.
+func (qb *QueryBuilder) checkDistinctOnOrder(orderBy []string) error {
+	if len(orderBy) == 0 {
+		return nil
+	}
+	var items []string
+	for _, clause := range orderBy {
+		items = append(items, splitTopLevel(clause)...)
+	}
+	if len(items) < len(qb.DistinctOnColumns) {
+		return fmt.Errorf("ORDER BY %s must start with the DISTINCT ON columns %s",
+			strings.Join(orderBy, ", "), strings.Join(qb.DistinctOnColumns, ", "))
+	}
+
+	remaining := make(map[string]bool, len(qb.DistinctOnColumns))
+	for _, selector := range qb.DistinctOnColumns {
+		remaining[normalizeOrderItem(selector)] = true
+	}
+	for _, item := range items[:len(qb.DistinctOnColumns)] {
+		key := normalizeOrderItem(item)
+		if !strings.Contains(key, ".") {
+			key = strings.ToLower(qb.Table) + "." + key
+		}
+		if !remaining[key] {
+			return fmt.Errorf("ORDER BY %s must start with the DISTINCT ON columns %s",
+				strings.Join(orderBy, ", "), strings.Join(qb.DistinctOnColumns, ", "))
+		}
+		delete(remaining, key)
+	}
+	return nil
+}
+
+// splitTopLevel splits a comma-separated list, ignoring commas inside parentheses
+func splitTopLevel(list string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(list); i++ {
+		switch list[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, list[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, list[start:])
+}
+
+// normalizeOrderItem reduces an ORDER BY item to its lowercase, unquoted expression
+func normalizeOrderItem(item string) string {
+	fields := strings.Fields(strings.ToLower(strings.ReplaceAll(item, `"`, "")))
+	for len(fields) > 1 {
+		last := fields[len(fields)-1]
+		if last != "asc" && last != "desc" && last != "nulls" && last != "first" && last != "last" {
+			break
+		}
+		fields = fields[:len(fields)-1]
+	}
+	return strings.Join(fields, " ")
+}
+
+// Columns makes Build select only cols of the base table instead of every model field
+// (and the joined tables' fields). Each col is a db column or Go field name of the model;
+// unknown columns panic like an unregistered table. Scanning into the full struct still
+// works: fields without a selected column keep their zero value.
+func (qb *QueryBuilder) Columns(cols ...string) *QueryBuilder {
+	selectors := make([]string, 0, len(cols))
+	for _, col := range cols {
+		selectors = append(selectors, qb.columnSelector(col))
+	}
+	qb.SelectColumns = selectors
+	return qb
+}
+
+// columnSelector resolves col (db column or Go field name) to a quoted "table"."column"
+// of the base table, panicking on unknown columns
+func (qb *QueryBuilder) columnSelector(col string) string {
+	if qb.isModellessCTE(qb.Table) {
+		// No model to resolve against: col is one of the CTE's column names
+		quoted, err := QuoteIdentifier(col)
+		if err != nil {
+			panic(fmt.Sprintf("invalid column for CTE %s: %v", qb.Table, err))
+		}
+		return `"` + qb.Table + `".` + quoted
+	}
+
+	modelInfo, ok := getModelInfo(qb.Table)
+	if !ok {
+		panic("table name not initialized: " + qb.Table)
+	}
+	if dbField, ok := modelInfo.dbTagMap[col]; ok {
+		col = dbField
+	} else if _, ok := modelInfo.fieldTypes[col]; !ok {
+		panic(fmt.Sprintf("unknown column %s for table %s", col, qb.Table))
+	}
+	return modelInfo.quotedTableName + `."` + col + `"`
+}
+
+// aggregateFuncs are the functions Aggregate accepts
+var aggregateFuncs = map[string]bool{"SUM": true, "AVG": true, "MIN": true, "MAX": true, "COUNT": true}
+
+// Aggregate makes Build select a single aggregate of a base table column, e.g.
+// SUM("orders"."total") AS "sum", for scanning into a scalar (sql.NullInt64 and friends, as
+// SUM/AVG/MIN/MAX are NULL over no rows). fn is SUM, AVG, MIN, MAX or COUNT (any case); column
+// is resolved like Columns, and COUNT also takes "*". Invalid functions or columns panic.
+func (qb *QueryBuilder) Aggregate(fn string, column string) *QueryBuilder {
+	fn = strings.ToUpper(fn)
+	if !aggregateFuncs[fn] {
+		panic("unsupported aggregate function: " + fn)
+	}
+
+	selector := "*"
+	if column != "*" {
+		selector = qb.columnSelector(column)
+	} else if fn != "COUNT" {
+		panic(fn + "(*) is not supported, only COUNT(*)")
+	}
+	qb.SelectColumns = []string{fmt.Sprintf(`%s(%s) AS "%s"`, fn, selector, strings.ToLower(fn))}
+	return qb
+}
+
+// ExcludeDeleted skips soft-deleted base rows: those whose column (e.g. "deleted_at") is set.
+// The condition goes in the base table's WHERE subquery, so it filters before any join
+// and FilterQuery can still append its own WHERE. See SoftDelete.
+func (qb *QueryBuilder) ExcludeDeleted(column string) *QueryBuilder {
+	quoted, err := QuoteIdentifier(column)
+	if err != nil {
+		panic(fmt.Sprintf("invalid soft-delete column: %v", err))
+	}
+	qb.DeletedColumn = quoted
+	return qb
+}
+
 func (qb *QueryBuilder) Join(table string, alias string, on string) *QueryBuilder {
 	qb.Steps = append(qb.Steps, JoinStep{Join{
 		Table:       table,
@@ -211,7 +1008,113 @@ func (qb *QueryBuilder) Left(table string, alias string, on string) *QueryBuilde
 	return qb
 }
 
+// Right adds a RIGHT JOIN: rows of table without a base match have NULL base columns,
+// so scan them into pointer (or Null*) fields
+func (qb *QueryBuilder) Right(table string, alias string, on string) *QueryBuilder {
+	qb.Steps = append(qb.Steps, JoinStep{Join{
+		Table:       table,
+		TableAlias:  alias,
+		JoinType:    "RIGHT JOIN",
+		OnCondition: on,
+	}})
+	return qb
+}
+
+// FullOuter adds a FULL OUTER JOIN: unmatched rows of either side come back with the
+// other side's columns NULL (see Right for the base table)
+func (qb *QueryBuilder) FullOuter(table string, alias string, on string) *QueryBuilder {
+	qb.Steps = append(qb.Steps, JoinStep{Join{
+		Table:       table,
+		TableAlias:  alias,
+		JoinType:    "FULL OUTER JOIN",
+		OnCondition: on,
+	}})
+	return qb
+}
+
+// Union combines other's query with UNION: Build renders (query) UNION (other).
+// Both sides must select the same columns (narrow them with Columns); Validate reports a mismatch.
+// other's WhereArgs placeholders are renumbered after this builder's, and Args returns both.
+func (qb *QueryBuilder) Union(other *QueryBuilder) *QueryBuilder {
+	qb.Unions = append(qb.Unions, UnionPart{Query: other})
+	return qb
+}
+
+// UnionAll is Union keeping duplicate rows (UNION ALL)
+func (qb *QueryBuilder) UnionAll(other *QueryBuilder) *QueryBuilder {
+	qb.Unions = append(qb.Unions, UnionPart{Query: other, All: true})
+	return qb
+}
+
+// outputColumns returns the names of the columns the builder selects (a union's are those of
+// its first query); ok is false when they can't be known up front (raw queries, wildcards)
+func (qb *QueryBuilder) outputColumns() (columns []string, ok bool) {
+	if qb.Raw != "" || qb.Wildcard {
+		return nil, false
+	}
+	switch {
+	case len(qb.SelectColumns) > 0:
+		columns = make([]string, 0, len(qb.SelectColumns)+len(qb.AggColumns))
+		for _, selector := range qb.SelectColumns {
+			columns = append(columns, selectorName(selector))
+		}
+	case qb.isModellessCTE(qb.Table):
+		return nil, false
+	default:
+		_, baseColumns := GetSelectFields(qb.Table, "")
+		columns = slices.Clone(baseColumns) // the cached names must not be appended to
+		for _, step := range qb.Steps {
+			if s, isJoin := step.(JoinStep); isJoin && !qb.isModellessCTE(s.Join.Table) {
+				_, joinColumns := GetSelectFields(s.Join.Table, s.Join.TableAlias)
+				columns = append(columns, joinColumns...)
+			}
+		}
+	}
+	for _, column := range qb.AggColumns {
+		columns = append(columns, selectorName(column))
+	}
+	return columns, true
+}
+
+// selectorName returns the output name of a select list entry: its AS alias, or the column
+// of a "table"."column" selector
+func selectorName(selector string) string {
+	if i := strings.LastIndex(selector, " AS "); i >= 0 {
+		return strings.Trim(selector[i+len(" AS "):], `"`)
+	}
+	return strings.Trim(selector[strings.LastIndexByte(selector, '.')+1:], `"`)
+}
+
 func (qb *QueryBuilder) Build() string {
+	if len(qb.Unions) == 0 {
+		return qb.buildSelect()
+	}
+
+	argCount := len(qb.ownArgs())
+
+	var sb strings.Builder
+	sb.WriteString("(")
+	sb.WriteString(qb.buildSelect())
+	sb.WriteString(")")
+	for _, union := range qb.Unions {
+		if union.All {
+			sb.WriteString(" UNION ALL (")
+		} else {
+			sb.WriteString(" UNION (")
+		}
+		sb.WriteString(shiftPlaceholders(union.Query.Build(), argCount))
+		sb.WriteString(")")
+		argCount += len(union.Query.Args())
+	}
+	return sb.String()
+}
+
+// buildSelect renders the builder's own query, without unions
+func (qb *QueryBuilder) buildSelect() string {
+	if qb.Raw != "" {
+		return qb.withClause() + qb.Raw
+	}
+
 	var baseWheres []string
 	var joinsList []*Join
 	var whereConditions []string
@@ -220,22 +1123,44 @@ func (qb *QueryBuilder) Build() string {
 	hasJoins := false
 
 	// Collect fields from base table
-	baseFields, _ = GetSelectFields(qb.Table, "")
+	if qb.isModellessCTE(qb.Table) {
+		baseFields = []string{`"` + qb.Table + `".*`}
+	} else if qb.Wildcard {
+		baseFields = wildcardSelectFields(qb.Table)
+	} else {
+		baseFields, _ = GetSelectFields(qb.Table, "")
+	}
 	fields = append(fields, baseFields...)
 
+	var orderBy []string
+	var limit, offset *int64
+	argCount := 0
 	for _, step := range qb.Steps {
 		switch s := step.(type) {
+		case orderByStep:
+			orderBy = append(orderBy, s.Clause)
+		case limitStep:
+			limit = &s.Limit
+		case offsetStep:
+			offset = &s.Offset
 		case WhereStep:
+			condition := s.Condition
+			if len(s.Args) > 0 {
+				condition = shiftPlaceholders(condition, argCount)
+				argCount += len(s.Args)
+			}
 			if !hasJoins {
-				baseWheres = append(baseWheres, s.Condition)
+				baseWheres = append(baseWheres, condition)
 			} else {
-				whereConditions = append(whereConditions, s.Condition)
+				whereConditions = append(whereConditions, condition)
 			}
 		case JoinStep:
 			hasJoins = true
-			// Collect fields from join table
-			joinFields, _ := GetSelectFields(s.Join.Table, s.Join.TableAlias)
-			fields = append(fields, joinFields...)
+			// Collect fields from join table (a joined CTE without a model adds none)
+			if !qb.isModellessCTE(s.Join.Table) {
+				joinFields, _ := GetSelectFields(s.Join.Table, s.Join.TableAlias)
+				fields = append(fields, joinFields...)
+			}
 			// Add join to joinsList
 			joinsList = append(joinsList, &s.Join)
 		default:
@@ -243,12 +1168,16 @@ func (qb *QueryBuilder) Build() string {
 		}
 	}
 
+	if qb.DeletedColumn != "" {
+		baseWheres = append(baseWheres, fmt.Sprintf(`"%s".%s IS NULL`, qb.Table, qb.DeletedColumn))
+	}
+
 	// Build base table without using SELECT *
 	var baseTable string
 	if len(baseWheres) > 0 {
 		baseTable = fmt.Sprintf(`(SELECT %s FROM "%s" WHERE %s) AS "%s"`, strings.Join(baseFields, ", "), qb.Table, strings.Join(baseWheres, " AND "), qb.Table)
 	} else {
-		baseTable = qb.Table
+		baseTable = `"` + qb.Table + `"`
 	}
 
 	// Build joins
@@ -261,8 +1190,22 @@ func (qb *QueryBuilder) Build() string {
 		joins = append(joins, fmt.Sprintf(` %s %s ON %s `, join.JoinType, table, join.OnCondition))
 	}
 
+	// Explicit columns replace the generated list; a base-WHERE subquery above still selects every base field
+	if len(qb.SelectColumns) > 0 {
+		fields = qb.SelectColumns
+	}
+	if len(qb.AggColumns) > 0 {
+		fields = append(slices.Clone(fields), qb.AggColumns...)
+	}
+	selectKeyword := "SELECT"
+	if len(qb.DistinctOnColumns) > 0 {
+		selectKeyword = "SELECT DISTINCT ON (" + strings.Join(qb.DistinctOnColumns, ", ") + ")"
+	} else if qb.DistinctRows {
+		selectKeyword = "SELECT DISTINCT"
+	}
+
 	// Build query
-	query := fmt.Sprintf(`SELECT %s FROM "%s" `, strings.Join(fields, ", "), baseTable)
+	query := fmt.Sprintf(`%s%s %s FROM %s `, qb.withClause(), selectKeyword, strings.Join(fields, ", "), baseTable)
 
 	if len(joins) > 0 {
 		query += " " + strings.Join(joins, " ")
@@ -272,9 +1215,55 @@ func (qb *QueryBuilder) Build() string {
 		query += " WHERE " + strings.Join(whereConditions, " AND ")
 	}
 
+	// ORDER BY, LIMIT, OFFSET in SQL order (BuildFilterCount strips all three)
+	if len(orderBy) > 0 {
+		query += " ORDER BY " + strings.Join(orderBy, ", ")
+	}
+	if limit != nil {
+		query += " LIMIT " + strconv.FormatInt(*limit, 10)
+	}
+	if offset != nil {
+		query += " OFFSET " + strconv.FormatInt(*offset, 10)
+	}
+
 	return query
 }
 
+// shiftPlaceholders adds offset to every $N placeholder outside single-quoted literals
+func shiftPlaceholders(condition string, offset int) string {
+	if offset == 0 {
+		return condition
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(condition) + 4)
+	inQuote := false
+	for i := 0; i < len(condition); i++ {
+		c := condition[i]
+		if c == '\'' {
+			inQuote = !inQuote
+		}
+		if c != '$' || inQuote {
+			sb.WriteByte(c)
+			continue
+		}
+
+		j := i + 1
+		for j < len(condition) && condition[j] >= '0' && condition[j] <= '9' {
+			j++
+		}
+		if j == i+1 {
+			sb.WriteByte(c)
+			continue
+		}
+		n, _ := strconv.Atoi(condition[i+1 : j])
+		sb.WriteByte('$')
+		sb.WriteString(strconv.Itoa(n + offset))
+		i = j - 1
+	}
+	return sb.String()
+}
+
 func GenNewUUID(table string) string {
 	return uuid.New().String()
 }
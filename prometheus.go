@@ -0,0 +1,59 @@
+//go:build fsql_prometheus
+
+// prometheus.go - Prometheus collector for the connection pool
+// Built only with -tags fsql_prometheus, so the core package doesn't depend on client_golang.
+package fsql
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// PoolCollector reports DB pool statistics as Prometheus metrics.
+// Stats are read from DB.Stat() on every scrape; nothing is reported while DB is nil.
+type PoolCollector struct {
+	totalConns      *prometheus.Desc
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	acquireDuration *prometheus.Desc
+}
+
+// NewPoolCollector returns a collector for the global pool, ready for prometheus.MustRegister
+func NewPoolCollector() *PoolCollector {
+	return &PoolCollector{
+		totalConns: prometheus.NewDesc("fsql_pool_total_connections",
+			"Connections currently in the pool.", nil, nil),
+		acquiredConns: prometheus.NewDesc("fsql_pool_acquired_connections",
+			"Connections currently acquired (in use).", nil, nil),
+		idleConns: prometheus.NewDesc("fsql_pool_idle_connections",
+			"Connections currently idle.", nil, nil),
+		emptyAcquires: prometheus.NewDesc("fsql_pool_empty_acquire_total",
+			"Acquires that had to wait because the pool was empty.", nil, nil),
+		acquireDuration: prometheus.NewDesc("fsql_pool_acquire_duration_seconds_total",
+			"Total time spent acquiring connections.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.totalConns
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.emptyAcquires
+	ch <- c.acquireDuration
+}
+
+// Collect implements prometheus.Collector
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	pool := DB
+	if pool == nil {
+		return
+	}
+
+	stats := pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(stats.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(stats.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(stats.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stats.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stats.AcquireDuration().Seconds())
+}
@@ -3,7 +3,11 @@ package fsql
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 )
 
 // Insert executes an INSERT query and scans the RETURNING value
@@ -33,32 +37,502 @@ func Insert(ctx context.Context, tableName string, values map[string]interface{}
 }
 
 // Update executes an UPDATE query and scans the RETURNING value
+// The row is matched by values[returning], so returning is required (see GetUpdateQuerySafe)
 func Update(ctx context.Context, tableName string, values map[string]interface{}, returning string) error {
-	query, args := GetUpdateQuery(tableName, values, returning)
+	query, args, err := GetUpdateQuerySafe(tableName, values, returning)
+	if err != nil {
+		return err
+	}
+
+	// Scan RETURNING value back into the values map
+	var returnValue interface{}
+	if err := DB.QueryRow(ctx, query, args...).Scan(&returnValue); err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	// Store returned value in values map
+	values[returning] = returnValue
+	return nil
+}
+
+// InsertReturning is Insert scanning the RETURNING column straight into a T,
+// without round-tripping it through interface{} and the values map
+//
+//	id, err := fsql.InsertReturning[string](ctx, "users", values, "uuid")
+func InsertReturning[T any](ctx context.Context, tableName string, values map[string]interface{}, returning string) (T, error) {
+	var result T
+	if returning == "" {
+		return result, errors.New("insert returning requires a returning column")
+	}
+
+	query, args := GetInsertQuery(tableName, values, returning)
+	if err := DB.QueryRow(ctx, query, args...).Scan(&result); err != nil {
+		var zero T
+		return zero, fmt.Errorf("insert failed: %w", err)
+	}
+	return result, nil
+}
+
+// UpdateReturning is Update scanning the RETURNING column straight into a T.
+// It returns sql.ErrNoRows (wrapped) when no row matched values[returning].
+func UpdateReturning[T any](ctx context.Context, tableName string, values map[string]interface{}, returning string) (T, error) {
+	var result T
+	query, args, err := GetUpdateQuerySafe(tableName, values, returning)
+	if err != nil {
+		return result, err
+	}
+
+	if err = DB.QueryRow(ctx, query, args...).Scan(&result); err != nil {
+		var zero T
+		return zero, fmt.Errorf("update failed: %w", err)
+	}
+	return result, nil
+}
+
+// ErrVersionConflict is returned by UpdateVersioned when the row's version no longer matches
+var ErrVersionConflict = errors.New("version conflict")
+
+// UpdateVersioned executes GetUpdateQueryVersioned: values are written only if the row identified
+// by values[returning] still has versionCol = expectedVersion, which is then incremented.
+// It returns ErrVersionConflict when no row matched, i.e. the row was updated concurrently
+// (or no longer exists); reload it and retry. values must contain the returning column.
+func UpdateVersioned(ctx context.Context, tableName string, values map[string]interface{}, returning string, versionCol string, expectedVersion int) error {
+	if _, ok := values[returning]; !ok {
+		return fmt.Errorf("returning column %s missing from values", returning)
+	}
+	query, args := GetUpdateQueryVersioned(tableName, values, returning, versionCol, expectedVersion)
+
+	var returnValue interface{}
+	err := DB.QueryRow(ctx, query, args...).Scan(&returnValue)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVersionConflict
+	}
+	if err != nil {
+		return fmt.Errorf("update failed: %w", err)
+	}
+
+	values[returning] = returnValue
+	values[versionCol] = expectedVersion + 1
+	return nil
+}
+
+// InsertMap inserts values into a table keyed by a "uuid" column and returns the row's uuid.
+// A new uuid is generated when values has none; the caller's map is not modified.
+func InsertMap(ctx context.Context, tableName string, values map[string]interface{}) (string, error) {
+	if id, ok := values["uuid"]; !ok || id == nil || id == "" {
+		withID := make(map[string]interface{}, len(values)+1)
+		for k, v := range values {
+			withID[k] = v
+		}
+		withID["uuid"] = GenNewUUID(tableName)
+		values = withID
+	}
+
+	query, args := GetInsertQuery(tableName, values, "uuid")
+
+	var id string
+	if err := DB.QueryRow(ctx, query, args...).Scan(&id); err != nil {
+		return "", fmt.Errorf("insert failed: %w", err)
+	}
+
+	return id, nil
+}
 
+// InsertColumns inserts one row from parallel column/value slices, keeping the column order,
+// and returns the RETURNING value (nil when returning is empty)
+func InsertColumns(ctx context.Context, tableName string, columns []string, values []interface{}, returning string) (interface{}, error) {
+	query, args, err := buildInsertColumnsQuery(tableName, columns, values, returning)
+	if err != nil {
+		return nil, err
+	}
+
+	if returning == "" {
+		if _, err := DB.Exec(ctx, query, args...); err != nil {
+			return nil, fmt.Errorf("insert failed: %w", err)
+		}
+		return nil, nil
+	}
+
+	var returnValue interface{}
+	if err := DB.QueryRow(ctx, query, args...).Scan(&returnValue); err != nil {
+		return nil, fmt.Errorf("insert failed: %w", err)
+	}
+	return returnValue, nil
+}
+
+// buildInsertColumnsQuery builds the INSERT for InsertColumns
+func buildInsertColumnsQuery(tableName string, columns []string, values []interface{}, returning string) (string, []interface{}, error) {
+	if len(columns) != len(values) {
+		return "", nil, fmt.Errorf("insert columns/values mismatch: %d columns, %d values", len(columns), len(values))
+	}
+	if len(columns) == 0 {
+		return "", nil, errors.New("no columns to insert")
+	}
+
+	quotedTable, err := QuoteIdentifier(tableName)
+	if err != nil {
+		return "", nil, err
+	}
+
+	quotedColumns := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	args := make([]interface{}, len(values))
+	for i, col := range columns {
+		if quotedColumns[i], err = QuoteIdentifier(col); err != nil {
+			return "", nil, err
+		}
+		// Same ::jsonb cast as GetInsertQuery
+		if isJSONBType(values[i]) {
+			placeholders[i] = fmt.Sprintf("$%d::jsonb", i+1)
+			args[i] = jsonbArg(values[i])
+		} else {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+			args[i] = values[i]
+		}
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (%s) VALUES (%s)`, quotedTable, strings.Join(quotedColumns, ","), strings.Join(placeholders, ","))
 	if returning != "" {
-		// Scan RETURNING value back into the values map
-		var returnValue interface{}
-		err := DB.QueryRow(ctx, query, args...).Scan(&returnValue)
+		query += returningClause(tableName, []string{returning})
+	}
+
+	return query, args, nil
+}
+
+// InsertDefault is Insert returning the table's registered primary key (see RegisterPrimaryKey)
+func InsertDefault(ctx context.Context, tableName string, values map[string]interface{}) error {
+	pk, ok := PrimaryKey(tableName)
+	if !ok {
+		return fmt.Errorf("no primary key registered for table: %s", tableName)
+	}
+	return Insert(ctx, tableName, values, pk)
+}
+
+// UpdateDefault is Update keyed on (and returning) the table's registered primary key
+func UpdateDefault(ctx context.Context, tableName string, values map[string]interface{}) error {
+	pk, ok := PrimaryKey(tableName)
+	if !ok {
+		return fmt.Errorf("no primary key registered for table: %s", tableName)
+	}
+	return Update(ctx, tableName, values, pk)
+}
+
+// InsertObject inserts object's "i" mode fields (see StructToInsertMap) into the registered
+// tableName against the pool and, when returning is set, scans the RETURNING column back into
+// the field tagged db:"<returning>", so object must then be a pointer.
+//
+//	err := fsql.InsertObject(ctx, &user, "users", "uuid") // user.UUID is set
+func InsertObject(ctx context.Context, object interface{}, tableName string, returning string) error {
+	var dest interface{}
+	if returning != "" {
+		field, err := columnField(object, returning)
 		if err != nil {
-			return fmt.Errorf("update failed: %w", err)
+			return err
 		}
+		dest = field.Addr().Interface()
+	}
 
-		// Store returned value in values map
-		values[returning] = returnValue
+	query, args, err := buildInsertObjectQuery(object, tableName, returning)
+	if err != nil {
+		return err
+	}
+
+	if dest == nil {
+		if _, err := DB.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("insert failed: %w", err)
+		}
 		return nil
 	}
+	if err := DB.QueryRow(ctx, query, args...).Scan(dest); err != nil {
+		return fmt.Errorf("insert failed: %w", err)
+	}
+	return nil
+}
 
-	// No RETURNING clause - just exec
-	_, err := DB.Exec(ctx, query, args...)
+// UpdateObject updates object's "u" mode fields in the registered tableName against the pool,
+// for the row whose whereCol equals the object's field tagged db:"<whereCol>". SET values get
+// the same ::jsonb casts as GetUpdateQuery.
+//
+//	err := fsql.UpdateObject(ctx, &user, "users", "uuid")
+func UpdateObject(ctx context.Context, object interface{}, tableName string, whereCol string) error {
+	key, err := structToMap(object, func(f ModelField, v reflect.Value) bool {
+		return whereCol != "" && f.DbName == whereCol
+	})
+	if err != nil {
+		return err
+	}
+	keyValue, ok := key[whereCol]
+	if !ok {
+		return fmt.Errorf("no field tagged db:%q in %T", whereCol, object)
+	}
+
+	where := fmt.Sprintf(`"%s"."%s" = $1`, quotesReplacer.Replace(tableName), quotesReplacer.Replace(whereCol))
+	query, args, err := buildUpdateObjectQuery(object, tableName, where, keyValue)
 	if err != nil {
+		return err
+	}
+	if _, err := DB.Exec(ctx, query, args...); err != nil {
 		return fmt.Errorf("update failed: %w", err)
 	}
+	return nil
+}
+
+// columnField returns the settable field of the struct object points to tagged db:"column"
+func columnField(object interface{}, column string) (reflect.Value, error) {
+	val := reflect.ValueOf(object)
+	if val.Kind() != reflect.Ptr || val.IsNil() || val.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("object must be a non-nil pointer to a struct, got %T", object)
+	}
+	val = val.Elem()
+
+	tagCache, err := getModelTagCache(val.Type())
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("failed to get model tag cache: %w", err)
+	}
+	for _, field := range tagCache.Fields {
+		if field.DbName == column {
+			return val.FieldByName(field.Name), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("no field tagged db:%q in %s", column, val.Type())
+}
+
+// InsertObjectContext inserts a struct object directly against the pool (no transaction);
+// it is InsertObject without RETURNING
+func InsertObjectContext(ctx context.Context, object interface{}, tableName string) error {
+	return InsertObject(ctx, object, tableName, "")
+}
+
+// UpdateObjectContext updates a struct object's "u" fields directly against the pool (no
+// transaction) for the rows matching whereClause; UpdateObject is the same keyed on one column
+func UpdateObjectContext(ctx context.Context, object interface{}, tableName, whereClause string, whereArgs ...interface{}) error {
+	query, values, err := buildUpdateObjectQuery(object, tableName, whereClause, whereArgs...)
+	if err != nil {
+		return err
+	}
+
+	_, err = DB.Exec(ctx, query, values...)
+	if err != nil {
+		return fmt.Errorf("failed to execute update: %w", err)
+	}
 
 	return nil
 }
 
-// SelectOne executes a query and scans a single row into dest
+// SoftDelete sets column (e.g. "deleted_at") to NOW() on the rows of tableName matching
+// whereClause and returns how many were deleted. Rows already soft-deleted keep their
+// original timestamp. Exclude them from reads with QueryBuilder.ExcludeDeleted.
+//
+//	n, err := fsql.SoftDelete(ctx, "website", "deleted_at", "uuid = $1", id)
+func SoftDelete(ctx context.Context, tableName, column, whereClause string, args ...interface{}) (int64, error) {
+	query, err := buildSoftDeleteQuery(tableName, column, whereClause)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := DB.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("soft delete failed: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// buildSoftDeleteQuery renders the UPDATE behind SoftDelete
+func buildSoftDeleteQuery(tableName, column, whereClause string) (string, error) {
+	if strings.TrimSpace(whereClause) == "" {
+		return "", errors.New("soft delete requires a where clause")
+	}
+	quotedTable, err := QuoteIdentifier(tableName)
+	if err != nil {
+		return "", fmt.Errorf("soft delete failed: %w", err)
+	}
+	quotedColumn, err := QuoteIdentifier(column)
+	if err != nil {
+		return "", fmt.Errorf("soft delete failed: %w", err)
+	}
+
+	return fmt.Sprintf(`UPDATE %s SET %s = NOW() WHERE (%s) AND %s IS NULL`,
+		quotedTable, quotedColumn, whereClause, quotedColumn), nil
+}
+
+// Delete removes the rows of tableName matching whereClause and returns how many were deleted.
+// An empty where clause is rejected; use Truncate to empty a table.
+//
+//	n, err := fsql.Delete(ctx, "website", "realm_uuid = $1", realmID)
+func Delete(ctx context.Context, tableName, whereClause string, args ...interface{}) (int64, error) {
+	query, err := buildDeleteQuery(tableName, whereClause)
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := DB.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete failed: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// DeleteByField removes the rows whose field equals value (IS NULL for nil) and returns how many
+// were deleted. field is a struct field name of the table's registered model or a column name.
+func DeleteByField(ctx context.Context, tableName, field string, value interface{}) (int64, error) {
+	column := field
+	if modelInfo, ok := getModelInfo(tableName); ok {
+		if dbField, ok := modelInfo.dbTagMap[field]; ok {
+			column = dbField
+		}
+	}
+	quotedColumn, err := QuoteIdentifier(column)
+	if err != nil {
+		return 0, fmt.Errorf("delete failed: %w", err)
+	}
+
+	if isNilValue(value) {
+		return Delete(ctx, tableName, quotedColumn+" IS NULL")
+	}
+	return Delete(ctx, tableName, quotedColumn+" = $1", value)
+}
+
+// DeleteByKeys removes the row matched by a (possibly composite) key: every column of keys must
+// equal its value in values. It returns how many rows were deleted.
+func DeleteByKeys(ctx context.Context, tableName string, keys []string, values map[string]interface{}) (int64, error) {
+	where, args, err := keyConditions(tableName, keys, values, 1)
+	if err != nil {
+		return 0, fmt.Errorf("delete failed: %w", err)
+	}
+	return Delete(ctx, tableName, where, args...)
+}
+
+// buildDeleteQuery renders the DELETE behind Delete
+func buildDeleteQuery(tableName, whereClause string) (string, error) {
+	if strings.TrimSpace(whereClause) == "" {
+		return "", errors.New("delete requires a where clause")
+	}
+	quotedTable, err := QuoteIdentifier(tableName)
+	if err != nil {
+		return "", fmt.Errorf("delete failed: %w", err)
+	}
+	return fmt.Sprintf(`DELETE FROM %s WHERE %s`, quotedTable, whereClause), nil
+}
+
+// CountSimple counts the rows of tableName matching whereClause ("" counts every row) with a
+// plain SELECT COUNT(*) FROM "table" WHERE ..., skipping BuildFilterCount's subquery
+func CountSimple(ctx context.Context, tableName, whereClause string, args ...interface{}) (int, error) {
+	query, err := buildSimpleTableQuery("SELECT COUNT(*) FROM ", tableName, whereClause, "")
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := DB.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count failed: %w", err)
+	}
+	return count, nil
+}
+
+// Exists reports whether any row of tableName matches whereClause ("" for any row).
+// Postgres stops at the first match, so it's cheaper than CountSimple for existence checks.
+func Exists(ctx context.Context, tableName, whereClause string, args ...interface{}) (bool, error) {
+	query, err := buildSimpleTableQuery("SELECT EXISTS(SELECT 1 FROM ", tableName, whereClause, ")")
+	if err != nil {
+		return false, err
+	}
+
+	var exists bool
+	if err := DB.QueryRow(ctx, query, args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("exists failed: %w", err)
+	}
+	return exists, nil
+}
+
+// buildSimpleTableQuery renders prefix + "table" [WHERE whereClause] + suffix
+func buildSimpleTableQuery(prefix, tableName, whereClause, suffix string) (string, error) {
+	quotedTable, err := QuoteIdentifier(tableName)
+	if err != nil {
+		return "", err
+	}
+	query := prefix + quotedTable
+	if strings.TrimSpace(whereClause) != "" {
+		query += " WHERE " + whereClause
+	}
+	return query + suffix, nil
+}
+
+// InsertOrGet inserts values with ON CONFLICT (conflictColumns) DO NOTHING and returns the
+// inserted row, or - when the insert hit a conflict - the existing row matched by the conflict
+// columns, with existed=true. Both statements run in one transaction. conflictWhere is the
+// optional predicate of a partial unique index (e.g. "deleted_at IS NULL").
+// values must contain every conflict column.
+func InsertOrGet[T any](ctx context.Context, tableName string, values map[string]interface{}, conflictColumns []string, conflictWhere ...string) (*T, bool, error) {
+	if len(conflictColumns) == 0 {
+		return nil, false, errors.New("insert or get requires at least one conflict column")
+	}
+
+	modelInfo, ok := getModelInfo(tableName)
+	if !ok {
+		return nil, false, fmt.Errorf("table name not initialized: %s", tableName)
+	}
+	// Only the base table's own columns, not the computed ones GetSelectFields adds
+	returningSQL := returningClause(tableName, modelInfo.dbFieldsSelect)
+	returning := strings.TrimPrefix(returningSQL, " RETURNING ")
+
+	quotedColumns := make([]string, len(conflictColumns))
+	for i, col := range conflictColumns {
+		if _, ok := values[col]; !ok {
+			return nil, false, fmt.Errorf("conflict column %s missing from values", col)
+		}
+		q, err := QuoteIdentifier(col)
+		if err != nil {
+			return nil, false, err
+		}
+		quotedColumns[i] = q
+	}
+
+	insertQuery, insertArgs := GetInsertQuery(tableName, values, "")
+	insertQuery += " ON CONFLICT (" + strings.Join(quotedColumns, ", ") + ")"
+	if len(conflictWhere) > 0 && conflictWhere[0] != "" {
+		insertQuery += " WHERE " + conflictWhere[0]
+	}
+	insertQuery += " DO NOTHING" + returningSQL
+
+	conditions := make([]string, len(quotedColumns))
+	selectArgs := make([]interface{}, len(conflictColumns))
+	for i, col := range conflictColumns {
+		conditions[i] = fmt.Sprintf(`"%s".%s = $%d`, tableName, quotedColumns[i], i+1)
+		selectArgs[i] = values[col]
+	}
+	if len(conflictWhere) > 0 && conflictWhere[0] != "" {
+		conditions = append(conditions, "("+conflictWhere[0]+")")
+	}
+	selectQuery := fmt.Sprintf(`SELECT %s FROM "%s" WHERE %s LIMIT 1`, returning, tableName, strings.Join(conditions, " AND "))
+
+	var result T
+	var existed bool
+	err := WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+		// WithTx may retry, so start each attempt from a clean result
+		var zero T
+		result, existed = zero, false
+
+		err := tx.GetContext(ctx, &result, insertQuery, insertArgs...)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		// Conflict: DO NOTHING returned no row, load the existing one
+		existed = true
+		return tx.GetContext(ctx, &result, selectQuery, selectArgs...)
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("insert or get failed: %w", err)
+	}
+
+	return &result, existed, nil
+}
+
+// SelectOne executes a query and scans a single row into dest.
+// It returns sql.ErrNoRows (check with errors.Is) when no row matches.
 func SelectOne(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
 	rows, err := DB.Query(ctx, query, args...)
 	if err != nil {
@@ -89,3 +563,40 @@ func Exec(ctx context.Context, query string, args ...interface{}) error {
 func QueryRow(ctx context.Context, query string, args ...interface{}) interface{} {
 	return DB.QueryRow(ctx, query, args...)
 }
+
+// TruncateOptions controls the optional clauses of Truncate
+type TruncateOptions struct {
+	Cascade         bool // Also truncate tables with foreign keys referencing these tables
+	RestartIdentity bool // Reset sequences owned by the truncated tables
+}
+
+// Truncate empties one or more tables in a single TRUNCATE statement
+func Truncate(ctx context.Context, tables []string, opts TruncateOptions) error {
+	if len(tables) == 0 {
+		return errors.New("truncate requires at least one table")
+	}
+
+	quoted := make([]string, len(tables))
+	for i, table := range tables {
+		q, err := QuoteIdentifier(table)
+		if err != nil {
+			return fmt.Errorf("truncate failed: %w", err)
+		}
+		quoted[i] = q
+	}
+
+	query := "TRUNCATE TABLE " + strings.Join(quoted, ", ")
+	if opts.RestartIdentity {
+		query += " RESTART IDENTITY"
+	}
+	if opts.Cascade {
+		query += " CASCADE"
+	}
+
+	_, err := DB.Exec(ctx, query)
+	if err != nil {
+		return fmt.Errorf("truncate failed: %w", err)
+	}
+
+	return nil
+}
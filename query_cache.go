@@ -2,13 +2,19 @@
 package fsql
 
 import (
+	"context"
 	"crypto/sha256"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
+	"math"
+	"reflect"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
-	"unsafe"
 )
 
 // QueryCacheEntry represents a cached query result
@@ -20,10 +26,18 @@ type QueryCacheEntry struct {
 	UseCount  int64
 }
 
+// resultCacheEntry is a result set cached by CachedSelect
+type resultCacheEntry struct {
+	table     string
+	rows      reflect.Value // Slice owned by the cache, only ever copied out
+	expiresAt time.Time
+}
+
 // QueryCache is a simple LRU cache for query results
 type QueryCache struct {
 	entries        map[string]*QueryCacheEntry
 	preparedStmt   map[string]*PreparedStatement
+	results        map[string]*resultCacheEntry
 	maxSize        int
 	ttl            time.Duration
 	mutex          sync.RWMutex
@@ -31,6 +45,8 @@ type QueryCache struct {
 	misses         int64
 	evictions      int64
 	savedAllocations int64
+	resultHits     int64
+	resultMisses   int64
 }
 
 // PreparedStatement represents a prepared query statement
@@ -55,13 +71,6 @@ var (
 		{"$1", "$2", "$3", "$4"}, // 4 params
 		{"$1", "$2", "$3", "$4", "$5"}, // 5 params
 	}
-
-	// Object pools for query cache operations
-	argSlicePool = sync.Pool{
-		New: func() interface{} {
-			return make([]interface{}, 0, 8)
-		},
-	}
 )
 
 // NewQueryCache creates a new query cache with specified capacity and TTL
@@ -69,6 +78,7 @@ func NewQueryCache(maxSize int, ttl time.Duration) *QueryCache {
 	return &QueryCache{
 		entries:      make(map[string]*QueryCacheEntry, maxSize),
 		preparedStmt: make(map[string]*PreparedStatement, maxSize),
+		results:      make(map[string]*resultCacheEntry),
 		maxSize:      maxSize,
 		ttl:          ttl,
 	}
@@ -79,25 +89,9 @@ func generateCacheKey(query string, args []interface{}) string {
 	h := sha256.New()
 	h.Write([]byte(query))
 
+	var buf [8]byte
 	for _, arg := range args {
-		switch v := arg.(type) {
-		case string:
-			h.Write([]byte(v))
-		case int:
-			h.Write([]byte{byte(v & 0xFF), byte((v >> 8) & 0xFF), byte((v >> 16) & 0xFF), byte((v >> 24) & 0xFF)})
-		case int64:
-			h.Write([]byte{byte(v & 0xFF), byte((v >> 8) & 0xFF), byte((v >> 16) & 0xFF), byte((v >> 24) & 0xFF),
-				byte((v >> 32) & 0xFF), byte((v >> 40) & 0xFF), byte((v >> 48) & 0xFF), byte((v >> 56) & 0xFF)})
-		case float64:
-			var buf [8]byte
-			binary := *(*[8]byte)(unsafe.Pointer(&v))
-			for i := 0; i < 8; i++ {
-				buf[i] = binary[i]
-			}
-			h.Write(buf[:])
-		default:
-			h.Write([]byte(fmt.Sprintf("%v", v)))
-		}
+		writeCacheKeyArg(h, arg, buf[:])
 		h.Write([]byte{':'})
 	}
 
@@ -105,6 +99,69 @@ func generateCacheKey(query string, args []interface{}) string {
 	return hex.EncodeToString(sum)
 }
 
+// writeCacheKeyArg hashes a single argument, prefixed by a type tag so that
+// values of different types with the same textual form don't collide.
+// Slices are hashed element by element with a length prefix.
+func writeCacheKeyArg(h hash.Hash, arg interface{}, buf []byte) {
+	switch v := arg.(type) {
+	case nil:
+		h.Write([]byte{'n'})
+	case string:
+		h.Write([]byte{'s'})
+		binary.LittleEndian.PutUint64(buf, uint64(len(v)))
+		h.Write(buf)
+		h.Write([]byte(v))
+	case []byte:
+		h.Write([]byte{'y'})
+		binary.LittleEndian.PutUint64(buf, uint64(len(v)))
+		h.Write(buf)
+		h.Write(v)
+	case int:
+		h.Write([]byte{'i'})
+		binary.LittleEndian.PutUint64(buf, uint64(v))
+		h.Write(buf)
+	case int64:
+		h.Write([]byte{'i'})
+		binary.LittleEndian.PutUint64(buf, uint64(v))
+		h.Write(buf)
+	case float64:
+		h.Write([]byte{'f'})
+		binary.LittleEndian.PutUint64(buf, math.Float64bits(v))
+		h.Write(buf)
+	case bool:
+		if v {
+			h.Write([]byte{'b', 1})
+		} else {
+			h.Write([]byte{'b', 0})
+		}
+	case time.Time:
+		h.Write([]byte{'t'})
+		binary.LittleEndian.PutUint64(buf, uint64(v.UnixNano()))
+		h.Write(buf)
+	case *time.Time:
+		if v == nil {
+			h.Write([]byte{'n'})
+			return
+		}
+		writeCacheKeyArg(h, *v, buf)
+	default:
+		rv := reflect.ValueOf(arg)
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			h.Write([]byte{'['})
+			binary.LittleEndian.PutUint64(buf, uint64(rv.Len()))
+			h.Write(buf)
+			for i := 0; i < rv.Len(); i++ {
+				writeCacheKeyArg(h, rv.Index(i).Interface(), buf)
+				h.Write([]byte{','})
+			}
+			h.Write([]byte{']'})
+			return
+		}
+		h.Write([]byte{'v'})
+		h.Write([]byte(fmt.Sprintf("%T:%v", v, v)))
+	}
+}
+
 // Get retrieves a cached query, returns nil if not found
 func (c *QueryCache) Get(query string, args []interface{}) *QueryCacheEntry {
 	key := generateCacheKey(query, args)
@@ -162,22 +219,15 @@ func (c *QueryCache) Set(query string, args []interface{}) *QueryCacheEntry {
 	return entry
 }
 
-// CloneArgs creates a deep copy of the argument slice
+// CloneArgs creates a copy of the argument slice
+// The copy is freshly allocated (never pooled) because cache entries keep it indefinitely
 func CloneArgs(args []interface{}) []interface{} {
 	if len(args) == 0 {
 		return nil
 	}
 
-	newArgs := argSlicePool.Get().([]interface{})
-	newArgs = newArgs[:0]
-
-	if cap(newArgs) < len(args) {
-		newArgs = make([]interface{}, 0, len(args)*2)
-	}
-
-	for _, arg := range args {
-		newArgs = append(newArgs, arg)
-	}
+	newArgs := make([]interface{}, len(args))
+	copy(newArgs, args)
 
 	return newArgs
 }
@@ -215,6 +265,9 @@ func (c *QueryCache) Stats() map[string]int64 {
 		"misses":      atomic.LoadInt64(&c.misses),
 		"evictions":   atomic.LoadInt64(&c.evictions),
 		"saved_allocs": atomic.LoadInt64(&c.savedAllocations),
+		"results":       int64(c.resultCount()),
+		"result_hits":   atomic.LoadInt64(&c.resultHits),
+		"result_misses": atomic.LoadInt64(&c.resultMisses),
 	}
 }
 
@@ -224,6 +277,7 @@ func (c *QueryCache) Clear() {
 	defer c.mutex.Unlock()
 	c.entries = make(map[string]*QueryCacheEntry, c.maxSize)
 	c.preparedStmt = make(map[string]*PreparedStatement, c.maxSize)
+	c.results = make(map[string]*resultCacheEntry)
 }
 
 // GetPreparedStatement retrieves a prepared statement from cache
@@ -298,6 +352,173 @@ func CachedQuery(query string, args []interface{}) (string, []interface{}) {
 	return entry.Query, entry.Args
 }
 
+// CachedSelect is SelectMany with the scanned rows cached for ttl, keyed on query and args.
+// dest must be a pointer to a slice; it always receives a deep copy, so callers may modify it.
+// Writes don't expire entries: call InvalidateCache for the tables they touch.
+func CachedSelect(ctx context.Context, dest interface{}, ttl time.Duration, query string, args ...interface{}) error {
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() || destVal.Elem().Kind() != reflect.Slice {
+		return errors.New("CachedSelect dest must be a pointer to a slice")
+	}
+	sliceType := destVal.Elem().Type()
+	key := generateCacheKey(query, args)
+
+	if rows, ok := globalQueryCache.getResult(key, sliceType); ok {
+		destVal.Elem().Set(deepCopyValue(rows))
+		return nil
+	}
+
+	fresh := reflect.New(sliceType)
+	if err := SelectMany(ctx, fresh.Interface(), query, args...); err != nil {
+		return err
+	}
+	globalQueryCache.setResult(key, extractTableName(query), deepCopyValue(fresh.Elem()), ttl)
+	destVal.Elem().Set(fresh.Elem())
+	return nil
+}
+
+// InvalidateCache drops the CachedSelect results of queries whose table starts with tablePrefix
+// ("" drops everything). The table is the one extractTableName finds in the query.
+func InvalidateCache(tablePrefix string) {
+	globalQueryCache.invalidateResults(tablePrefix)
+}
+
+// getResult returns the unexpired cached rows for key if they were scanned into sliceType
+func (c *QueryCache) getResult(key string, sliceType reflect.Type) (reflect.Value, bool) {
+	c.mutex.RLock()
+	entry, found := c.results[key]
+	c.mutex.RUnlock()
+
+	if !found || entry.rows.Type() != sliceType {
+		atomic.AddInt64(&c.resultMisses, 1)
+		return reflect.Value{}, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		c.mutex.Lock()
+		if c.results[key] == entry {
+			delete(c.results, key)
+			atomic.AddInt64(&c.evictions, 1)
+		}
+		c.mutex.Unlock()
+		atomic.AddInt64(&c.resultMisses, 1)
+		return reflect.Value{}, false
+	}
+
+	atomic.AddInt64(&c.resultHits, 1)
+	return entry.rows, true
+}
+
+// setResult caches rows for key until ttl elapses
+func (c *QueryCache) setResult(key, table string, rows reflect.Value, ttl time.Duration) {
+	now := time.Now()
+	entry := &resultCacheEntry{table: table, rows: rows, expiresAt: now.Add(ttl)}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.results[key]; !exists && len(c.results) >= c.maxSize {
+		c.evictResult(now)
+	}
+	c.results[key] = entry
+}
+
+// evictResult removes expired results, or the one closest to expiring if none has
+func (c *QueryCache) evictResult(now time.Time) {
+	var soonestKey string
+	var soonest time.Time
+	for k, v := range c.results {
+		if now.After(v.expiresAt) {
+			delete(c.results, k)
+			atomic.AddInt64(&c.evictions, 1)
+			continue
+		}
+		if soonestKey == "" || v.expiresAt.Before(soonest) {
+			soonestKey = k
+			soonest = v.expiresAt
+		}
+	}
+
+	if len(c.results) >= c.maxSize && soonestKey != "" {
+		delete(c.results, soonestKey)
+		atomic.AddInt64(&c.evictions, 1)
+	}
+}
+
+// invalidateResults removes the results whose table starts with tablePrefix
+func (c *QueryCache) invalidateResults(tablePrefix string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for k, v := range c.results {
+		if strings.HasPrefix(v.table, tablePrefix) {
+			delete(c.results, k)
+		}
+	}
+}
+
+// resultCount returns the number of cached result sets
+func (c *QueryCache) resultCount() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.results)
+}
+
+// deepCopyValue copies v recursively through pointers, slices, maps, arrays, interfaces
+// and exported struct fields. Unexported fields (e.g. inside time.Time) are copied shallowly.
+func deepCopyValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		cp := reflect.New(v.Type().Elem())
+		cp.Elem().Set(deepCopyValue(v.Elem()))
+		return cp
+	case reflect.Slice:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		cp := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return cp
+	case reflect.Map:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		cp := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), deepCopyValue(iter.Value()))
+		}
+		return cp
+	case reflect.Array:
+		cp := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			cp.Index(i).Set(deepCopyValue(v.Index(i)))
+		}
+		return cp
+	case reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(v.Type())
+		}
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(deepCopyValue(v.Elem()))
+		return cp
+	case reflect.Struct:
+		cp := reflect.New(v.Type()).Elem()
+		cp.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				cp.Field(i).Set(deepCopyValue(v.Field(i)))
+			}
+		}
+		return cp
+	default:
+		return v
+	}
+}
+
 // GetParamPlaceholders returns parameter placeholders ($1, $2, etc.) for the given count
 func GetParamPlaceholders(count int) []string {
 	if count < len(paramPlaceholders) {
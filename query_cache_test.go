@@ -0,0 +1,162 @@
+package fsql
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestCloneArgsNoSharedBacking tests that cache entries own their args
+func TestCloneArgsNoSharedBacking(t *testing.T) {
+	cache := NewQueryCache(10, time.Minute)
+
+	args := []interface{}{"a", 1}
+	entry1 := cache.Set("SELECT 1 WHERE a = $1 AND b = $2", args)
+	entry2 := cache.Set("SELECT 2 WHERE a = $1 AND b = $2", args)
+
+	if &entry1.Args[0] == &entry2.Args[0] {
+		t.Fatal("Cache entries share the same backing array")
+	}
+	if &entry1.Args[0] == &args[0] {
+		t.Fatal("Cache entry shares the caller's backing array")
+	}
+
+	// Mutating the caller's slice or another entry must not leak into an entry
+	args[0] = "changed"
+	entry2.Args[1] = 99
+	if entry1.Args[0] != "a" || entry1.Args[1] != 1 {
+		t.Errorf("Entry args were modified: %v", entry1.Args)
+	}
+
+	if CloneArgs(nil) != nil {
+		t.Error("Expected nil for empty args")
+	}
+}
+
+// TestGenerateCacheKeyDeterministic tests cache keys for slice, time and bool args
+func TestGenerateCacheKeyDeterministic(t *testing.T) {
+	query := "SELECT * FROM ai_model WHERE type = ANY($1)"
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+
+	same := [][]interface{}{
+		{[]string{"a", "b"}},
+		{[]int64{1, 2, 3}},
+		{ts},
+		{true},
+		{int64(1) << 40},
+	}
+	for _, args := range same {
+		if generateCacheKey(query, args) != generateCacheKey(query, CloneArgs(args)) {
+			t.Errorf("Identical args produced different keys: %v", args)
+		}
+	}
+
+	distinct := [][]interface{}{
+		{[]string{"a", "b"}},
+		{[]string{"ab"}},
+		{[]string{"a", "c"}},
+		{[]string{"b", "a"}},
+		{[]int{1, 2}},
+		{ts},
+		{ts.Add(time.Nanosecond)},
+		{true},
+		{false},
+		{"true"},
+		{1},
+		{"1"},
+		{1 << 40},
+		{(1 << 40) + 1},
+		{nil},
+	}
+	keys := make(map[string]int)
+	for i, args := range distinct {
+		key := generateCacheKey(query, args)
+		if j, exists := keys[key]; exists {
+			t.Errorf("Args %v and %v produced the same key", distinct[j], args)
+		}
+		keys[key] = i
+	}
+
+	// Same instant in different locations is the same timestamp
+	if generateCacheKey(query, []interface{}{ts}) != generateCacheKey(query, []interface{}{ts.In(time.FixedZone("X", 3600))}) {
+		t.Error("Expected equal instants to produce the same key")
+	}
+}
+
+// TestCachedSelect tests result caching, deep copies, TTL expiry and invalidation
+func TestCachedSelect(t *testing.T) {
+	cleanDatabase(t)
+	ResetCache()
+	InvalidateCache("")
+	ctx := context.Background()
+
+	name := "Cached Model"
+	model := AIModel{Key: "cached_key", Name: &name, Type: "cached_type", Provider: "cached_provider"}
+	if err := model.Insert(); err != nil {
+		t.Fatalf("Insert error: %v", err)
+	}
+
+	query := aiModelBaseQuery + ` WHERE "ai_model".type = $1`
+	stats := globalQueryCache.Stats()
+	var first []AIModel
+	if err := CachedSelect(ctx, &first, time.Minute, query, "cached_type"); err != nil {
+		t.Fatalf("CachedSelect failed: %v", err)
+	}
+	if len(first) != 1 || *first[0].Name != "Cached Model" {
+		t.Fatalf("Unexpected rows: %+v", first)
+	}
+
+	// The copy handed out is the caller's: mutating it doesn't touch the cache
+	*first[0].Name = "mutated"
+	first[0].Key = "mutated"
+
+	// A write isn't seen until the cache is invalidated
+	if _, err := SafeExec(`UPDATE ai_model SET key = 'changed_key' WHERE uuid = $1`, model.UUID); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	var second []AIModel
+	if err := CachedSelect(ctx, &second, time.Minute, query, "cached_type"); err != nil {
+		t.Fatalf("CachedSelect failed: %v", err)
+	}
+	if len(second) != 1 || second[0].Key != "cached_key" || *second[0].Name != "Cached Model" {
+		t.Errorf("Expected an untouched cached copy, got %+v", second)
+	}
+
+	after := globalQueryCache.Stats()
+	if after["result_hits"]-stats["result_hits"] != 1 || after["result_misses"]-stats["result_misses"] != 1 || after["results"] != 1 {
+		t.Errorf("Unexpected cache stats: %v", after)
+	}
+
+	// Other args are a different entry; other tables' invalidation leaves it alone
+	var none []AIModel
+	if err := CachedSelect(ctx, &none, time.Minute, query, "other_type"); err != nil || len(none) != 0 {
+		t.Errorf("Expected no rows for other args, got %+v (%v)", none, err)
+	}
+	InvalidateCache("realm")
+	if err := CachedSelect(ctx, &second, time.Minute, query, "cached_type"); err != nil || second[0].Key != "cached_key" {
+		t.Errorf("Expected the cached row after invalidating another table, got %+v (%v)", second, err)
+	}
+
+	InvalidateCache("ai_")
+	if err := CachedSelect(ctx, &second, time.Minute, query, "cached_type"); err != nil || second[0].Key != "changed_key" {
+		t.Errorf("Expected fresh rows after invalidation, got %+v (%v)", second, err)
+	}
+
+	// Expired entries are reloaded
+	if _, err := SafeExec(`UPDATE ai_model SET key = 'expired_key' WHERE uuid = $1`, model.UUID); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	var short []AIModel
+	if err := CachedSelect(ctx, &short, time.Millisecond, aiModelBaseQuery); err != nil {
+		t.Fatalf("CachedSelect failed: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if err := CachedSelect(ctx, &short, time.Millisecond, aiModelBaseQuery); err != nil || len(short) != 1 || short[0].Key != "expired_key" {
+		t.Errorf("Expected reloaded rows after expiry, got %+v (%v)", short, err)
+	}
+
+	var notSlice AIModel
+	if err := CachedSelect(ctx, &notSlice, time.Minute, query, "cached_type"); err == nil {
+		t.Error("Expected error for a non-slice dest")
+	}
+}
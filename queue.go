@@ -0,0 +1,71 @@
+// queue.go - Blocking job claiming with SKIP LOCKED and LISTEN/NOTIFY
+package fsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DequeueJob claims one row of table matching filters inside tx, blocking until one is available.
+// The row is selected FOR UPDATE SKIP LOCKED, so concurrent consumers never claim the same row;
+// the lock is held until tx ends, so mark the job done (or delete it) before committing.
+// When nothing is claimable it waits for a NOTIFY on notifyChannel and tries again, so producers
+// should NOTIFY after inserting work. The wait ends with ctx (ctx.Err() is returned).
+// tx must be READ COMMITTED (the default) so retries see rows committed while waiting.
+func DequeueJob[T any](ctx context.Context, tx *Tx, table string, filters *Filter, notifyChannel string) (*T, error) {
+	query, args, err := buildDequeueQuery(table, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	channel, err := QuoteIdentifier(notifyChannel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Listen before the first attempt so a NOTIFY sent in between isn't missed
+	conn, err := listenConn(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseListenConn(conn, channel)
+
+	for {
+		var job T
+		err := tx.GetContext(ctx, &job, query, args...)
+		if err == nil {
+			return &job, nil
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("dequeue failed: %w", err)
+		}
+
+		if _, err := conn.Raw().WaitForNotification(ctx); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, fmt.Errorf("wait for notification failed: %w", err)
+		}
+	}
+}
+
+// buildDequeueQuery selects one row of table matching filters with FOR UPDATE SKIP LOCKED
+func buildDequeueQuery(table string, filters *Filter) (string, []interface{}, error) {
+	modelInfo, ok := getModelInfo(table)
+	if !ok {
+		return "", nil, fmt.Errorf("table name not initialized: %s", table)
+	}
+
+	selectFields, _ := GetSelectFields(table, "")
+	baseQuery := fmt.Sprintf(`SELECT %s FROM %s`, strings.Join(selectFields, ", "), modelInfo.quotedTableName)
+
+	query, args, err := FilterQuery(baseQuery, table, filters, nil, table, 1, 1)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return query + " FOR UPDATE SKIP LOCKED", args, nil
+}
@@ -0,0 +1,115 @@
+// queue_test.go
+package fsql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestDequeueJobWaitsForNotify tests a consumer blocking until a producer inserts and NOTIFYs
+func TestDequeueJobWaitsForNotify(t *testing.T) {
+	cleanDatabase(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filters := &Filter{"Type": "queued_job"}
+
+	type result struct {
+		job *AIModel
+		err error
+	}
+	done := make(chan result, 1)
+
+	go func() {
+		tx, err := BeginTx(ctx)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer tx.Rollback()
+
+		job, err := DequeueJob[AIModel](ctx, tx, "ai_model", filters, "fsql_test_jobs")
+		if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE ai_model SET type = 'done_job' WHERE uuid = $1`, job.UUID)
+		}
+		if err == nil {
+			err = tx.Commit(ctx)
+		}
+		done <- result{job: job, err: err}
+	}()
+
+	// Nothing is queued yet: the consumer must block
+	select {
+	case r := <-done:
+		t.Fatalf("Expected consumer to block, got %+v", r)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	// Producer: enqueue then notify
+	job := AIModel{Key: "job_1", Type: "queued_job", Provider: "queue"}
+	if err := job.Insert(); err != nil {
+		t.Fatalf("Failed to enqueue job: %v", err)
+	}
+	if _, err := SafeExec("NOTIFY fsql_test_jobs"); err != nil {
+		t.Fatalf("Failed to notify: %v", err)
+	}
+
+	var r result
+	select {
+	case r = <-done:
+	case <-ctx.Done():
+		t.Fatal("Consumer did not wake up after NOTIFY")
+	}
+	if r.err != nil {
+		t.Fatalf("DequeueJob failed: %v", r.err)
+	}
+	if r.job == nil || r.job.UUID != job.UUID {
+		t.Fatalf("Expected job %s, got %+v", job.UUID, r.job)
+	}
+
+	var jobType string
+	if err := SafeGet(&jobType, "SELECT type FROM ai_model WHERE uuid = $1", job.UUID); err != nil {
+		t.Fatalf("Failed to read job: %v", err)
+	}
+	if jobType != "done_job" {
+		t.Errorf("Expected job to be marked done, got %q", jobType)
+	}
+}
+
+// TestDequeueJobSkipsLocked tests that a row claimed by one consumer isn't handed to another
+func TestDequeueJobSkipsLocked(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	job := AIModel{Key: "job_locked", Type: "queued_job", Provider: "queue"}
+	if err := job.Insert(); err != nil {
+		t.Fatalf("Failed to enqueue job: %v", err)
+	}
+	filters := &Filter{"Type": "queued_job"}
+
+	first, err := BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	defer first.Rollback()
+
+	claimed, err := DequeueJob[AIModel](ctx, first, "ai_model", filters, "fsql_test_jobs")
+	if err != nil || claimed.UUID != job.UUID {
+		t.Fatalf("First consumer expected job %s, got %+v (%v)", job.UUID, claimed, err)
+	}
+
+	second, err := BeginTx(ctx)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	defer second.Rollback()
+
+	waitCtx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+	if _, err := DequeueJob[AIModel](waitCtx, second, "ai_model", filters, "fsql_test_jobs"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected second consumer to wait until its deadline, got %v", err)
+	}
+}
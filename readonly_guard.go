@@ -0,0 +1,73 @@
+// readonly_guard.go - Client-side write rejection for read-only transactions
+package fsql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// readOnlyGuardTx wraps a read-only pgx.Tx and fails writes before they reach the server.
+// Exec, Query, QueryRow and CopyFrom are checked; batches are left to the server.
+type readOnlyGuardTx struct {
+	pgx.Tx
+}
+
+func (g *readOnlyGuardTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	if err := checkReadOnlyStatement(sql); err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	return g.Tx.Exec(ctx, sql, args...)
+}
+
+func (g *readOnlyGuardTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	if err := checkReadOnlyStatement(sql); err != nil {
+		return nil, err
+	}
+	return g.Tx.Query(ctx, sql, args...)
+}
+
+func (g *readOnlyGuardTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	if err := checkReadOnlyStatement(sql); err != nil {
+		return errRow{err: err}
+	}
+	return g.Tx.QueryRow(ctx, sql, args...)
+}
+
+func (g *readOnlyGuardTx) CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error) {
+	return 0, fmt.Errorf("%w: COPY into %s", ErrWriteInReadOnlyTx, tableName.Sanitize())
+}
+
+// checkReadOnlyStatement allows read-only queries plus the session, cursor and savepoint
+// statements Postgres accepts inside a read-only transaction
+func checkReadOnlyStatement(query string) error {
+	if IsReadOnlyQuery(query) {
+		return nil
+	}
+
+	words := sqlWords(query)
+	if len(words) > 0 {
+		switch words[0] {
+		case "SET", "RESET", "FETCH", "MOVE", "CLOSE", "SAVEPOINT", "RELEASE", "ROLLBACK":
+			return nil
+		case "DECLARE":
+			// DECLARE name CURSOR FOR <query>: classify the cursor's query
+			for i, w := range words {
+				if w == "FOR" && i+1 < len(words) {
+					if readOnlyWords(words[i+1:]) {
+						return nil
+					}
+					break
+				}
+			}
+		}
+	}
+
+	verb := "statement"
+	if len(words) > 0 {
+		verb = words[0]
+	}
+	return fmt.Errorf("%w: %s", ErrWriteInReadOnlyTx, verb)
+}
@@ -0,0 +1,213 @@
+// relations.go - Batch loading of linked entities
+package fsql
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// relation maps a linked struct field to the rows of another table
+type relation struct {
+	fieldIndex   int    // Index of the linked field in the parent struct
+	fkIndex      int    // Index of the foreign key field in the parent struct
+	relatedTable string // Registered table holding the related rows
+	relatedKey   string // Column of relatedTable matched against the foreign key
+	keyIndex     int    // Index of the relatedKey field in the related struct
+	relatedType  reflect.Type
+}
+
+// Relation registry keyed by parent struct type, then linked field name
+var (
+	relationsMutex sync.RWMutex
+	relations      = make(map[reflect.Type]map[string]*relation)
+)
+
+// RegisterRelation declares how a linked field of parent is loaded by Preload:
+// rows of relatedTable whose relatedKey column equals the parent's foreignKey column.
+//
+//	RegisterRelation(Website{}, "Realm", "realm_uuid", "realm", "uuid")
+func RegisterRelation(parent interface{}, field, foreignKey, relatedTable, relatedKey string) error {
+	parentType := getModelType(parent)
+
+	sf, ok := parentType.FieldByName(field)
+	if !ok || len(sf.Index) != 1 {
+		return fmt.Errorf("unknown field %s on %s", field, parentType)
+	}
+	relatedType := sf.Type
+	if relatedType.Kind() == reflect.Ptr {
+		relatedType = relatedType.Elem()
+	}
+	if relatedType.Kind() != reflect.Struct {
+		return fmt.Errorf("relation field %s must be a struct or struct pointer, got %s", field, sf.Type)
+	}
+
+	fkIndex := fieldIndexByColumn(parentType, foreignKey)
+	if fkIndex < 0 {
+		return fmt.Errorf("unknown foreign key column %s on %s", foreignKey, parentType)
+	}
+	if _, ok := getModelInfo(relatedTable); !ok {
+		return fmt.Errorf("table name not initialized: %s", relatedTable)
+	}
+	keyIndex := fieldIndexByColumn(relatedType, relatedKey)
+	if keyIndex < 0 {
+		return fmt.Errorf("unknown key column %s on %s", relatedKey, relatedType)
+	}
+
+	relationsMutex.Lock()
+	defer relationsMutex.Unlock()
+	if relations[parentType] == nil {
+		relations[parentType] = make(map[string]*relation)
+	}
+	relations[parentType][field] = &relation{
+		fieldIndex:   sf.Index[0],
+		fkIndex:      fkIndex,
+		relatedTable: relatedTable,
+		relatedKey:   relatedKey,
+		keyIndex:     keyIndex,
+		relatedType:  relatedType,
+	}
+	return nil
+}
+
+// fieldIndexByColumn returns the index of the top-level field tagged db:"column", or -1
+func fieldIndexByColumn(t reflect.Type, column string) int {
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("db") == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// LoadRelation selects every row of relatedTable whose keyColumn is in keys into dest
+// (a pointer to a slice). It is the single query behind Preload.
+func LoadRelation(ctx context.Context, dest interface{}, relatedTable, keyColumn string, keys []interface{}) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	selectFields, _ := GetSelectFields(relatedTable, "")
+	quotedKey, err := QuoteIdentifier(keyColumn)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM "%s" WHERE "%s".%s IN (%s)`,
+		strings.Join(selectFields, ", "), relatedTable, relatedTable, quotedKey, PlaceholdersString(1, len(keys)))
+
+	return SelectMany(ctx, dest, query, keys...)
+}
+
+// Preload fills the linked field named relation on every parent with one batched query.
+// parents is a slice (or pointer to a slice) of structs or struct pointers whose type
+// was registered with RegisterRelation. Parents sharing a foreign key share the loaded value;
+// parents with a nil/empty foreign key or no matching row are left untouched.
+func Preload(parents interface{}, relation string) error {
+	return PreloadContext(context.Background(), parents, relation)
+}
+
+// PreloadContext is Preload with a context
+func PreloadContext(ctx context.Context, parents interface{}, relationName string) error {
+	slice := reflect.ValueOf(parents)
+	for slice.Kind() == reflect.Ptr {
+		slice = slice.Elem()
+	}
+	if slice.Kind() != reflect.Slice {
+		return fmt.Errorf("parents must be a slice, got %T", parents)
+	}
+
+	elemType := slice.Type().Elem()
+	elemIsPtr := elemType.Kind() == reflect.Ptr
+	if elemIsPtr {
+		elemType = elemType.Elem()
+	}
+
+	relationsMutex.RLock()
+	rel := relations[elemType][relationName]
+	relationsMutex.RUnlock()
+	if rel == nil {
+		return fmt.Errorf("no relation %s registered for %s", relationName, elemType)
+	}
+
+	// Collect the distinct foreign keys
+	keys := make([]interface{}, 0, slice.Len())
+	seen := make(map[string]struct{}, slice.Len())
+	for i := 0; i < slice.Len(); i++ {
+		parent := slice.Index(i)
+		if elemIsPtr {
+			if parent.IsNil() {
+				continue
+			}
+			parent = parent.Elem()
+		}
+		key, ok := relationKey(parent.Field(rel.fkIndex))
+		if !ok {
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, reflect.Indirect(parent.Field(rel.fkIndex)).Interface())
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	related := reflect.New(reflect.SliceOf(rel.relatedType))
+	if err := LoadRelation(ctx, related.Interface(), rel.relatedTable, rel.relatedKey, keys); err != nil {
+		return fmt.Errorf("preload %s failed: %w", relationName, err)
+	}
+
+	byKey := make(map[string]reflect.Value, related.Elem().Len())
+	for i := 0; i < related.Elem().Len(); i++ {
+		row := related.Elem().Index(i)
+		if key, ok := relationKey(row.Field(rel.keyIndex)); ok {
+			byKey[key] = row.Addr()
+		}
+	}
+
+	for i := 0; i < slice.Len(); i++ {
+		parent := slice.Index(i)
+		if elemIsPtr {
+			if parent.IsNil() {
+				continue
+			}
+			parent = parent.Elem()
+		}
+		key, ok := relationKey(parent.Field(rel.fkIndex))
+		if !ok {
+			continue
+		}
+		row, ok := byKey[key]
+		if !ok {
+			continue
+		}
+
+		field := parent.Field(rel.fieldIndex)
+		if field.Kind() == reflect.Ptr {
+			field.Set(row)
+		} else {
+			field.Set(row.Elem())
+		}
+	}
+
+	return nil
+}
+
+// relationKey normalizes a key field for matching parents to related rows
+func relationKey(v reflect.Value) (string, bool) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", false
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || (v.Kind() == reflect.String && v.Len() == 0) {
+		return "", false
+	}
+	return fmt.Sprint(v.Interface()), true
+}
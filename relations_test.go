@@ -0,0 +1,137 @@
+// relations_test.go
+package fsql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestPreloadRealms tests batch-loading realms into websites
+func TestPreloadRealms(t *testing.T) {
+	cleanDatabase(t)
+
+	if err := RegisterRelation(Website{}, "Realm", "realm_uuid", "realm", "uuid"); err != nil {
+		t.Fatalf("RegisterRelation failed: %v", err)
+	}
+
+	realmOne := Realm{UUID: GenNewUUID(""), Name: "Realm One"}
+	realmTwo := Realm{UUID: GenNewUUID(""), Name: "Realm Two"}
+	insertRealm(t, realmOne)
+	insertRealm(t, realmTwo)
+	for i := 0; i < 3; i++ {
+		insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: fmt.Sprintf("one-%d.com", i), RealmUUID: realmOne.UUID})
+	}
+	insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: "two.com", RealmUUID: realmTwo.UUID})
+
+	// Plain select without the join leaves Realm nil
+	var websites []Website
+	if err := Db.Select(&websites, `SELECT uuid, created_at, updated_at, domain, realm_uuid FROM website ORDER BY domain`); err != nil {
+		t.Fatalf("Failed to select websites: %v", err)
+	}
+	for _, w := range websites {
+		if w.Realm != nil {
+			t.Fatalf("Expected nil Realm before preload for %s", w.Domain)
+		}
+	}
+
+	if err := Preload(&websites, "Realm"); err != nil {
+		t.Fatalf("Preload failed: %v", err)
+	}
+	for _, w := range websites {
+		if w.Realm == nil {
+			t.Errorf("Expected Realm to be loaded for %s", w.Domain)
+			continue
+		}
+		if w.Realm.UUID != w.RealmUUID {
+			t.Errorf("Website %s got realm %s, expected %s", w.Domain, w.Realm.UUID, w.RealmUUID)
+		}
+	}
+	if websites[0].Realm.Name != "Realm One" || websites[3].Realm.Name != "Realm Two" {
+		t.Errorf("Unexpected realm names: %s, %s", websites[0].Realm.Name, websites[3].Realm.Name)
+	}
+
+	// Slices of pointers work too
+	pointers := []*Website{{Domain: "a", RealmUUID: realmTwo.UUID}, nil, {Domain: "b"}}
+	if err := Preload(pointers, "Realm"); err != nil {
+		t.Fatalf("Preload of pointers failed: %v", err)
+	}
+	if pointers[0].Realm == nil || pointers[0].Realm.Name != "Realm Two" {
+		t.Errorf("Expected Realm Two, got %+v", pointers[0].Realm)
+	}
+	if pointers[2].Realm != nil {
+		t.Errorf("Expected nil Realm for empty foreign key, got %+v", pointers[2].Realm)
+	}
+
+	if err := Preload(&websites, "Missing"); err == nil {
+		t.Error("Expected error for unregistered relation")
+	}
+	if err := RegisterRelation(Website{}, "Realm", "missing_column", "realm", "uuid"); err == nil {
+		t.Error("Expected error for unknown foreign key column")
+	}
+}
+
+// realmWithWebsites loads a realm's websites through a json_agg column
+type realmWithWebsites struct {
+	UUID     string     `db:"uuid" dbMode:"i"`
+	Name     string     `db:"name" dbMode:"i,u"`
+	Websites []Website  `db:"websites" dbMode:"agg"`
+	Pointers []*Website `db:"website_pointers" dbMode:"agg"`
+}
+
+// TestJSONAgg tests loading one-to-many children in the parent query
+func TestJSONAgg(t *testing.T) {
+	cleanDatabase(t)
+
+	realmOne := Realm{UUID: GenNewUUID(""), Name: "Realm One"}
+	realmTwo := Realm{UUID: GenNewUUID(""), Name: "Realm Two"}
+	insertRealm(t, realmOne)
+	insertRealm(t, realmTwo)
+	for i := 0; i < 3; i++ {
+		insertWebsite(t, Website{UUID: GenNewUUID(""), Domain: fmt.Sprintf("one-%d.com", i), RealmUUID: realmOne.UUID})
+	}
+
+	children := SelectBase("website", "").Where(`"website".realm_uuid = "realm".uuid`).Build() + `ORDER BY "website".domain`
+	query := SelectBase("realm", "").
+		JSONAgg("websites", children).
+		JSONAgg("website_pointers", children).
+		Build() + `ORDER BY "realm".name`
+	if !strings.Contains(query, `(SELECT COALESCE(json_agg("websites"), '[]') FROM (`) {
+		t.Fatalf("Unexpected query: %s", query)
+	}
+
+	var realms []realmWithWebsites
+	if err := Db.Select(&realms, query); err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if len(realms) != 2 {
+		t.Fatalf("Expected 2 realms, got %d", len(realms))
+	}
+	websites := realms[0].Websites
+	if len(websites) != 3 || len(realms[0].Pointers) != 3 {
+		t.Fatalf("Expected 3 websites for Realm One, got %+v", realms[0])
+	}
+	if websites[0].Domain != "one-0.com" || websites[2].Domain != "one-2.com" || websites[0].RealmUUID != realmOne.UUID {
+		t.Errorf("Unexpected websites: %+v", websites)
+	}
+	if websites[0].UUID == "" || websites[0].CreatedAt.IsZero() {
+		t.Errorf("Expected uuid and created_at decoded, got %+v", websites[0])
+	}
+	if realms[0].Pointers[1] == nil || realms[0].Pointers[1].Domain != "one-1.com" {
+		t.Errorf("Unexpected pointer websites: %+v", realms[0].Pointers)
+	}
+	if realms[1].Websites == nil || len(realms[1].Websites) != 0 {
+		t.Errorf("Expected an empty, non-nil slice for Realm Two, got %#v", realms[1].Websites)
+	}
+
+	// The agg field is not a column of the model
+	InitModelTagCache(realmWithWebsites{}, "realm_with_websites")
+	defer ClearModelCache("realm_with_websites")
+	if _, names := GetSelectFields("realm_with_websites", ""); len(names) != 2 {
+		t.Errorf("Expected only uuid and name as columns, got %v", names)
+	}
+	values, err := StructToInsertMap(realmWithWebsites{Name: "x"})
+	if err != nil || len(values) != 2 {
+		t.Errorf("Expected the agg fields left out of inserts, got %v (%v)", values, err)
+	}
+}
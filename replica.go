@@ -0,0 +1,224 @@
+// replica.go - Read replica pools with round-robin routing and health tracking
+package fsql
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Replica states stored in DBConnection.State
+const (
+	ReplicaHealthy int32 = iota
+	ReplicaUnhealthy
+)
+
+var (
+	// ReplicaFailureThreshold is the number of consecutive connection failures that mark a replica unhealthy
+	ReplicaFailureThreshold int32 = 3
+
+	// ReplicaRetryCooldown is how long an unhealthy replica is skipped before it is tried again
+	ReplicaRetryCooldown = 30 * time.Second
+)
+
+// DBConnection represents a database connection with health tracking
+type DBConnection struct {
+	URI          string
+	FailureCount int32 // Consecutive connection failures (atomic)
+	State        int32 // ReplicaHealthy or ReplicaUnhealthy (atomic)
+
+	pool      *pgxpool.Pool
+	downSince int64 // UnixNano of the last failure while unhealthy (atomic)
+}
+
+// Pool returns the replica's connection pool
+func (c *DBConnection) Pool() *pgxpool.Pool {
+	return c.pool
+}
+
+// Healthy reports whether the replica is currently taking reads
+func (c *DBConnection) Healthy() bool {
+	return atomic.LoadInt32(&c.State) == ReplicaHealthy
+}
+
+// available reports whether a read may be sent to the replica. An unhealthy replica
+// becomes available again once per cooldown, so a single read probes whether it recovered.
+func (c *DBConnection) available() bool {
+	if c.Healthy() {
+		return true
+	}
+	since := atomic.LoadInt64(&c.downSince)
+	if time.Since(time.Unix(0, since)) < ReplicaRetryCooldown {
+		return false
+	}
+	return atomic.CompareAndSwapInt64(&c.downSince, since, time.Now().UnixNano())
+}
+
+// recordResult updates the replica's health from a query error and reports
+// whether the error was a connection failure (the read should fall back to the primary)
+func (c *DBConnection) recordResult(ctx context.Context, err error) bool {
+	if err != nil && ctx.Err() != nil {
+		// The caller's own cancellation or deadline says nothing about the replica
+		return false
+	}
+	if !isReplicaFailure(err) {
+		atomic.StoreInt32(&c.FailureCount, 0)
+		atomic.StoreInt32(&c.State, ReplicaHealthy)
+		return false
+	}
+	if atomic.AddInt32(&c.FailureCount, 1) >= ReplicaFailureThreshold {
+		c.markUnhealthy()
+	}
+	return true
+}
+
+// markUnhealthy takes the replica out of rotation for ReplicaRetryCooldown
+func (c *DBConnection) markUnhealthy() {
+	atomic.StoreInt64(&c.downSince, time.Now().UnixNano())
+	atomic.StoreInt32(&c.State, ReplicaUnhealthy)
+}
+
+// isReplicaFailure reports whether err means the replica couldn't serve the query.
+// Errors reported by the server (the replica answered) don't count against it.
+func isReplicaFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pgErr *pgconn.PgError
+	return !errors.As(err, &pgErr)
+}
+
+// Replica registry
+var (
+	replicasMutex sync.RWMutex
+	replicas      []*DBConnection
+	replicaCursor uint32
+
+	// replicaReadsEnabled routes SafeGet/SafeSelect to replicas (0 = off, 1 = on)
+	replicaReadsEnabled int32
+)
+
+// InitDbReplicas opens a pool for each replica URI, replacing any previously opened replicas.
+// Replicas use the primary's pool settings (or config, if given). A replica that can't be
+// reached at startup is kept but starts unhealthy, so it's retried after ReplicaRetryCooldown.
+func InitDbReplicas(databases []string, config ...DBConfig) {
+	cfg := DefaultConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+
+	opened := make([]*DBConnection, 0, len(databases))
+	for _, uri := range databases {
+		pool, err := newPool(uri, cfg.MaxConnections, cfg.MinConnections, cfg.PreparedStatements)
+		if err != nil {
+			log.Printf("fsql: skipping replica: %v", err)
+			continue
+		}
+		replica := &DBConnection{URI: uri, pool: pool}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := pool.Ping(ctx); err != nil {
+			log.Printf("fsql: replica unreachable, retrying after %s: %v", ReplicaRetryCooldown, err)
+			atomic.StoreInt32(&replica.FailureCount, ReplicaFailureThreshold)
+			replica.markUnhealthy()
+		}
+		cancel()
+
+		opened = append(opened, replica)
+	}
+
+	replicasMutex.Lock()
+	previous := replicas
+	replicas = opened
+	replicasMutex.Unlock()
+
+	for _, replica := range previous {
+		replica.pool.Close()
+	}
+}
+
+// GetReplika returns a handle reading from the next healthy replica (round-robin),
+// or the primary Db when no replicas are configured or all of them are unhealthy
+func GetReplika() *dbCompat {
+	if replica := nextReplica(); replica != nil {
+		return &dbCompat{replica: replica}
+	}
+	return Db
+}
+
+// nextReplica returns the next available replica in round-robin order, or nil
+func nextReplica() *DBConnection {
+	replicasMutex.RLock()
+	defer replicasMutex.RUnlock()
+
+	n := len(replicas)
+	if n == 0 {
+		return nil
+	}
+	start := int(atomic.AddUint32(&replicaCursor, 1) % uint32(n))
+	for i := 0; i < n; i++ {
+		if replica := replicas[(start+i)%n]; replica.available() {
+			return replica
+		}
+	}
+	return nil
+}
+
+// Replicas returns the configured replicas and their health
+func Replicas() []*DBConnection {
+	replicasMutex.RLock()
+	defer replicasMutex.RUnlock()
+	return append([]*DBConnection(nil), replicas...)
+}
+
+// CloseReplicas closes all replica connections
+func CloseReplicas() {
+	replicasMutex.Lock()
+	previous := replicas
+	replicas = nil
+	replicasMutex.Unlock()
+
+	for _, replica := range previous {
+		replica.pool.Close()
+	}
+}
+
+// SetReplicaReads routes SafeGet and SafeSelect (and Db.Get/Db.Select) to replicas; queries that
+// write (see IsReadOnlyQuery) still go to the primary. Off by default: replicas lag the primary, so only enable it when reads may be slightly stale.
+func SetReplicaReads(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&replicaReadsEnabled, v)
+}
+
+// ReplicaReadsEnabled reports whether SafeGet/SafeSelect are routed to replicas
+func ReplicaReadsEnabled() bool {
+	return atomic.LoadInt32(&replicaReadsEnabled) == 1
+}
+
+// routedQuery runs a read on a replica when replica reads are enabled, falling back
+// to the primary when no replica is available or the chosen one fails to connect.
+// Anything IsReadOnlyQuery doesn't accept (INSERT ... RETURNING, writing CTEs) goes to the primary.
+func routedQuery(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error) {
+	if ReplicaReadsEnabled() && IsReadOnlyQuery(query) {
+		if replica := nextReplica(); replica != nil {
+			rows, err := poolQuery(ctx, replica.pool, query, args...)
+			if !replica.recordResult(ctx, err) {
+				return rows, err
+			}
+			if rows != nil {
+				rows.Close()
+			}
+		}
+	}
+	return poolQuery(ctx, DB, query, args...)
+}
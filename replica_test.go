@@ -0,0 +1,177 @@
+// replica_test.go
+package fsql
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unreachableReplica is a connection string nothing listens on
+const unreachableReplica = "host=127.0.0.1 port=1 user=nobody dbname=nothing sslmode=disable connect_timeout=1"
+
+var replicaTestConfig = DBConfig{MaxConnections: 4, MinConnections: 0}
+
+// TestReplicaRouting tests round-robin reads across replicas and opt-in routing of SafeGet/SafeSelect
+func TestReplicaRouting(t *testing.T) {
+	cleanDatabase(t)
+
+	model := AIModel{Key: "replica_key", Type: "replica_type", Provider: "replica_provider"}
+	if err := model.Insert(); err != nil {
+		t.Fatalf("Insert error: %v", err)
+	}
+
+	// The test database doubles as two replicas
+	uri := DB.Config().ConnString()
+	InitDbReplicas([]string{uri, uri}, replicaTestConfig)
+	defer CloseReplicas()
+
+	if got := Replicas(); len(got) != 2 || !got[0].Healthy() || !got[1].Healthy() {
+		t.Fatalf("Expected 2 healthy replicas, got %+v", got)
+	}
+
+	first, second := GetReplika(), GetReplika()
+	if first == Db || second == Db {
+		t.Fatal("Expected replica handles, got the primary")
+	}
+	if first.replica == second.replica {
+		t.Error("Expected consecutive GetReplika calls to alternate replicas")
+	}
+
+	var fetched AIModel
+	if err := first.Get(&fetched, aiModelBaseQuery+` WHERE "ai_model".key = $1`, "replica_key"); err != nil {
+		t.Fatalf("Replica Get failed: %v", err)
+	}
+	if fetched.UUID != model.UUID {
+		t.Errorf("Expected %s from replica, got %+v", model.UUID, fetched)
+	}
+
+	// A server-side error doesn't count against the replica
+	if err := first.Get(&fetched, `SELECT * FROM missing_table`); err == nil {
+		t.Error("Expected error for a missing table")
+	}
+	if !first.replica.Healthy() || first.replica.FailureCount != 0 {
+		t.Errorf("Expected replica to stay healthy after a SQL error, got %+v", first.replica)
+	}
+
+	SetReplicaReads(true)
+	defer SetReplicaReads(false)
+
+	var models []AIModel
+	if err := SafeSelect(&models, aiModelBaseQuery+` WHERE "ai_model".type = $1`, "replica_type"); err != nil {
+		t.Fatalf("Routed SafeSelect failed: %v", err)
+	}
+	if len(models) != 1 {
+		t.Errorf("Expected 1 model through replica routing, got %d", len(models))
+	}
+
+	CloseReplicas()
+	if GetReplika() != Db {
+		t.Error("Expected the primary once replicas are closed")
+	}
+}
+
+// TestReplicaRoutingWrites tests that writes through SafeGet stay on the primary with replica reads on
+func TestReplicaRoutingWrites(t *testing.T) {
+	cleanDatabase(t)
+
+	// A read-only session stands in for a real replica: any write routed to it fails
+	uri := DB.Config().ConnString()
+	if strings.Contains(uri, "://") {
+		if strings.Contains(uri, "?") {
+			uri += "&default_transaction_read_only=on"
+		} else {
+			uri += "?default_transaction_read_only=on"
+		}
+	} else {
+		uri += " default_transaction_read_only=on"
+	}
+	InitDbReplicas([]string{uri}, replicaTestConfig)
+	defer CloseReplicas()
+
+	SetReplicaReads(true)
+	defer SetReplicaReads(false)
+
+	var key string
+	if err := SafeGet(&key, `INSERT INTO ai_model (uuid, key, type, provider) VALUES ($1, $2, $3, $4) RETURNING key`,
+		GenNewUUID(""), "routed_write", "replica_type", "replica_provider"); err != nil {
+		t.Fatalf("Write through SafeGet failed: %v", err)
+	}
+	if key != "routed_write" {
+		t.Errorf("Expected routed_write, got %q", key)
+	}
+
+	var readOnly string
+	if err := SafeGet(&readOnly, `SELECT current_setting('transaction_read_only')`); err != nil {
+		t.Fatalf("Routed read failed: %v", err)
+	}
+	if readOnly != "on" {
+		t.Errorf("Expected the read to use the replica, got transaction_read_only = %s", readOnly)
+	}
+}
+
+// TestReplicaHealth tests marking replicas unhealthy, skipping them and retrying after the cooldown
+func TestReplicaHealth(t *testing.T) {
+	cleanDatabase(t)
+	defer func(cooldown time.Duration) { ReplicaRetryCooldown = cooldown }(ReplicaRetryCooldown)
+	ctx := context.Background()
+
+	InitDbReplicas([]string{unreachableReplica}, replicaTestConfig)
+	defer CloseReplicas()
+
+	bad := Replicas()[0]
+	if bad.Healthy() {
+		t.Fatal("Expected an unreachable replica to start unhealthy")
+	}
+	if GetReplika() != Db {
+		t.Error("Expected the primary while the only replica is unhealthy")
+	}
+
+	// Routed reads fall back to the primary, including when the cooldown lets a probe through
+	SetReplicaReads(true)
+	defer SetReplicaReads(false)
+	ReplicaRetryCooldown = 0
+
+	var count int
+	if err := SafeGet(&count, `SELECT COUNT(*) FROM ai_model`); err != nil {
+		t.Fatalf("Expected SafeGet to fall back to the primary, got %v", err)
+	}
+	if bad.Healthy() {
+		t.Error("Expected the failed probe to keep the replica unhealthy")
+	}
+
+	// Consecutive connection failures mark a healthy replica down; a success restores it
+	bad.recordResult(ctx, nil)
+	if !bad.Healthy() || bad.FailureCount != 0 {
+		t.Fatalf("Expected a success to restore the replica, got %+v", bad)
+	}
+	connErr := errors.New("connection refused")
+	for i := int32(1); i < ReplicaFailureThreshold; i++ {
+		if !bad.recordResult(ctx, connErr) {
+			t.Error("Expected a connection error to count as a replica failure")
+		}
+	}
+	if !bad.Healthy() {
+		t.Error("Expected replica to stay healthy below the failure threshold")
+	}
+	bad.recordResult(ctx, connErr)
+	if bad.Healthy() {
+		t.Error("Expected replica to be unhealthy at the failure threshold")
+	}
+
+	// The caller's own cancellation isn't the replica's fault
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if bad.recordResult(cancelled, context.Canceled) || bad.Healthy() {
+		t.Error("Expected a cancelled context to leave the replica's health unchanged")
+	}
+
+	// Cooldown: skipped until it elapses
+	bad.markUnhealthy()
+	ReplicaRetryCooldown = time.Hour
+	if bad.available() {
+		t.Error("Expected an unhealthy replica to be skipped during its cooldown")
+	}
+}
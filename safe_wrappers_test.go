@@ -2,9 +2,17 @@
 package fsql
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog"
 )
 
 // TestSafeExec tests the SafeExec wrapper function
@@ -32,6 +40,51 @@ func TestSafeExec(t *testing.T) {
 	}
 }
 
+// TestSafeExecResult tests statement classification of SafeExecResult
+func TestSafeExecResult(t *testing.T) {
+	cleanDatabase(t)
+
+	uuid1 := GenNewUUID("")
+	uuid2 := GenNewUUID("")
+	res, err := SafeExecResult(
+		`INSERT INTO ai_model (uuid, key, type, provider) VALUES ($1, 'k1', 't', 'p'), ($2, 'k2', 't', 'p')`,
+		uuid1, uuid2,
+	)
+	if err != nil {
+		t.Fatalf("SafeExecResult insert failed: %v", err)
+	}
+	if !res.IsInsert() || res.IsUpdate() || res.IsDelete() || res.RowsAffected() != 2 {
+		t.Errorf("Expected INSERT of 2 rows, got %q", res.String())
+	}
+	if res.InsertOID() != 0 {
+		t.Errorf("Expected insert OID 0, got %d", res.InsertOID())
+	}
+
+	res, err = SafeExecResult(`UPDATE ai_model SET provider = 'p2' WHERE type = 't'`)
+	if err != nil {
+		t.Fatalf("SafeExecResult update failed: %v", err)
+	}
+	if !res.IsUpdate() || res.IsInsert() || res.RowsAffected() != 2 {
+		t.Errorf("Expected UPDATE of 2 rows, got %q", res.String())
+	}
+
+	res, err = SafeExecResult(`DELETE FROM ai_model WHERE uuid = $1`, uuid1)
+	if err != nil {
+		t.Fatalf("SafeExecResult delete failed: %v", err)
+	}
+	if !res.IsDelete() || res.IsUpdate() || res.RowsAffected() != 1 {
+		t.Errorf("Expected DELETE of 1 row, got %q", res.String())
+	}
+
+	res, err = SafeExecResult(`SELECT * FROM ai_model`)
+	if err != nil {
+		t.Fatalf("SafeExecResult select failed: %v", err)
+	}
+	if !res.IsSelect() || res.IsInsert() || res.IsUpdate() || res.IsDelete() {
+		t.Errorf("Expected SELECT classification, got %q", res.String())
+	}
+}
+
 // TestSafeQuery tests the SafeQuery wrapper function
 func TestSafeQuery(t *testing.T) {
 	cleanDatabase(t)
@@ -128,6 +181,50 @@ func TestSafeSelect(t *testing.T) {
 	}
 }
 
+// TestSafeSelectReset tests that SafeSelectReset replaces a pre-populated slice
+func TestSafeSelectReset(t *testing.T) {
+	cleanDatabase(t)
+
+	for i := 1; i <= 2; i++ {
+		_, err := SafeExec(
+			`INSERT INTO ai_model (uuid, key, name, type, provider) VALUES ($1, $2, $3, $4, $5)`,
+			GenNewUUID(""), fmt.Sprintf("key_%d", i), fmt.Sprintf("Model %d", i), "test_type", "test_provider",
+		)
+		if err != nil {
+			t.Fatalf("Failed to setup test data: %v", err)
+		}
+	}
+
+	stale := []AIModel{{Key: "stale_1"}, {Key: "stale_2"}, {Key: "stale_3"}}
+	query := "SELECT uuid, key, name, type, provider FROM ai_model ORDER BY key"
+
+	// SafeSelect appends
+	appended := append([]AIModel(nil), stale...)
+	if err := SafeSelect(&appended, query); err != nil {
+		t.Fatalf("SafeSelect failed: %v", err)
+	}
+	if len(appended) != 5 {
+		t.Errorf("Expected SafeSelect to append to 5 models, got %d", len(appended))
+	}
+
+	// SafeSelectReset replaces
+	reset := append([]AIModel(nil), stale...)
+	if err := SafeSelectReset(&reset, query); err != nil {
+		t.Fatalf("SafeSelectReset failed: %v", err)
+	}
+	if len(reset) != 2 {
+		t.Fatalf("Expected exactly 2 models, got %d", len(reset))
+	}
+	if reset[0].Key != "key_1" || reset[1].Key != "key_2" {
+		t.Errorf("Expected key_1, key_2, got %s, %s", reset[0].Key, reset[1].Key)
+	}
+
+	var notSlice AIModel
+	if err := SafeSelectReset(&notSlice, query); err == nil {
+		t.Error("Expected error for non-slice destination")
+	}
+}
+
 // TestSafeQueryRow tests the SafeQueryRow wrapper function
 func TestSafeQueryRow(t *testing.T) {
 	cleanDatabase(t)
@@ -236,6 +333,125 @@ func TestSafeNamedQuery(t *testing.T) {
 	}
 }
 
+// TestNamedToPositionalPrefixes tests that overlapping parameter names and literals are handled
+func TestNamedToPositionalPrefixes(t *testing.T) {
+	params := map[string]interface{}{
+		"user":    "alice",
+		"user_id": 42,
+		"name":    "ignored",
+	}
+
+	query, args, err := namedToPositional(
+		`SELECT * FROM t WHERE user_id = :user_id AND owner = :user AND note = ':user' AND "col:name" = :user::text -- :name`,
+		params,
+	)
+	if err != nil {
+		t.Fatalf("namedToPositional failed: %v", err)
+	}
+
+	expected := `SELECT * FROM t WHERE user_id = $1 AND owner = $2 AND note = ':user' AND "col:name" = $2::text -- :name`
+	if query != expected {
+		t.Errorf("Unexpected query:\n got: %s\nwant: %s", query, expected)
+	}
+	if len(args) != 2 || args[0] != 42 || args[1] != "alice" {
+		t.Errorf("Unexpected args: %v", args)
+	}
+
+	// Struct args match db tags the same way
+	type userArgs struct {
+		User   string `db:"user"`
+		UserID int    `db:"user_id"`
+	}
+	query, args, err = namedToPositional(`VALUES (:user, :user_id)`, userArgs{User: "bob", UserID: 7})
+	if err != nil {
+		t.Fatalf("namedToPositional with struct failed: %v", err)
+	}
+	if query != `VALUES ($1, $2)` || len(args) != 2 || args[0] != "bob" || args[1] != 7 {
+		t.Errorf("Unexpected struct binding: %s %v", query, args)
+	}
+
+	// Unknown names are passed through untouched
+	query, args, err = namedToPositional(`SELECT :missing, :user`, params)
+	if err != nil || query != `SELECT :missing, $1` || len(args) != 1 {
+		t.Errorf("Expected :missing to pass through, got %s %v (%v)", query, args, err)
+	}
+
+	// End to end: the :key literal stays untouched and :key_name is not split
+	cleanDatabase(t)
+	_, err = SafeNamedExec(`INSERT INTO ai_model (uuid, key, name, type, provider) VALUES (:key_uuid, :key, :key_name, ':key', :key)`,
+		map[string]interface{}{"key_uuid": GenNewUUID(""), "key": "prefix_key", "key_name": "Prefix Name"})
+	if err != nil {
+		t.Fatalf("SafeNamedExec failed: %v", err)
+	}
+	var model AIModel
+	if err := SafeGet(&model, "SELECT * FROM ai_model WHERE key = $1", "prefix_key"); err != nil {
+		t.Fatalf("Failed to read model: %v", err)
+	}
+	if model.Name == nil || *model.Name != "Prefix Name" || model.Type != ":key" || model.Provider != "prefix_key" {
+		t.Errorf("Unexpected model: %+v", model)
+	}
+}
+
+// TestNamedInSliceExpansion tests expanding slices bound inside IN (...) into one placeholder per element
+func TestNamedInSliceExpansion(t *testing.T) {
+	cleanDatabase(t)
+
+	query, args, err := namedToPositional(
+		`SELECT * FROM ai_model WHERE type IN (:types) AND provider = :provider AND key NOT IN ( :keys ) AND uuid = ANY(:uuids)`,
+		map[string]interface{}{
+			"types":    []string{"chat", "image", "audio"},
+			"provider": "p",
+			"keys":     []int{1, 2},
+			"uuids":    []string{"u1"},
+		},
+	)
+	if err != nil {
+		t.Fatalf("namedToPositional failed: %v", err)
+	}
+	expected := `SELECT * FROM ai_model WHERE type IN ($1, $2, $3) AND provider = $4 AND key NOT IN ( $5, $6 ) AND uuid = ANY($7)`
+	if query != expected {
+		t.Errorf("Unexpected query:\n got: %s\nwant: %s", query, expected)
+	}
+	if len(args) != 7 || args[0] != "chat" || args[2] != "audio" || args[3] != "p" || args[5] != 2 {
+		t.Errorf("Unexpected args: %v", args)
+	}
+	if _, ok := args[6].([]string); !ok {
+		t.Errorf("Expected the ANY() slice to stay a single array arg, got %T", args[6])
+	}
+
+	// Struct fields expand the same way; a repeated name reuses its placeholders
+	type typeArgs struct {
+		Types []string `db:"types"`
+	}
+	query, args, err = namedToPositional(`SELECT 1 WHERE 'a' IN (:types) OR 'b' IN (:types)`, typeArgs{Types: []string{"a", "b"}})
+	if err != nil || query != `SELECT 1 WHERE 'a' IN ($1, $2) OR 'b' IN ($1, $2)` || len(args) != 2 {
+		t.Errorf("Unexpected struct expansion: %s %v (%v)", query, args, err)
+	}
+
+	if _, _, err := namedToPositional(`SELECT 1 WHERE 'a' IN (:types)`, map[string]interface{}{"types": []string{}}); err == nil {
+		t.Error("Expected error for an empty IN slice")
+	}
+
+	// End to end through SafeNamedQuery
+	for i, modelType := range []string{"chat", "image", "audio"} {
+		model := AIModel{Key: fmt.Sprintf("in_named_%d", i), Type: modelType, Provider: "in_provider"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+	rows, err := SafeNamedQuery(`SELECT * FROM ai_model WHERE type IN (:types)`, map[string]interface{}{"types": []string{"chat", "audio"}})
+	if err != nil {
+		t.Fatalf("SafeNamedQuery failed: %v", err)
+	}
+	var models []AIModel
+	if err := StructsScan(rows, &models); err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(models) != 2 {
+		t.Errorf("Expected 2 models, got %d", len(models))
+	}
+}
+
 // TestSafeBeginx tests the SafeBeginx wrapper function
 func TestSafeBeginx(t *testing.T) {
 	cleanDatabase(t)
@@ -297,6 +513,165 @@ func TestSafeWrappersTimeout(t *testing.T) {
 	}
 }
 
+// TestSafeQueryTimeoutIteration tests that SafeQueryTimeout's deadline covers iteration
+func TestSafeQueryTimeoutIteration(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping timeout test in short mode")
+	}
+
+	// The deadline is still running while rows are consumed
+	rows, err := SafeQueryTimeout(200*time.Millisecond, "SELECT g, pg_sleep(0.1) FROM generate_series(1, 10) g")
+	if err == nil {
+		for rows.Next() {
+		}
+		err = rows.Err()
+		rows.Close()
+	}
+	if err == nil {
+		t.Error("Expected timeout error while iterating, got nil")
+	}
+
+	// Fully consumed rows are unaffected by the context being released
+	rows, err = SafeQueryTimeout(2*time.Second, "SELECT g FROM generate_series(1, 3) g")
+	if err != nil {
+		t.Fatalf("SafeQueryTimeout failed: %v", err)
+	}
+	var sum int
+	for rows.Next() {
+		var g int
+		if err := rows.Scan(&g); err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		sum += g
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || sum != 6 {
+		t.Errorf("Expected sum 6, got %d (%v)", sum, err)
+	}
+
+	// Closing early releases the connection back to the pool
+	before := DB.Stat().AcquiredConns()
+	rows, err = SafeQueryTimeout(2*time.Second, "SELECT g FROM generate_series(1, 100) g")
+	if err != nil {
+		t.Fatalf("SafeQueryTimeout failed: %v", err)
+	}
+	rows.Next()
+	rows.Close()
+	if after := DB.Stat().AcquiredConns(); after != before {
+		t.Errorf("Expected %d acquired connections after Close, got %d", before, after)
+	}
+}
+
+// TestSafeCtxWrappers tests that the Ctx wrappers follow the caller's context
+func TestSafeCtxWrappers(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	if _, err := SafeExecCtx(ctx, `INSERT INTO ai_model (uuid, key, type, provider) VALUES ($1, 'ctx_key', 'ctx_type', 'ctx_provider')`, GenNewUUID("")); err != nil {
+		t.Fatalf("SafeExecCtx failed: %v", err)
+	}
+	var model AIModel
+	if err := SafeGetCtx(ctx, &model, aiModelBaseQuery+` WHERE "ai_model".key = $1`, "ctx_key"); err != nil || model.Type != "ctx_type" {
+		t.Fatalf("SafeGetCtx failed: %+v (%v)", model, err)
+	}
+	var models []AIModel
+	if err := SafeSelectCtx(ctx, &models, aiModelBaseQuery); err != nil || len(models) != 1 {
+		t.Fatalf("SafeSelectCtx failed: %d rows (%v)", len(models), err)
+	}
+	rows, err := SafeQueryCtx(ctx, "SELECT key FROM ai_model")
+	if err != nil {
+		t.Fatalf("SafeQueryCtx failed: %v", err)
+	}
+	rows.Close()
+
+	// A cancelled request context aborts the query
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := SafeExecCtx(cancelled, "SELECT 1"); err == nil {
+		t.Error("Expected error for a cancelled context")
+	}
+	if err := SafeGetCtx(cancelled, &model, aiModelBaseQuery); err == nil {
+		t.Error("Expected SafeGetCtx error for a cancelled context")
+	}
+
+	if testing.Short() {
+		return
+	}
+	// The caller's deadline wins over DefaultDBTimeout
+	short, cancelShort := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancelShort()
+	if _, err := SafeExecCtx(short, "SELECT pg_sleep(1)"); err == nil {
+		t.Error("Expected the caller's deadline to abort the query")
+	}
+}
+
+// TestSafeQueryRowTimeout tests that SafeQueryRowTimeout's deadline covers the query
+func TestSafeQueryRowTimeout(t *testing.T) {
+	cleanDatabase(t)
+
+	var n int
+	if err := SafeQueryRowTimeout(time.Second, "SELECT 1").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("SafeQueryRowTimeout failed: %d (%v)", n, err)
+	}
+	var key string
+	if err := SafeQueryRow("SELECT key FROM ai_model").Scan(&key); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+
+	if testing.Short() {
+		return
+	}
+	if err := SafeQueryRowTimeout(100*time.Millisecond, "SELECT 1 FROM pg_sleep(1)").Scan(&n); err == nil {
+		t.Error("Expected SafeQueryRowTimeout to time out")
+	}
+}
+
+// TestSetStatementTimeout tests the server-side statement_timeout on Safe wrappers
+func TestSetStatementTimeout(t *testing.T) {
+	cleanDatabase(t)
+	SetStatementTimeout(200 * time.Millisecond)
+	defer SetStatementTimeout(0)
+
+	if GetStatementTimeout() != 200*time.Millisecond {
+		t.Fatalf("Expected 200ms, got %v", GetStatementTimeout())
+	}
+
+	// Writes still commit under the per-statement transaction
+	if _, err := SafeExec(`INSERT INTO ai_model (uuid, key, type, provider) VALUES ($1, 'st_key', 'st_type', 'st_provider')`, GenNewUUID("")); err != nil {
+		t.Fatalf("SafeExec failed: %v", err)
+	}
+	var models []AIModel
+	if err := SafeSelect(&models, aiModelBaseQuery); err != nil || len(models) != 1 {
+		t.Fatalf("SafeSelect failed: %d rows (%v)", len(models), err)
+	}
+	rows, err := SafeQuery("SELECT key FROM ai_model")
+	if err != nil {
+		t.Fatalf("SafeQuery failed: %v", err)
+	}
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	rows.Close()
+	if count != 1 {
+		t.Errorf("Expected 1 row, got %d", count)
+	}
+
+	// The setting is scoped to the statement's transaction, not the pooled connection
+	var setting string
+	if err := DB.QueryRow(context.Background(), "SHOW statement_timeout").Scan(&setting); err != nil || setting == "200ms" {
+		t.Errorf("statement_timeout leaked to the pool: %q (%v)", setting, err)
+	}
+
+	if testing.Short() {
+		return
+	}
+	// Postgres cancels the statement even though the client timeout is much longer
+	if _, err := SafeExec("SELECT pg_sleep(1)"); err == nil {
+		t.Error("Expected statement_timeout to cancel the query")
+	}
+}
+
 // TestSafeExecTimeoutCustom tests custom timeout behavior
 func TestSafeExecTimeoutCustom(t *testing.T) {
 	if testing.Short() {
@@ -392,3 +767,219 @@ func TestAllSafeWrappersSuccess(t *testing.T) {
 		t.Errorf("Expected value 99, got %d", value)
 	}
 }
+
+// TestSelectTGetT tests the generic typed select helpers
+func TestSelectTGetT(t *testing.T) {
+	cleanDatabase(t)
+
+	for i := 1; i <= 3; i++ {
+		model := AIModel{Key: fmt.Sprintf("typed_key_%d", i), Type: "typed_type", Provider: "typed_provider"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	models, err := SelectT[AIModel](aiModelBaseQuery+` WHERE "ai_model".type = $1 ORDER BY "ai_model".key`, "typed_type")
+	if err != nil {
+		t.Fatalf("SelectT failed: %v", err)
+	}
+	if len(models) != 3 || models[0].Key != "typed_key_1" || models[2].Key != "typed_key_3" {
+		t.Errorf("Unexpected models: %+v", models)
+	}
+
+	empty, err := SelectT[AIModel](aiModelBaseQuery+` WHERE "ai_model".type = $1`, "missing_type")
+	if err != nil {
+		t.Fatalf("SelectT with no rows failed: %v", err)
+	}
+	if empty == nil || len(empty) != 0 {
+		t.Errorf("Expected an empty non-nil slice, got %#v", empty)
+	}
+
+	model, err := GetT[AIModel](aiModelBaseQuery+` WHERE "ai_model".key = $1`, "typed_key_2")
+	if err != nil {
+		t.Fatalf("GetT failed: %v", err)
+	}
+	if model.Key != "typed_key_2" || model.Provider != "typed_provider" {
+		t.Errorf("Unexpected model: %+v", model)
+	}
+
+	count, err := GetT[int](`SELECT COUNT(*) FROM ai_model`)
+	if err != nil || count != 3 {
+		t.Errorf("Expected scalar count 3, got %d (%v)", count, err)
+	}
+
+	if _, err := GetT[AIModel](aiModelBaseQuery+` WHERE "ai_model".key = $1`, "missing_key"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+}
+
+// TestSingleRowErrNoRows tests that every single-row entry point reports sql.ErrNoRows on an empty result
+func TestSingleRowErrNoRows(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	emptyQuery := aiModelBaseQuery + ` WHERE "ai_model".key = $1`
+	scalarQuery := `SELECT key FROM ai_model WHERE key = $1`
+	scannerQuery := `SELECT name FROM ai_model WHERE key = $1`
+
+	queryRows := func(query string) pgx.Rows {
+		rows, err := DB.Query(ctx, query, "missing_key")
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		return rows
+	}
+
+	var model AIModel
+	var key string
+	var name sql.NullString
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"SelectOne", func() error { return SelectOne(ctx, &model, emptyQuery, "missing_key") }},
+		{"Get", func() error { return Get(&model, emptyQuery, "missing_key") }},
+		{"SafeGet", func() error { return SafeGet(&model, emptyQuery, "missing_key") }},
+		{"Db.Get", func() error { return Db.Get(&model, emptyQuery, "missing_key") }},
+		{"StructScan", func() error {
+			rows := queryRows(emptyQuery)
+			defer rows.Close()
+			return StructScan(rows, &model)
+		}},
+		{"ScanSingle", func() error {
+			rows := queryRows(emptyQuery)
+			defer rows.Close()
+			return ScanSingle(rows, &model)
+		}},
+		{"StructScan primitive", func() error {
+			rows := queryRows(scalarQuery)
+			defer rows.Close()
+			return StructScan(rows, &key)
+		}},
+		{"StructScan sql.Scanner", func() error {
+			rows := queryRows(scannerQuery)
+			defer rows.Close()
+			return StructScan(rows, &name)
+		}},
+		{"ScanSingle sql.Scanner", func() error {
+			rows := queryRows(scannerQuery)
+			defer rows.Close()
+			return ScanSingle(rows, &name)
+		}},
+		{"Tx.GetContext", func() error {
+			return WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+				return tx.GetContext(ctx, &model, emptyQuery, "missing_key")
+			})
+		}},
+	}
+
+	for _, tt := range tests {
+		if err := tt.run(); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("%s: expected sql.ErrNoRows, got %v", tt.name, err)
+		}
+	}
+
+	// Primitive and sql.Scanner destinations take the first of several rows; structs reject them
+	for i := 1; i <= 2; i++ {
+		m := AIModel{Key: fmt.Sprintf("scanner_key_%d", i), Type: "scanner_type", Provider: "p"}
+		if err := m.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+	if err := SafeGet(&key, `SELECT key FROM ai_model WHERE type = $1 ORDER BY key`, "scanner_type"); err != nil || key != "scanner_key_1" {
+		t.Errorf("Expected the first key, got %q (%v)", key, err)
+	}
+	rows, err := DB.Query(ctx, `SELECT name FROM ai_model WHERE type = $1`, "scanner_type")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	defer rows.Close()
+	if err := ScanSingle(rows, &name); err != nil {
+		t.Errorf("Expected a sql.Scanner destination to take the first row, got %v", err)
+	}
+	if err := Get(&model, aiModelBaseQuery+` WHERE "ai_model".type = $1`, "scanner_type"); err == nil {
+		t.Error("Expected an error scanning two rows into a struct")
+	}
+}
+
+// TestSlowQueryLogging tests warn-level logging of queries over the threshold
+func TestSlowQueryLogging(t *testing.T) {
+	cleanDatabase(t)
+
+	var buf bytes.Buffer
+	testLogger := zerolog.New(&buf)
+	SetLogger(&testLogger)
+	defer SetLogger(nil)
+	defer SetSlowQueryThreshold(0)
+	defer SetSlowQueryLogArgs(false)
+
+	// Off by default
+	if _, err := SafeExec("SELECT pg_sleep(0.02)"); err != nil {
+		t.Fatalf("SafeExec failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("Expected no log with the threshold off, got %s", buf.String())
+	}
+
+	SetSlowQueryThreshold(10 * time.Millisecond)
+
+	var n int
+	if err := SafeGet(&n, "SELECT 1 WHERE $1::text = 'secret'", "secret"); err != nil {
+		t.Fatalf("SafeGet failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected fast query not to be logged, got %s", buf.String())
+	}
+
+	if err := SafeGet(&n, "SELECT 1 FROM pg_sleep(0.02) WHERE $1::text = 'secret'", "secret"); err != nil {
+		t.Fatalf("SafeGet failed: %v", err)
+	}
+	logged := buf.String()
+	if !strings.Contains(logged, `"level":"warn"`) || !strings.Contains(logged, "pg_sleep") || !strings.Contains(logged, `"arg_count":1`) {
+		t.Errorf("Expected a warn log for the slow query, got %s", logged)
+	}
+	if strings.Contains(logged, `"secret"`) {
+		t.Errorf("Expected args to be redacted by default, got %s", logged)
+	}
+
+	buf.Reset()
+	SetSlowQueryLogArgs(true)
+	var rows []int
+	if err := SafeSelect(&rows, "SELECT 1 FROM pg_sleep(0.02) WHERE $1::text = 'secret'", "secret"); err != nil {
+		t.Fatalf("SafeSelect failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"args":["secret"]`) {
+		t.Errorf("Expected args in the log once enabled, got %s", buf.String())
+	}
+}
+
+// TestExplain tests plain and analyzed plans, and that analyzed writes are rolled back
+func TestExplain(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	plan, err := Explain(ctx, "SELECT * FROM ai_model WHERE key = $1", []interface{}{"explain_key"}, false)
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+	if !strings.Contains(plan, "ai_model") || strings.Contains(plan, "actual time") {
+		t.Errorf("Unexpected plan: %s", plan)
+	}
+
+	insert := `INSERT INTO ai_model (uuid, key, type, provider) VALUES ($1, 'explain_key', 'explain_type', 'explain_provider')`
+	plan, err = Explain(ctx, insert, []interface{}{GenNewUUID("")}, true)
+	if err != nil {
+		t.Fatalf("Explain analyze failed: %v", err)
+	}
+	if !strings.Contains(plan, "actual time") || !strings.Contains(plan, "\n") {
+		t.Errorf("Expected a multi-line analyzed plan, got: %s", plan)
+	}
+	if n, err := CountSimple(ctx, "ai_model", ""); err != nil || n != 0 {
+		t.Errorf("Expected the analyzed insert rolled back, got %d rows (%v)", n, err)
+	}
+
+	if _, err := Explain(ctx, "SELECT * FROM missing_table", nil, false); err == nil {
+		t.Error("Expected an error for an invalid query")
+	}
+}
@@ -5,11 +5,16 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jmoiron/sqlx/reflectx"
 )
 
@@ -17,28 +22,193 @@ import (
 // pgx returns string for JSONB in text mode, but sqlx-compatible Scanners expect []byte
 type pgxScannerWrapper struct {
 	target sql.Scanner
+	null   bool // The scanned value was NULL
+	raw    bool // Pass values through unconverted (registered decoders see what pgx returns)
 }
 
 func (p *pgxScannerWrapper) Scan(value interface{}) error {
+	p.null = value == nil
 	if value == nil {
 		return p.target.Scan(nil)
 	}
 	// pgx returns string for JSONB/text types, convert to []byte for compatibility
-	if str, ok := value.(string); ok {
+	if str, ok := value.(string); ok && !p.raw {
 		return p.target.Scan([]byte(str))
 	}
 	return p.target.Scan(value)
 }
 
+// TypeDecoder converts a value as pgx returns it (string for enums and unknown types,
+// int64, float64, time.Time, ...) into a value assignable to the registered Go type.
+// NULL never reaches a decoder: the field is set to its zero value.
+type TypeDecoder func(src interface{}) (interface{}, error)
+
+var (
+	typeDecoders     = make(map[reflect.Type]TypeDecoder)
+	typeDecodersLock sync.RWMutex
+	hasTypeDecoders  atomic.Bool
+)
+
+// RegisterTypeDecoder makes struct scanning decode columns into fields of goType with fn,
+// e.g. to map a Postgres enum's labels onto an int-backed Go enum. A decoder for T also
+// serves *T fields. Decoders take precedence over sql.Scanner; a nil fn removes the decoder.
+//
+//	fsql.RegisterTypeDecoder(reflect.TypeOf(StatusActive), func(src interface{}) (interface{}, error) {
+//		return ParseStatus(src.(string))
+//	})
+func RegisterTypeDecoder(goType reflect.Type, fn TypeDecoder) {
+	typeDecodersLock.Lock()
+	defer typeDecodersLock.Unlock()
+	if fn == nil {
+		delete(typeDecoders, goType)
+	} else {
+		typeDecoders[goType] = fn
+	}
+	hasTypeDecoders.Store(len(typeDecoders) > 0)
+}
+
+// typeDecoderFor returns the decoder registered for t (or, for a pointer, its element type)
+func typeDecoderFor(t reflect.Type) (TypeDecoder, bool) {
+	if !hasTypeDecoders.Load() {
+		return nil, false
+	}
+	typeDecodersLock.RLock()
+	defer typeDecodersLock.RUnlock()
+	if fn, ok := typeDecoders[t]; ok {
+		return fn, true
+	}
+	if t.Kind() == reflect.Ptr {
+		fn, ok := typeDecoders[t.Elem()]
+		return fn, ok
+	}
+	return nil, false
+}
+
+// decoderScanner scans a column into field through a registered TypeDecoder
+type decoderScanner struct {
+	decode TypeDecoder
+	field  reflect.Value
+}
+
+func (d *decoderScanner) Scan(src interface{}) error {
+	if src == nil {
+		d.field.Set(reflect.Zero(d.field.Type()))
+		return nil
+	}
+
+	decoded, err := d.decode(src)
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", d.field.Type(), err)
+	}
+
+	target := d.field
+	if target.Kind() == reflect.Ptr && !typeIs(decoded, target.Type()) {
+		// Decoder registered for the element type of a pointer field
+		target.Set(reflect.New(target.Type().Elem()))
+		target = target.Elem()
+	}
+	rv := reflect.ValueOf(decoded)
+	switch {
+	case !rv.IsValid():
+		target.Set(reflect.Zero(target.Type()))
+	case rv.Type().AssignableTo(target.Type()):
+		target.Set(rv)
+	case rv.Type().ConvertibleTo(target.Type()):
+		target.Set(rv.Convert(target.Type()))
+	default:
+		return fmt.Errorf("decoder for %s returned %T", target.Type(), decoded)
+	}
+	return nil
+}
+
+// typeIs reports whether v's dynamic type is t
+func typeIs(v interface{}, t reflect.Type) bool {
+	return v != nil && reflect.TypeOf(v) == t
+}
+
+// defaultTimeLocation is the location scanned time.Time fields are converted to (nil = as pgx returns them)
+var defaultTimeLocation atomic.Pointer[time.Location]
+
+// SetDefaultTimeLocation makes struct scanning convert time.Time and *time.Time fields to loc
+// (e.g. time.Local) instead of leaving timestamptz values in UTC. Only the presentation changes:
+// the instant is the same and Postgres still stores UTC. Only timestamptz columns are converted:
+// timestamp and date columns carry no zone, so their wall-clock value is kept as scanned.
+// nil restores the default.
+func SetDefaultTimeLocation(loc *time.Location) {
+	defaultTimeLocation.Store(loc)
+}
+
+// GetDefaultTimeLocation returns the location set by SetDefaultTimeLocation (nil when unset)
+func GetDefaultTimeLocation() *time.Location {
+	return defaultTimeLocation.Load()
+}
+
+// timestamptzColumns flags the timestamptz columns of rows, the ones SetDefaultTimeLocation
+// converts; nil when no default location is set
+func timestamptzColumns(rows pgx.Rows) []bool {
+	if defaultTimeLocation.Load() == nil {
+		return nil
+	}
+	fields := rows.FieldDescriptions()
+	zoned := make([]bool, len(fields))
+	for i, fd := range fields {
+		zoned[i] = fd.DataTypeOID == pgtype.TimestamptzOID
+	}
+	return zoned
+}
+
+// timeLocationScanner scans a time.Time or *time.Time field of a timestamptz column, converting it to loc
+type timeLocationScanner struct {
+	field    reflect.Value
+	loc      *time.Location
+	nullable bool // NULL leaves a non-pointer field zero (linked struct columns)
+}
+
+func (s *timeLocationScanner) Scan(src interface{}) error {
+	if src == nil {
+		if s.field.Kind() != reflect.Ptr && !s.nullable {
+			return errors.New("cannot scan NULL into time.Time field")
+		}
+		s.field.Set(reflect.Zero(s.field.Type()))
+		return nil
+	}
+	t, ok := src.(time.Time)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into time.Time field", src)
+	}
+	t = t.In(s.loc)
+	if s.field.Kind() == reflect.Ptr {
+		s.field.Set(reflect.ValueOf(&t))
+	} else {
+		s.field.Set(reflect.ValueOf(t))
+	}
+	return nil
+}
+
+// registerArrayTypes lets a connection encode array arguments pgx can't map on its own.
+// Array columns (text[], bigint[], uuid[]) scan natively into []string, []int64 and
+// []uuid.UUID fields, but with the simple protocol []uuid.UUID args need a registered type.
+func registerArrayTypes(m *pgtype.Map) {
+	m.RegisterDefaultPgType([]uuid.UUID{}, "_uuid")
+}
+
 // traversalCache caches traversals per (type, columns) combination
 type traversalCache struct {
 	traversals [][]int
 	hasScanner []bool // true if field implements sql.Scanner
+	jsonAgg    []bool // true if field is tagged dbMode:"agg" (a JSONAgg column)
+}
+
+// traversalKey identifies a destination type scanned from a given column list
+// Keyed by reflect.Type rather than its name so same-named types from different packages don't collide
+type traversalKey struct {
+	typ     reflect.Type
+	columns string
 }
 
 var (
-	// traversalCacheMap maps "typeName:col1,col2,col3" → cached traversals
-	traversalCacheMap  = make(map[string]*traversalCache)
+	// traversalCacheMap maps (type, "col1,col2,col3") → cached traversals
+	traversalCacheMap  = make(map[traversalKey]*traversalCache)
 	traversalCacheLock sync.RWMutex
 )
 
@@ -101,23 +271,26 @@ func scanSlice(rows pgx.Rows, slice reflect.Value, columns []string) error {
 
 	// Get field traversals and scanner flags ONCE for the type
 	tm := mapper.TypeMap(baseType)
-	traversals, hasScanner := getTraversalsAndScanners(tm, baseType, columns)
+	traversals, hasScanner, jsonAgg := getTraversalsAndScanners(tm, baseType, columns)
 
-	// Reusable values slice for scanning
+	// Reusable values slice and linked-column tracking for scanning
 	values := make([]interface{}, len(columns))
+	zoned := timestamptzColumns(rows)
+	var linked linkedScan
 
 	for rows.Next() {
 		vp := reflect.New(baseType)
 		v := vp.Elem()
 
 		// Set up scan destinations using reflectx field traversals
-		if err := setupScanDests(v, columns, traversals, hasScanner, values); err != nil {
+		if err := setupScanDests(v, columns, traversals, hasScanner, jsonAgg, zoned, values, &linked); err != nil {
 			return err
 		}
 
 		if err := rows.Scan(values...); err != nil {
 			return err
 		}
+		linked.apply()
 
 		if isPtr {
 			slice.Set(reflect.Append(slice, vp))
@@ -128,96 +301,116 @@ func scanSlice(rows pgx.Rows, slice reflect.Value, columns []string) error {
 	return rows.Err()
 }
 
-// scanSingle scans a single row into a struct
+// scanSingle scans a single row into a struct, primitive or sql.Scanner.
+// Every single-row path goes through here, so zero rows is always sql.ErrNoRows
+// (checked before the destination type). Primitives and sql.Scanners take the first
+// row; a field-mapped struct still errors when the query returns more than one.
 func scanSingle(rows pgx.Rows, dest reflect.Value, columns []string) error {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+
+	if err := scanCurrentRow(rows, dest, columns); err != nil {
+		return err
+	}
+
+	if dest.Kind() != reflect.Struct {
+		return nil
+	}
+	if _, ok := dest.Addr().Interface().(sql.Scanner); ok {
+		return nil
+	}
+	if rows.Next() {
+		return errors.New("query returned multiple rows for a single destination")
+	}
+	return rows.Err()
+}
+
+// scanCurrentRow scans the row rows is positioned on into dest
+func scanCurrentRow(rows pgx.Rows, dest reflect.Value, columns []string) error {
 	// Handle primitives (non-structs)
 	if dest.Kind() != reflect.Struct {
-		if !rows.Next() {
-			if err := rows.Err(); err != nil {
-				return err
-			}
-			return sql.ErrNoRows
-		}
 		return rows.Scan(dest.Addr().Interface())
 	}
 
 	// Handle sql.Scanner types (like sql.NullInt64, sql.NullString, etc.)
 	// These are structs but should be scanned directly, not via field mapping
 	if scanner, ok := dest.Addr().Interface().(sql.Scanner); ok {
-		if !rows.Next() {
-			if err := rows.Err(); err != nil {
-				return err
-			}
-			return sql.ErrNoRows
-		}
 		// Use pgx's scan which will call the Scanner interface
 		return rows.Scan(scanner)
 	}
 
-	if !rows.Next() {
-		if err := rows.Err(); err != nil {
-			return err
-		}
-		return sql.ErrNoRows
-	}
-
 	tm := mapper.TypeMap(dest.Type())
-	traversals, hasScanner := getTraversalsAndScanners(tm, dest.Type(), columns)
+	traversals, hasScanner, jsonAgg := getTraversalsAndScanners(tm, dest.Type(), columns)
 	values := make([]interface{}, len(columns))
 
-	if err := setupScanDests(dest, columns, traversals, hasScanner, values); err != nil {
+	var linked linkedScan
+	if err := setupScanDests(dest, columns, traversals, hasScanner, jsonAgg, timestamptzColumns(rows), values, &linked); err != nil {
 		return err
 	}
 
 	if err := rows.Scan(values...); err != nil {
 		return err
 	}
-
-	if rows.Next() {
-		return errors.New("query returned multiple rows for a single destination")
-	}
-	return rows.Err()
+	linked.apply()
+	return nil
 }
 
-// getTraversalsAndScanners gets field traversals and scanner flags for columns (cached)
-func getTraversalsAndScanners(tm *reflectx.StructMap, baseType reflect.Type, columns []string) ([][]int, []bool) {
-	// Build cache key: "typeName:col1,col2,col3..."
-	// Using a simple string concatenation for the key
-	keyLen := len(baseType.String()) + 1
+// getTraversalsAndScanners gets field traversals, scanner and json_agg flags for columns (cached)
+func getTraversalsAndScanners(tm *reflectx.StructMap, baseType reflect.Type, columns []string) ([][]int, []bool, []bool) {
+	// Build cache key: (type, "col1,col2,col3...")
+	// Computed columns (e.g. COUNT(...) AS website_count) are part of the column list,
+	// so the same type scanned from a plain and an aggregate query gets separate entries
+	keyLen := 0
 	for _, col := range columns {
 		keyLen += len(col) + 1
 	}
 
 	var keyBuilder strings.Builder
 	keyBuilder.Grow(keyLen)
-	keyBuilder.WriteString(baseType.String())
-	keyBuilder.WriteByte(':')
 	for i, col := range columns {
 		if i > 0 {
 			keyBuilder.WriteByte(',')
 		}
 		keyBuilder.WriteString(col)
 	}
-	cacheKey := keyBuilder.String()
+	cacheKey := traversalKey{typ: baseType, columns: keyBuilder.String()}
 
 	// Check cache with read lock
 	traversalCacheLock.RLock()
 	if cached, ok := traversalCacheMap[cacheKey]; ok {
 		traversalCacheLock.RUnlock()
-		return cached.traversals, cached.hasScanner
+		return cached.traversals, cached.hasScanner, cached.jsonAgg
 	}
 	traversalCacheLock.RUnlock()
 
 	// Not in cache, compute and store
 	traversals := make([][]int, len(columns))
 	hasScanner := make([]bool, len(columns))
+	jsonAgg := make([]bool, len(columns))
 
+	var prefixes map[string]string
 	for i, col := range columns {
 		fi := tm.GetByPath(col)
 		if fi == nil {
-			continue
+			// Aliased column whose alias differs from the field path: consult dbPrefix tags
+			if dot := strings.IndexByte(col, '.'); dot > 0 {
+				if prefixes == nil {
+					prefixes = dbPrefixPaths(baseType)
+				}
+				if path, ok := prefixes[col[:dot]]; ok {
+					fi = tm.GetByPath(path + col[dot:])
+				}
+			}
+			if fi == nil {
+				continue
+			}
 		}
 		traversals[i] = fi.Index
+		jsonAgg[i] = ModelField{Mode: fi.Field.Tag.Get("dbMode")}.HasMode("agg")
 
 		// Check if field type implements sql.Scanner
 		fieldType := fi.Field.Type
@@ -239,17 +432,117 @@ func getTraversalsAndScanners(tm *reflectx.StructMap, baseType reflect.Type, col
 	traversalCacheMap[cacheKey] = &traversalCache{
 		traversals: traversals,
 		hasScanner: hasScanner,
+		jsonAgg:    jsonAgg,
 	}
 	traversalCacheLock.Unlock()
 
-	return traversals, hasScanner
+	return traversals, hasScanner, jsonAgg
+}
+
+// dbPrefixPaths maps the dbPrefix alias of each top-level field to that field's db path,
+// e.g. `Owner *Realm db:"owner" dbPrefix:"r"` maps columns "r.*" onto "owner.*"
+func dbPrefixPaths(t reflect.Type) map[string]string {
+	prefixes := map[string]string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		prefix := field.Tag.Get("dbPrefix")
+		if prefix == "" {
+			continue
+		}
+		path := field.Tag.Get("db")
+		if path == "" {
+			path = strings.ToLower(field.Name)
+		}
+		// A leaf tagged db:"r.name" dbPrefix:"r" already matches its column by path
+		if path == prefix || strings.HasPrefix(path, prefix+".") {
+			continue
+		}
+		prefixes[prefix] = path
+	}
+	return prefixes
 }
 
 // sql.Scanner type for interface check
 var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
 
-// setupScanDests sets up scan destinations using field traversals
-func setupScanDests(v reflect.Value, columns []string, traversals [][]int, hasScanner []bool, values []interface{}) error {
+// linkedColumn is a column of a linked struct (reached through a pointer, e.g. Website.Realm).
+// Non-nullable fields are scanned into a nullable temporary (holder, a **T) and copied after the scan.
+type linkedColumn struct {
+	field   reflect.Value
+	holder  reflect.Value      // Set for non-nullable fields
+	wrapper *pgxScannerWrapper // Set for sql.Scanner fields
+	owner   int                // Innermost linked pointer, index into linkedScan.owners
+}
+
+// linkedOwner is a linked struct pointer and whether any of its columns was non-NULL
+type linkedOwner struct {
+	field   reflect.Value
+	addr    uintptr
+	parent  int // Enclosing linked pointer, or -1
+	nonNull bool
+}
+
+// linkedScan tracks the linked columns of one row, reused across rows
+type linkedScan struct {
+	columns []linkedColumn
+	owners  []linkedOwner
+}
+
+// ownerIndex returns the index of the linked pointer field f, adding it under parent if new
+func (ls *linkedScan) ownerIndex(f reflect.Value, parent int) int {
+	addr := f.Addr().Pointer()
+	for i := range ls.owners {
+		if ls.owners[i].addr == addr {
+			return i
+		}
+	}
+	ls.owners = append(ls.owners, linkedOwner{field: f, addr: addr, parent: parent})
+	return len(ls.owners) - 1
+}
+
+// apply finishes a scanned row: copies temporaries into their fields (zero for NULL) and
+// resets linked pointers whose columns were all NULL (an outer join miss) to nil
+func (ls *linkedScan) apply() {
+	for _, c := range ls.columns {
+		var isNull bool
+		switch {
+		case c.holder.IsValid():
+			scanned := c.holder.Elem()
+			isNull = scanned.IsNil()
+			if isNull {
+				c.field.Set(reflect.Zero(c.field.Type()))
+			} else {
+				c.field.Set(scanned.Elem())
+			}
+		case c.wrapper != nil:
+			isNull = c.wrapper.null
+		default:
+			isNull = c.field.IsNil()
+		}
+		if isNull {
+			continue
+		}
+		for owner := c.owner; owner >= 0 && !ls.owners[owner].nonNull; owner = ls.owners[owner].parent {
+			ls.owners[owner].nonNull = true
+		}
+	}
+	for _, owner := range ls.owners {
+		if !owner.nonNull {
+			owner.field.Set(reflect.Zero(owner.field.Type()))
+		}
+	}
+}
+
+// setupScanDests sets up scan destinations using field traversals.
+// Columns of linked structs are recorded in linked (reset here) so that an outer join miss,
+// which returns NULL for all of them, scans without error into a nil pointer; call
+// linked.apply() after rows.Scan. zoned (from timestamptzColumns) marks the columns whose
+// times are converted to the default time location.
+func setupScanDests(v reflect.Value, columns []string, traversals [][]int, hasScanner []bool, jsonAgg []bool, zoned []bool, values []interface{}, linked *linkedScan) error {
+	linked.columns = linked.columns[:0]
+	linked.owners = linked.owners[:0]
+	loc := defaultTimeLocation.Load()
+
 	for i, traversal := range traversals {
 		if traversal == nil {
 			// Column doesn't map to a field - use placeholder
@@ -260,8 +553,10 @@ func setupScanDests(v reflect.Value, columns []string, traversals [][]int, hasSc
 
 		// Navigate through the traversal, initializing nil pointers along the way
 		f := v
+		owner := -1
 		for _, idx := range traversal {
 			if f.Kind() == reflect.Ptr {
+				owner = linked.ownerIndex(f, owner)
 				if f.IsNil() {
 					f.Set(reflect.New(f.Type().Elem()))
 				}
@@ -270,8 +565,29 @@ func setupScanDests(v reflect.Value, columns []string, traversals [][]int, hasSc
 			f = f.Field(idx)
 		}
 
+		// json_agg columns decode into their slice by the elements' db tags
+		if jsonAgg[i] {
+			wrapper := &pgxScannerWrapper{target: &jsonAggScanner{field: f}}
+			values[i] = wrapper
+			if owner >= 0 {
+				linked.columns = append(linked.columns, linkedColumn{wrapper: wrapper, owner: owner})
+			}
+			continue
+		}
+
+		// Registered decoders come first, scanning the value exactly as pgx returns it
+		if decode, ok := typeDecoderFor(f.Type()); ok {
+			wrapper := &pgxScannerWrapper{target: &decoderScanner{decode: decode, field: f}, raw: true}
+			values[i] = wrapper
+			if owner >= 0 {
+				linked.columns = append(linked.columns, linkedColumn{wrapper: wrapper, owner: owner})
+			}
+			continue
+		}
+
 		// Wrap sql.Scanner types to handle pgx's string→[]byte conversion for JSONB
 		if hasScanner[i] {
+			var wrapper *pgxScannerWrapper
 			// For pointer fields (*JSONSettings), the Scanner interface is on the pointer type
 			// Initialize if nil, then use the pointer value directly as the scanner
 			if f.Kind() == reflect.Ptr {
@@ -279,25 +595,52 @@ func setupScanDests(v reflect.Value, columns []string, traversals [][]int, hasSc
 					f.Set(reflect.New(f.Type().Elem()))
 				}
 				if scanner, ok := f.Interface().(sql.Scanner); ok {
-					values[i] = &pgxScannerWrapper{target: scanner}
-					continue
+					wrapper = &pgxScannerWrapper{target: scanner}
 				}
 			} else {
 				// For non-pointer fields, get address and check for Scanner
 				ptr := f.Addr().Interface()
 				if scanner, ok := ptr.(sql.Scanner); ok {
-					values[i] = &pgxScannerWrapper{target: scanner}
-					continue
+					wrapper = &pgxScannerWrapper{target: scanner}
 				}
 			}
+			if wrapper != nil {
+				values[i] = wrapper
+				if owner >= 0 {
+					linked.columns = append(linked.columns, linkedColumn{wrapper: wrapper, owner: owner})
+				}
+				continue
+			}
 		}
 
-		values[i] = f.Addr().Interface()
+		// timestamptz times are converted to the default location when one is set
+		if loc != nil && zoned != nil && zoned[i] && (f.Type() == timeType || f.Type() == timePtrType) {
+			wrapper := &pgxScannerWrapper{target: &timeLocationScanner{field: f, loc: loc, nullable: owner >= 0}, raw: true}
+			values[i] = wrapper
+			if owner >= 0 {
+				linked.columns = append(linked.columns, linkedColumn{wrapper: wrapper, owner: owner})
+			}
+			continue
+		}
+
+		switch {
+		case owner < 0:
+			values[i] = f.Addr().Interface()
+		case f.Kind() == reflect.Ptr:
+			values[i] = f.Addr().Interface()
+			linked.columns = append(linked.columns, linkedColumn{field: f, owner: owner})
+		default:
+			// NULL from a join miss would fail a non-nullable field: scan through a temporary
+			holder := reflect.New(reflect.PointerTo(f.Type()))
+			values[i] = holder.Interface()
+			linked.columns = append(linked.columns, linkedColumn{field: f, holder: holder, owner: owner})
+		}
 	}
 	return nil
 }
 
-// StructScan scans a single row from pgx.Rows into a struct
+// StructScan scans a single row from pgx.Rows into a struct.
+// It returns sql.ErrNoRows when rows is empty.
 func StructScan(rows pgx.Rows, dest interface{}) error {
 	return ScanRows(rows, dest)
 }
@@ -308,7 +651,7 @@ func StructsScan(rows pgx.Rows, dest interface{}) error {
 	return ScanRows(rows, dest)
 }
 
-// Get scans a single row using a raw SQL query
+// Get scans a single row using a raw SQL query; it returns sql.ErrNoRows when no row matches
 func Get(dest interface{}, query string, args ...interface{}) error {
 	rows, err := DB.Query(context.Background(), query, args...)
 	if err != nil {
@@ -327,39 +670,152 @@ func Select(dest interface{}, query string, args ...interface{}) error {
 	return StructsScan(rows, dest)
 }
 
-// ScanSingle scans a single row into the destination struct
-func ScanSingle(rows pgx.Rows, dest interface{}) error {
-	if !rows.Next() {
-		if err := rows.Err(); err != nil {
+// RowScanner scans the current row of a ForEach iteration
+type RowScanner interface {
+	// Scan fills dest (a pointer to a struct, primitive or sql.Scanner) like StructScan
+	Scan(dest interface{}) error
+}
+
+// rowScanner is the RowScanner handed to ForEach callbacks
+type rowScanner struct {
+	rows    pgx.Rows
+	columns []string
+}
+
+func (r *rowScanner) Scan(dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("dest must be a non-nil pointer")
+	}
+	return scanCurrentRow(r.rows, v.Elem(), r.columns)
+}
+
+// ForEach runs query and calls fn once per row without buffering the result, for processing
+// large result sets. Iteration stops at the first error fn returns, which ForEach returns as is.
+// The rows are closed before ForEach returns, whatever the outcome.
+//
+//	err := fsql.ForEach(ctx, `SELECT * FROM ai_model`, nil, func(row fsql.RowScanner) error {
+//		var m AIModel
+//		if err := row.Scan(&m); err != nil {
+//			return err
+//		}
+//		return process(m)
+//	})
+func ForEach(ctx context.Context, query string, args []interface{}, fn func(scanner RowScanner) error) error {
+	rows, err := DB.Query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	scanner := &rowScanner{rows: rows, columns: getColumns(rows)}
+	for rows.Next() {
+		if err := fn(scanner); err != nil {
 			return err
 		}
-		return sql.ErrNoRows
 	}
+	return rows.Err()
+}
 
-	v := reflect.ValueOf(dest)
-	if v.Kind() != reflect.Ptr {
-		return errors.New("dest must be a pointer")
+// SelectMaps runs query and returns each row as a map keyed by column name, for ad-hoc
+// queries (exports, debugging) where defining a struct is overkill. Values are pgx's decoded
+// Go values, except uuid columns, which come back as their string form, and json/jsonb
+// columns, which keep their raw JSON text as []byte (what pgxScannerWrapper hands to Scanners).
+// If two columns share a name, the later one wins.
+func SelectMaps(ctx context.Context, query string, args ...interface{}) ([]map[string]interface{}, error) {
+	rows, err := DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	direct := reflect.Indirect(v)
 	columns := getColumns(rows)
+	results := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		row, err := scanMap(rows, columns)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
 
-	tm := mapper.TypeMap(direct.Type())
-	traversals, hasScanner := getTraversalsAndScanners(tm, direct.Type(), columns)
-	values := make([]interface{}, len(columns))
+// GetMap is SelectMaps for a single row; it returns sql.ErrNoRows when no row matches
+func GetMap(ctx context.Context, query string, args ...interface{}) (map[string]interface{}, error) {
+	rows, err := DB.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	if err := setupScanDests(direct, columns, traversals, hasScanner, values); err != nil {
-		return err
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, sql.ErrNoRows
+	}
+	row, err := scanMap(rows, getColumns(rows))
+	if err != nil {
+		return nil, err
+	}
+	if rows.Next() {
+		return nil, errors.New("query returned multiple rows for a single destination")
 	}
+	return row, rows.Err()
+}
 
-	if err := rows.Scan(values...); err != nil {
-		return err
+// scanMap converts the current row to a column-keyed map
+func scanMap(rows pgx.Rows, columns []string) (map[string]interface{}, error) {
+	values, err := rows.Values()
+	if err != nil {
+		return nil, err
 	}
+	fds := rows.FieldDescriptions()
+	raw := rows.RawValues()
 
-	if rows.Next() {
-		return errors.New("query returned multiple rows for a single destination")
+	row := make(map[string]interface{}, len(columns))
+	for i, col := range columns {
+		val := values[i]
+		if val != nil {
+			switch fds[i].DataTypeOID {
+			case pgtype.UUIDOID:
+				if u, ok := val.([16]byte); ok {
+					val = uuid.UUID(u).String()
+				}
+			case pgtype.UUIDArrayOID:
+				if elems, ok := val.([]interface{}); ok {
+					for j, elem := range elems {
+						if u, ok := elem.([16]byte); ok {
+							elems[j] = uuid.UUID(u).String()
+						}
+					}
+				}
+			case pgtype.JSONOID, pgtype.JSONBOID:
+				text := raw[i]
+				if fds[i].DataTypeOID == pgtype.JSONBOID && fds[i].Format == pgtype.BinaryFormatCode && len(text) > 0 {
+					text = text[1:] // Binary jsonb starts with a version byte
+				}
+				val = append([]byte(nil), text...)
+			}
+		}
+		row[col] = val
 	}
-	return rows.Err()
+	return row, nil
+}
+
+// ScanSingle scans a single row into the destination struct.
+// It returns sql.ErrNoRows when rows is empty.
+func ScanSingle(rows pgx.Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr {
+		return errors.New("dest must be a pointer")
+	}
+
+	return scanSingle(rows, reflect.Indirect(v), getColumns(rows))
 }
 
 // NullableString is a helper for scanning nullable strings
@@ -154,7 +154,7 @@ func BenchmarkGetTraversals(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, _ = getTraversalsAndScanners(tm, getModelType(AIModel{}), columns)
+		_, _, _ = getTraversalsAndScanners(tm, getModelType(AIModel{}), columns)
 	}
 }
 
@@ -163,14 +163,15 @@ func BenchmarkSetupScanDests(b *testing.B) {
 	// Simulate what happens per row
 	tm := mapper.TypeMap(getModelType(AIModel{}))
 	columns := []string{"uuid", "key", "name", "description", "type", "provider", "settings", "default_negative_prompt"}
-	traversals, hasScanner := getTraversalsAndScanners(tm, getModelType(AIModel{}), columns)
+	traversals, hasScanner, jsonAgg := getTraversalsAndScanners(tm, getModelType(AIModel{}), columns)
 	values := make([]interface{}, len(columns))
+	var linked linkedScan
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		var model AIModel
 		v := getModelValue(&model)
-		_ = setupScanDests(v, columns, traversals, hasScanner, values)
+		_ = setupScanDests(v, columns, traversals, hasScanner, jsonAgg, nil, values, &linked)
 	}
 }
 
@@ -0,0 +1,304 @@
+// scanner_test.go
+package fsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TaggedItem has Postgres array columns
+type TaggedItem struct {
+	UUID    string      `db:"uuid" dbMode:"i"`
+	Tags    []string    `db:"tags" dbMode:"i,u"`
+	Scores  []int64     `db:"scores" dbMode:"i,u"`
+	Related []uuid.UUID `db:"related" dbMode:"i,u"`
+}
+
+// TestSelectMapsGetMap tests scanning ad-hoc rows into column-keyed maps
+func TestSelectMapsGetMap(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	name := "Map Model"
+	model := AIModel{Key: "map_key", Name: &name, Type: "map_type", Provider: "map_provider"}
+	if err := model.Insert(); err != nil {
+		t.Fatalf("Insert error: %v", err)
+	}
+	if _, err := SafeExec(`UPDATE ai_model SET settings = '{"depth": 3}' WHERE uuid = $1`, model.UUID); err != nil {
+		t.Fatalf("Failed to set settings: %v", err)
+	}
+	other := AIModel{Key: "map_key_2", Type: "map_type", Provider: "map_provider"}
+	if err := other.Insert(); err != nil {
+		t.Fatalf("Insert error: %v", err)
+	}
+
+	rows, err := SelectMaps(ctx, `SELECT uuid, key, name, settings, 42 AS answer FROM ai_model WHERE type = $1 ORDER BY key`, "map_type")
+	if err != nil {
+		t.Fatalf("SelectMaps failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+
+	first := rows[0]
+	if first["uuid"] != model.UUID {
+		t.Errorf("Expected uuid as string %q, got %#v", model.UUID, first["uuid"])
+	}
+	if first["key"] != "map_key" || first["name"] != "Map Model" || first["answer"] != int32(42) {
+		t.Errorf("Unexpected row: %#v", first)
+	}
+	if settings, ok := first["settings"].([]byte); !ok || string(settings) != `{"depth": 3}` {
+		t.Errorf("Expected raw JSON bytes for jsonb, got %#v", first["settings"])
+	}
+	if rows[1]["name"] != nil || rows[1]["settings"] != nil {
+		t.Errorf("Expected NULLs as nil, got %#v", rows[1])
+	}
+
+	empty, err := SelectMaps(ctx, `SELECT key FROM ai_model WHERE type = $1`, "missing_type")
+	if err != nil || empty == nil || len(empty) != 0 {
+		t.Errorf("Expected an empty non-nil result, got %#v (%v)", empty, err)
+	}
+
+	row, err := GetMap(ctx, `SELECT key, provider FROM ai_model WHERE uuid = $1`, other.UUID)
+	if err != nil {
+		t.Fatalf("GetMap failed: %v", err)
+	}
+	if len(row) != 2 || row["key"] != "map_key_2" || row["provider"] != "map_provider" {
+		t.Errorf("Unexpected map: %#v", row)
+	}
+
+	if _, err := GetMap(ctx, `SELECT key FROM ai_model WHERE key = $1`, "missing_key"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", err)
+	}
+	if _, err := GetMap(ctx, `SELECT key FROM ai_model WHERE type = $1`, "map_type"); err == nil {
+		t.Error("Expected error for multiple rows")
+	}
+}
+
+// TestForEach tests streaming rows through a callback and stopping early
+func TestForEach(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"each_a", "each_b", "each_c"} {
+		model := AIModel{Key: key, Type: "each_type", Provider: "each_provider"}
+		if err := model.Insert(); err != nil {
+			t.Fatalf("Insert error: %v", err)
+		}
+	}
+
+	var keys []string
+	err := ForEach(ctx, aiModelBaseQuery+` WHERE "ai_model".type = $1 ORDER BY "ai_model".key`, []interface{}{"each_type"}, func(row RowScanner) error {
+		var model AIModel
+		if err := row.Scan(&model); err != nil {
+			return err
+		}
+		keys = append(keys, model.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEach failed: %v", err)
+	}
+	if len(keys) != 3 || keys[0] != "each_a" || keys[2] != "each_c" {
+		t.Errorf("Unexpected keys: %v", keys)
+	}
+
+	// An error from fn stops the iteration and is returned unchanged
+	errStop := errors.New("stop")
+	seen := 0
+	err = ForEach(ctx, `SELECT key FROM ai_model ORDER BY key`, nil, func(row RowScanner) error {
+		var key string
+		if err := row.Scan(&key); err != nil {
+			return err
+		}
+		seen++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || seen != 1 {
+		t.Errorf("Expected to stop after 1 row with errStop, got %d rows (%v)", seen, err)
+	}
+
+	// The connection is back in the pool after an early stop
+	if acquired := DB.Stat().AcquiredConns(); acquired != 0 {
+		t.Errorf("Expected no acquired connections, got %d", acquired)
+	}
+}
+
+// testStatus is an int-backed enum decoded from its text label
+type testStatus int
+
+const (
+	testStatusUnknown testStatus = iota
+	testStatusActive
+	testStatusArchived
+)
+
+// TestRegisterTypeDecoder tests scanning enum labels into an int-backed Go type
+func TestRegisterTypeDecoder(t *testing.T) {
+	RegisterTypeDecoder(reflect.TypeOf(testStatus(0)), func(src interface{}) (interface{}, error) {
+		switch src {
+		case "active":
+			return testStatusActive, nil
+		case "archived":
+			return testStatusArchived, nil
+		}
+		return nil, fmt.Errorf("unknown status %v", src)
+	})
+	defer RegisterTypeDecoder(reflect.TypeOf(testStatus(0)), nil)
+
+	type row struct {
+		Key      string      `db:"key"`
+		Status   testStatus  `db:"status"`
+		Previous *testStatus `db:"previous"`
+	}
+
+	var rows []row
+	err := Db.Select(&rows, `SELECT * FROM (VALUES ('a', 'active', 'archived'), ('b', 'archived', NULL)) AS v(key, status, previous) ORDER BY key`)
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Status != testStatusActive || rows[1].Status != testStatusArchived {
+		t.Fatalf("Unexpected decoded rows: %+v", rows)
+	}
+	if rows[0].Previous == nil || *rows[0].Previous != testStatusArchived || rows[1].Previous != nil {
+		t.Errorf("Unexpected decoded pointers: %v, %v", rows[0].Previous, rows[1].Previous)
+	}
+
+	// Decoder errors surface from the scan
+	var bad row
+	if err := Db.Get(&bad, `SELECT 'c' AS key, 'deleted' AS status, NULL AS previous`); err == nil {
+		t.Error("Expected decode error for an unknown label")
+	}
+}
+
+// TestSetDefaultTimeLocation tests that scanned times are converted to the default location
+func TestSetDefaultTimeLocation(t *testing.T) {
+	cleanDatabase(t)
+
+	loc := time.FixedZone("UTC+5", 5*60*60)
+	SetDefaultTimeLocation(loc)
+	defer SetDefaultTimeLocation(nil)
+	if GetDefaultTimeLocation() != loc {
+		t.Fatal("Expected GetDefaultTimeLocation to return the set location")
+	}
+
+	type row struct {
+		At      time.Time  `db:"at"`
+		Pointer *time.Time `db:"pointer"`
+		Missing *time.Time `db:"missing"`
+	}
+	var r row
+	err := Db.Get(&r, `SELECT '2024-05-01 10:00:00+00'::timestamptz AS at, '2024-05-01 12:00:00+00'::timestamptz AS pointer, NULL::timestamptz AS missing`)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	want := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	if r.At.Location() != loc || !r.At.Equal(want) || r.At.Hour() != 15 {
+		t.Errorf("Expected %v in UTC+5, got %v", want, r.At)
+	}
+	if r.Pointer == nil || r.Pointer.Location() != loc || r.Pointer.Hour() != 17 {
+		t.Errorf("Expected the pointer field in UTC+5, got %v", r.Pointer)
+	}
+	if r.Missing != nil {
+		t.Errorf("Expected NULL to leave the pointer nil, got %v", r.Missing)
+	}
+
+	// date and timestamp columns have no zone, so their wall clock is kept
+	var plain struct {
+		Day   time.Time `db:"day"`
+		Local time.Time `db:"local"`
+	}
+	if err := Db.Get(&plain, `SELECT '2024-03-10'::date AS day, '2024-03-10 08:30:00'::timestamp AS local`); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if y, m, d := plain.Day.Date(); y != 2024 || m != time.March || d != 10 || plain.Day.Hour() != 0 || plain.Day.Location() == loc {
+		t.Errorf("Expected the date 2024-03-10 unconverted, got %v", plain.Day)
+	}
+	if plain.Local.Hour() != 8 || plain.Local.Minute() != 30 || plain.Local.Location() == loc {
+		t.Errorf("Expected the timestamp 08:30 unconverted, got %v", plain.Local)
+	}
+
+	// Linked structs from an outer-join miss still come back nil
+	website := Website{UUID: GenNewUUID(""), Domain: "tz.com", RealmUUID: GenNewUUID("")}
+	if _, err := SafeExec(`INSERT INTO website (uuid, domain, realm_uuid) VALUES ($1, $2, $3)`, website.UUID, website.Domain, website.RealmUUID); err != nil {
+		t.Fatalf("Failed to insert website: %v", err)
+	}
+	var websites []Website
+	if err := Db.Select(&websites, websiteBaseQuery); err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if len(websites) != 1 || websites[0].Realm != nil || websites[0].CreatedAt.Location() != loc {
+		t.Errorf("Unexpected website scan: %+v", websites)
+	}
+
+	SetDefaultTimeLocation(nil)
+	if err := Db.Get(&r, `SELECT '2024-05-01 10:00:00+00'::timestamptz AS at, NULL::timestamptz AS pointer, NULL::timestamptz AS missing`); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if r.At.Location() == loc {
+		t.Errorf("Expected the default location cleared, got %v", r.At)
+	}
+}
+
+// TestArrayColumnScanning tests binding and scanning text[], bigint[] and uuid[] columns
+func TestArrayColumnScanning(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	related := []uuid.UUID{uuid.New(), uuid.New()}
+	item := TaggedItem{UUID: GenNewUUID(""), Tags: []string{"go", "with space", `quo"te`}, Scores: []int64{3, 1 << 40}, Related: related}
+	if _, err := SafeExec(`INSERT INTO tagged_item (uuid, tags, scores, related) VALUES ($1, $2, $3, $4)`,
+		item.UUID, item.Tags, item.Scores, item.Related); err != nil {
+		t.Fatalf("Insert with array args failed: %v", err)
+	}
+	empty := GenNewUUID("")
+	if _, err := SafeExec(`INSERT INTO tagged_item (uuid, tags) VALUES ($1, '{}')`, empty); err != nil {
+		t.Fatalf("Insert with NULL arrays failed: %v", err)
+	}
+
+	var fetched TaggedItem
+	if err := Get(&fetched, `SELECT uuid, tags, scores, related FROM tagged_item WHERE uuid = $1`, item.UUID); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if len(fetched.Tags) != 3 || fetched.Tags[1] != "with space" || fetched.Tags[2] != `quo"te` {
+		t.Errorf("Unexpected tags: %#v", fetched.Tags)
+	}
+	if len(fetched.Scores) != 2 || fetched.Scores[1] != 1<<40 {
+		t.Errorf("Unexpected scores: %#v", fetched.Scores)
+	}
+	if len(fetched.Related) != 2 || fetched.Related[0] != related[0] || fetched.Related[1] != related[1] {
+		t.Errorf("Unexpected related: %#v", fetched.Related)
+	}
+
+	var items []TaggedItem
+	if err := Select(&items, `SELECT uuid, tags, scores, related FROM tagged_item WHERE uuid = $1`, empty); err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if len(items) != 1 || items[0].Tags == nil || len(items[0].Tags) != 0 || items[0].Scores != nil || items[0].Related != nil {
+		t.Errorf("Expected empty tags and nil NULL arrays, got %#v", items)
+	}
+
+	// NULL elements need pointer elements
+	var withNull []*string
+	if err := DB.QueryRow(ctx, `SELECT ARRAY['a', NULL]::text[]`).Scan(&withNull); err != nil {
+		t.Fatalf("Scan into []*string failed: %v", err)
+	}
+	if len(withNull) != 2 || withNull[0] == nil || *withNull[0] != "a" || withNull[1] != nil {
+		t.Errorf("Unexpected array with NULL: %#v", withNull)
+	}
+
+	row, err := GetMap(ctx, `SELECT related FROM tagged_item WHERE uuid = $1`, item.UUID)
+	if err != nil {
+		t.Fatalf("GetMap failed: %v", err)
+	}
+	if elems, ok := row["related"].([]interface{}); !ok || len(elems) != 2 || elems[0] != related[0].String() {
+		t.Errorf("Expected uuid[] elements as strings, got %#v", row["related"])
+	}
+}
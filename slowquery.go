@@ -0,0 +1,98 @@
+// slowquery.go - Warn-level logging of queries exceeding a duration threshold, and EXPLAIN plans
+package fsql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// slowQueryThreshold in nanoseconds (0 = slow-query logging off)
+	slowQueryThreshold int64
+
+	// slowQueryLogArgs includes argument values in slow-query logs (0 = redacted, 1 = logged)
+	slowQueryLogArgs int32
+)
+
+// SetSlowQueryThreshold logs Safe* wrapper queries (SafeExec, SafeGet, SafeSelect, SafeQuery)
+// taking at least d at warn level on the logger set with SetLogger. 0 turns it off (the default).
+func SetSlowQueryThreshold(d time.Duration) {
+	atomic.StoreInt64(&slowQueryThreshold, int64(d))
+}
+
+// GetSlowQueryThreshold returns the current slow-query threshold (0 when off)
+func GetSlowQueryThreshold() time.Duration {
+	return time.Duration(atomic.LoadInt64(&slowQueryThreshold))
+}
+
+// SetSlowQueryLogArgs includes argument values in slow-query logs.
+// Off by default since args often hold personal data or secrets; only the count is logged.
+func SetSlowQueryLogArgs(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&slowQueryLogArgs, v)
+}
+
+// logSlowQuery logs query if it ran for at least the slow-query threshold since start.
+// Call it deferred: defer logSlowQuery(time.Now(), query, args)
+func logSlowQuery(start time.Time, query string, args []interface{}) {
+	threshold := GetSlowQueryThreshold()
+	if threshold <= 0 || logger == nil {
+		return
+	}
+	elapsed := time.Since(start)
+	if elapsed < threshold {
+		return
+	}
+
+	event := logger.Warn().
+		Str("table", extractTableName(query)).
+		Str("query", query).
+		Int("arg_count", len(args)).
+		Dur("duration", elapsed)
+	if atomic.LoadInt32(&slowQueryLogArgs) == 1 {
+		event = event.Interface("args", args)
+	}
+	event.Msg("fsql: slow query")
+}
+
+// Explain returns the text plan of query with args, one plan line per line. With analyze
+// the query is executed (EXPLAIN (ANALYZE, BUFFERS)); it always runs inside a transaction
+// that is rolled back, so writes being analyzed leave no trace - though sequences still
+// advance and side effects outside the database still happen.
+func Explain(ctx context.Context, query string, args []interface{}, analyze bool) (string, error) {
+	explain := "EXPLAIN (FORMAT TEXT) "
+	if analyze {
+		explain = "EXPLAIN (ANALYZE, BUFFERS) "
+	}
+
+	tx, err := DB.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("explain failed: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, explain+query, args...)
+	if err != nil {
+		return "", fmt.Errorf("explain failed: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", fmt.Errorf("explain failed: %w", err)
+		}
+		lines = append(lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("explain failed: %w", err)
+	}
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,214 @@
+// sqlscan.go - Lightweight SQL keyword scanning (no full parser)
+package fsql
+
+import "strings"
+
+// sqlWords returns the upper-cased bare words of query in order, skipping
+// comments, string literals, quoted identifiers and dollar-quoted bodies.
+// Punctuation (parentheses, commas, operators) is dropped.
+func sqlWords(query string) []string {
+	var words []string
+	n := len(query)
+
+	for i := 0; i < n; {
+		if next, ok := skipSQLNonCode(query, i); ok {
+			i = next
+			continue
+		}
+
+		c := query[i]
+		switch {
+		case c == '$':
+			// $1 placeholder
+			i++
+			for i < n && query[i] >= '0' && query[i] <= '9' {
+				i++
+			}
+		case isWordByte(c) && !(c >= '0' && c <= '9'):
+			start := i
+			for i < n && isWordByte(query[i]) {
+				i++
+			}
+			words = append(words, strings.ToUpper(query[start:i]))
+		default:
+			i++
+		}
+	}
+
+	return words
+}
+
+// sqlToken is an identifier, keyword or punctuation byte of a query
+type sqlToken struct {
+	text   string // Identifier as written (without quotes) or the punctuation byte
+	upper  string // Upper-cased text of bare words for keyword matching ("" for quoted identifiers)
+	quoted bool
+}
+
+// sqlTokens splits query into words, quoted identifiers and the punctuation
+// "(", ")" and ".", skipping comments, string literals, dollar-quoted bodies and placeholders
+func sqlTokens(query string) []sqlToken {
+	var tokens []sqlToken
+	n := len(query)
+
+	for i := 0; i < n; {
+		c := query[i]
+		if c == '"' {
+			next, _ := skipSQLNonCode(query, i)
+			end := next - 1
+			if end <= i || query[end] != '"' {
+				end = next
+			}
+			tokens = append(tokens, sqlToken{text: strings.ReplaceAll(query[i+1:end], `""`, `"`), quoted: true})
+			i = next
+			continue
+		}
+		if next, ok := skipSQLNonCode(query, i); ok {
+			i = next
+			continue
+		}
+
+		switch {
+		case c == '$':
+			i++
+			for i < n && query[i] >= '0' && query[i] <= '9' {
+				i++
+			}
+		case isWordByte(c):
+			start := i
+			for i < n && isWordByte(query[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{text: query[start:i], upper: strings.ToUpper(query[start:i])})
+		case c == '(' || c == ')' || c == '.':
+			tokens = append(tokens, sqlToken{text: query[i : i+1]})
+			i++
+		default:
+			i++
+		}
+	}
+
+	return tokens
+}
+
+// skipSQLNonCode returns the index just past the comment, string literal, quoted
+// identifier or dollar-quoted body starting at query[i], or false if none starts there
+func skipSQLNonCode(query string, i int) (int, bool) {
+	n := len(query)
+	c := query[i]
+
+	switch {
+	case c == '-' && i+1 < n && query[i+1] == '-':
+		// Line comment
+		for i < n && query[i] != '\n' {
+			i++
+		}
+		return i, true
+	case c == '/' && i+1 < n && query[i+1] == '*':
+		// Block comment (Postgres allows nesting)
+		depth := 0
+		for i < n {
+			if query[i] == '/' && i+1 < n && query[i+1] == '*' {
+				depth++
+				i += 2
+				continue
+			}
+			if query[i] == '*' && i+1 < n && query[i+1] == '/' {
+				depth--
+				i += 2
+				if depth == 0 {
+					break
+				}
+				continue
+			}
+			i++
+		}
+		return i, true
+	case c == '\'' || c == '"':
+		// String literal or quoted identifier ('' and "" escape the quote)
+		i++
+		for i < n {
+			if query[i] == c {
+				if i+1 < n && query[i+1] == c {
+					i += 2
+					continue
+				}
+				i++
+				break
+			}
+			i++
+		}
+		return i, true
+	case c == '$':
+		// Dollar-quoted body ($$...$$ or $tag$...$tag$); $1 placeholders are not skipped
+		end := i + 1
+		for end < n && isWordByte(query[end]) && !(query[end] >= '0' && query[end] <= '9' && end == i+1) {
+			end++
+		}
+		if end < n && query[end] == '$' {
+			tag := query[i : end+1]
+			if close := strings.Index(query[end+1:], tag); close >= 0 {
+				return end + 1 + close + len(tag), true
+			}
+			return n, true
+		}
+	}
+
+	return i, false
+}
+
+// isWordByte reports whether c can be part of an unquoted SQL identifier or keyword
+func isWordByte(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c >= 0x80
+}
+
+// IsReadOnlyQuery reports whether query only reads data: SELECT, VALUES, TABLE, SHOW,
+// EXPLAIN (without ANALYZE of a write) and WITH ... SELECT. Writes hidden in CTEs
+// (WITH x AS (UPDATE ...)), SELECT ... INTO and row locks (FOR UPDATE/SHARE) are not read-only.
+// Leading whitespace and comments are ignored; unknown statements are treated as writes.
+func IsReadOnlyQuery(query string) bool {
+	words := sqlWords(query)
+	if len(words) == 0 {
+		return false
+	}
+
+	if words[0] == "EXPLAIN" {
+		analyze := false
+		for _, w := range words[1:] {
+			if w == "ANALYZE" || w == "ANALYSE" {
+				analyze = true
+				break
+			}
+		}
+		if !analyze {
+			return true
+		}
+		// EXPLAIN ANALYZE executes the statement: classify the statement itself
+		for i, w := range words[1:] {
+			switch w {
+			case "SELECT", "WITH", "VALUES", "TABLE", "INSERT", "UPDATE", "DELETE", "MERGE":
+				return readOnlyWords(words[1+i:])
+			}
+		}
+		return false
+	}
+
+	return readOnlyWords(words)
+}
+
+// readOnlyWords classifies a statement from its leading keyword and any write keywords in its body
+func readOnlyWords(words []string) bool {
+	switch words[0] {
+	case "SHOW":
+		return true
+	case "SELECT", "WITH", "VALUES", "TABLE":
+		for _, w := range words {
+			switch w {
+			case "INSERT", "UPDATE", "DELETE", "MERGE", "INTO", "SHARE":
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
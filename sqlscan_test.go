@@ -0,0 +1,85 @@
+// sqlscan_test.go
+package fsql
+
+import "testing"
+
+func TestIsReadOnlyQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		readOnly bool
+	}{
+		{"SELECT * FROM realm", true},
+		{"   \n\tselect 1", true},
+		{"-- fetch realms\nSELECT * FROM realm", true},
+		{"/* outer /* nested */ comment */ SELECT 1", true},
+		{"WITH recent AS (SELECT * FROM realm) SELECT * FROM recent", true},
+		{"EXPLAIN SELECT * FROM realm", true},
+		{"EXPLAIN (FORMAT JSON) DELETE FROM realm", true},
+		{"EXPLAIN ANALYZE SELECT * FROM realm", true},
+		{"EXPLAIN ANALYZE DELETE FROM realm", false},
+		{"VALUES (1), (2)", true},
+		{"SHOW search_path", true},
+		{"SELECT 'UPDATE realm SET x = 1' AS text", true},
+		{`SELECT "update" FROM audit`, true},
+		{"SELECT $$DELETE FROM realm$$", true},
+		{"SELECT * FROM realm WHERE uuid = $1", true},
+		{"INSERT INTO realm (name) VALUES ($1)", false},
+		{"UPDATE realm SET name = $1", false},
+		{"DELETE FROM realm", false},
+		{"-- comment\n  delete from realm", false},
+		{"CREATE TABLE t (id int)", false},
+		{"DROP TABLE t", false},
+		{"TRUNCATE realm", false},
+		{"WITH x AS (UPDATE realm SET name = 'a' RETURNING *) SELECT * FROM x", false},
+		{"WITH x AS (SELECT 1) INSERT INTO realm (name) SELECT 'a' FROM x", false},
+		{"SELECT * INTO realm_copy FROM realm", false},
+		{"SELECT * FROM realm FOR UPDATE", false},
+		{"SELECT * FROM realm FOR SHARE", false},
+		{"", false},
+		{"-- only a comment", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsReadOnlyQuery(tt.query); got != tt.readOnly {
+			t.Errorf("IsReadOnlyQuery(%q) = %v, want %v", tt.query, got, tt.readOnly)
+		}
+	}
+}
+
+func TestExtractTableName(t *testing.T) {
+	tests := []struct {
+		query string
+		table string
+	}{
+		// Base queries generated by this package
+		{`SELECT "ai_model"."uuid", "ai_model"."key" FROM "ai_model" `, "ai_model"},
+		{`SELECT "website"."uuid", "r"."name" AS "r.name" FROM "website"   LEFT JOIN "realm" AS r ON website.realm_uuid = r.uuid `, "website"},
+		{`SELECT "website"."uuid" FROM (SELECT "website"."uuid" FROM "website" WHERE website.uuid = $1) AS "website"   LEFT JOIN "realm" AS r ON website.realm_uuid = r.uuid `, "website"},
+		{`SELECT COUNT(*) FROM (SELECT "realm"."uuid" FROM "realm"  WHERE "realm".name = $1) AS count_subquery`, "realm"},
+		{`INSERT INTO "realm" ("uuid", "name") VALUES ($1, $2) RETURNING "realm"."uuid"`, "realm"},
+		{`UPDATE "realm" SET "name" = $1 WHERE "uuid" = $2`, "realm"},
+		{`DELETE FROM "realm" WHERE "uuid" = $1`, "realm"},
+		{`UPDATE "user_profile" SET "bio" = v."bio" FROM unnest($1::text[]) AS v("bio")`, "user_profile"},
+
+		// Hand-written shapes
+		{"select * from realm where name = 'FROM other'", "realm"},
+		{"SELECT * FROM public.realm", "realm"},
+		{`SELECT * FROM "My ""Table"""`, `My "Table"`},
+		{"DELETE FROM ONLY realm", "realm"},
+		{"-- comment FROM x\nINSERT INTO website (uuid) VALUES ($1)", "website"},
+		{"WITH recent AS (SELECT * FROM realm) SELECT * FROM website", "website"},
+		{"WITH moved AS (DELETE FROM realm RETURNING *) INSERT INTO realm_archive SELECT * FROM moved", "realm_archive"},
+
+		// Nothing to find
+		{"SELECT 1", "unknown"},
+		{"SELECT NOW()", "unknown"},
+		{"TRUNCATE realm", "unknown"},
+		{"", "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := extractTableName(tt.query); got != tt.table {
+			t.Errorf("extractTableName(%q) = %q, want %q", tt.query, got, tt.table)
+		}
+	}
+}
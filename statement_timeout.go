@@ -0,0 +1,147 @@
+// statement_timeout.go - Server-side statement_timeout for the Safe wrappers
+package fsql
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// statementTimeout is the server-side statement_timeout for Safe wrapper queries (0 = off)
+var statementTimeout int64
+
+// SetStatementTimeout makes Postgres itself cancel Safe wrapper statements running longer
+// than d, on top of the client-side context timeout. Each statement then runs in a short
+// transaction that issues SET LOCAL statement_timeout first, so the setting never leaks
+// to other users of the pooled connection. Zero or negative disables it.
+func SetStatementTimeout(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	atomic.StoreInt64(&statementTimeout, int64(d))
+}
+
+// GetStatementTimeout returns the server-side timeout set by SetStatementTimeout
+func GetStatementTimeout() time.Duration {
+	return time.Duration(atomic.LoadInt64(&statementTimeout))
+}
+
+// beginStatementTimeout opens a transaction on pool with statement_timeout set, or
+// returns a nil tx when no statement timeout is configured
+func beginStatementTimeout(ctx context.Context, pool *pgxpool.Pool) (pgx.Tx, error) {
+	d := GetStatementTimeout()
+	if d <= 0 {
+		return nil, nil
+	}
+	ms := d.Milliseconds()
+	if ms < 1 {
+		ms = 1
+	}
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, "SET LOCAL statement_timeout = "+strconv.FormatInt(ms, 10)); err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// endStatementTimeout commits tx when the statement succeeded and rolls it back otherwise
+func endStatementTimeout(ctx context.Context, tx pgx.Tx, err error) error {
+	if err != nil {
+		tx.Rollback(ctx)
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// poolExec runs Exec on pool, under the statement timeout when one is set
+func poolExec(ctx context.Context, pool *pgxpool.Pool, query string, args ...interface{}) (pgconn.CommandTag, error) {
+	tx, err := beginStatementTimeout(ctx, pool)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if tx == nil {
+		return pool.Exec(ctx, query, args...)
+	}
+	tag, err := tx.Exec(ctx, query, args...)
+	return tag, endStatementTimeout(ctx, tx, err)
+}
+
+// poolQuery runs Query on pool, under the statement timeout when one is set; the
+// transaction ends when the returned rows are closed
+func poolQuery(ctx context.Context, pool *pgxpool.Pool, query string, args ...interface{}) (pgx.Rows, error) {
+	tx, err := beginStatementTimeout(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return pool.Query(ctx, query, args...)
+	}
+	rows, err := tx.Query(ctx, query, args...)
+	if err != nil {
+		tx.Rollback(ctx)
+		return nil, err
+	}
+	return &releaseOnCloseRows{Rows: rows, release: func() {
+		endStatementTimeout(ctx, tx, rows.Err())
+	}}, nil
+}
+
+// releaseOnCloseRows is pgx.Rows that runs release once, after the rows are done
+type releaseOnCloseRows struct {
+	pgx.Rows
+	release  func()
+	released bool
+}
+
+// Next advances the rows, releasing after the last row
+func (r *releaseOnCloseRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.Close()
+	return false
+}
+
+// Close closes the rows, then releases
+func (r *releaseOnCloseRows) Close() {
+	r.Rows.Close()
+	if !r.released {
+		r.released = true
+		r.release()
+	}
+}
+
+// rowsRow adapts pgx.Rows to pgx.Row, so single-row reads go through the same path as
+// SafeQuery and release their context once scanned
+type rowsRow struct {
+	rows pgx.Rows
+	err  error
+}
+
+// Scan reads the first row into dest and closes the rows (pgx.ErrNoRows when empty)
+func (r *rowsRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	defer r.rows.Close()
+	if !r.rows.Next() {
+		if err := r.rows.Err(); err != nil {
+			return err
+		}
+		return pgx.ErrNoRows
+	}
+	if err := r.rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.rows.Close()
+	return r.rows.Err()
+}
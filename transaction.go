@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -26,6 +27,13 @@ type TxOptions struct {
 	AccessMode   pgx.TxAccessMode
 	DeferrableMode pgx.TxDeferrableMode
 	MaxRetries   int
+	// ReadOnlyGuard rejects writes client-side with ErrWriteInReadOnlyTx when AccessMode is
+	// pgx.ReadOnly, instead of waiting for the server to refuse them (opt-in)
+	ReadOnlyGuard bool
+	// BaseBackoff is the wait before the first retry, doubling on each attempt (0 = 100ms)
+	BaseBackoff time.Duration
+	// MaxBackoff caps the wait between retries (0 = uncapped)
+	MaxBackoff time.Duration
 }
 
 // Default transaction options
@@ -39,10 +47,22 @@ var DefaultTxOptions = TxOptions{
 // ErrTxDone is returned when attempting an operation on a completed transaction
 var ErrTxDone = errors.New("transaction has already been committed or rolled back")
 
+// ErrWriteInReadOnlyTx is returned by guarded read-only transactions for statements that write
+var ErrWriteInReadOnlyTx = errors.New("write statement in read-only transaction")
+
+// defaultBaseBackoff is the first retry wait when TxOptions.BaseBackoff is zero
+const defaultBaseBackoff = 100 * time.Millisecond
+
 // ErrMaxRetriesExceeded is returned when transaction exceeds max retry attempts
 var ErrMaxRetriesExceeded = errors.New("transaction max retries exceeded")
 
-// Common retryable error substrings
+// Retryable SQLSTATE codes
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// Retryable error substrings, only checked when SetRetryableErrorStringMatch is enabled
 var retryableErrors = []string{
 	"deadlock detected",
 	"serialize",
@@ -55,7 +75,42 @@ var retryableErrors = []string{
 	"lock timeout",
 	"connection reset",
 	"40001", // Serialization failure
-	"40P01", // Deadlock detected
+	"40p01", // Deadlock detected
+}
+
+var (
+	// retryableErrorFunc holds a retryableErrorCheck overriding the default classification
+	retryableErrorFunc atomic.Value
+
+	// retryableStringMatch enables the retryableErrors substring fallback (0 = off, 1 = on)
+	retryableStringMatch int32
+)
+
+// retryableErrorCheck wraps the user function so atomic.Value always stores one concrete type
+type retryableErrorCheck struct {
+	fn func(error) bool
+}
+
+// SetRetryableErrorFunc replaces how WithTxRetry decides an error is worth retrying.
+// nil restores the default (SQLSTATE 40001 serialization failure and 40P01 deadlock).
+func SetRetryableErrorFunc(fn func(error) bool) {
+	retryableErrorFunc.Store(retryableErrorCheck{fn: fn})
+}
+
+// SetRetryableErrorStringMatch also retries errors whose text contains a known substring
+// ("deadlock", "conflict", ...), for errors that lost their SQLSTATE when wrapped as text.
+// Off by default since it can match unrelated application errors.
+func SetRetryableErrorStringMatch(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&retryableStringMatch, v)
+}
+
+// GetRetryableErrorStringMatch reports whether the substring fallback is enabled
+func GetRetryableErrorStringMatch() bool {
+	return atomic.LoadInt32(&retryableStringMatch) == 1
 }
 
 // BeginTx starts a new transaction with the default options
@@ -80,6 +135,10 @@ func BeginTxWithOptions(ctx context.Context, opts TxOptions) (*Tx, error) {
 		return nil, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 
+	if opts.ReadOnlyGuard && opts.AccessMode == pgx.ReadOnly {
+		tx = &readOnlyGuardTx{Tx: tx}
+	}
+
 	return &Tx{tx: tx}, nil
 }
 
@@ -178,6 +237,71 @@ func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interfa
 // TxFn defines a function that uses a transaction
 type TxFn func(context.Context, *Tx) error
 
+// Savepoint creates a savepoint named name inside the transaction
+func (tx *Tx) Savepoint(ctx context.Context, name string) error {
+	return tx.savepointExec(ctx, "SAVEPOINT ", name)
+}
+
+// RollbackToSavepoint undoes everything done since the savepoint and clears an aborted
+// transaction state; the savepoint itself stays defined
+func (tx *Tx) RollbackToSavepoint(ctx context.Context, name string) error {
+	return tx.savepointExec(ctx, "ROLLBACK TO SAVEPOINT ", name)
+}
+
+// ReleaseSavepoint forgets the savepoint, keeping the work done since it was created
+func (tx *Tx) ReleaseSavepoint(ctx context.Context, name string) error {
+	return tx.savepointExec(ctx, "RELEASE SAVEPOINT ", name)
+}
+
+// savepointExec runs a savepoint statement with a quoted name
+func (tx *Tx) savepointExec(ctx context.Context, statement, name string) error {
+	if tx.tx == nil {
+		return ErrTxDone
+	}
+	quoted, err := QuoteIdentifier(name)
+	if err != nil {
+		return fmt.Errorf("invalid savepoint name: %w", err)
+	}
+	if _, err := tx.tx.Exec(ctx, statement+quoted); err != nil {
+		return fmt.Errorf("%s%s failed: %w", statement, name, err)
+	}
+	return nil
+}
+
+// savepointCounter numbers the savepoints created by WithSavepoint
+var savepointCounter uint64
+
+// WithSavepoint runs fn inside a savepoint of tx, giving it its own rollback boundary:
+// if fn returns an error (or panics) only its work is undone and the outer transaction
+// can continue; otherwise the savepoint is released. fn's error is returned as is.
+// Inside WithTxRetry, returning a serialization failure or deadlock from the outer function
+// retries the whole transaction (savepoints included); swallowing it keeps the outer work.
+func WithSavepoint(ctx context.Context, tx *Tx, fn TxFn) (err error) {
+	name := fmt.Sprintf("fsql_sp_%d", atomic.AddUint64(&savepointCounter, 1))
+	if err := tx.Savepoint(ctx, name); err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.RollbackToSavepoint(ctx, name)
+			panic(p) // Re-throw panic after rollback
+		}
+	}()
+
+	if err := fn(ctx, tx); err != nil {
+		if rbErr := tx.RollbackToSavepoint(ctx, name); rbErr != nil {
+			return fmt.Errorf("savepoint err: %v, rb err: %v", err, rbErr)
+		}
+		if relErr := tx.ReleaseSavepoint(ctx, name); relErr != nil {
+			return fmt.Errorf("savepoint err: %v, release err: %v", err, relErr)
+		}
+		return err
+	}
+
+	return tx.ReleaseSavepoint(ctx, name)
+}
+
 // WithTx executes a function within a transaction
 // If the function returns an error, the transaction is rolled back
 // If the function returns nil, the transaction is committed
@@ -215,7 +339,9 @@ func WithTxRetry(ctx context.Context, fn TxFn) error {
 	return WithTxRetryOptions(ctx, DefaultTxOptions, fn)
 }
 
-// WithTxRetryOptions executes a function within a transaction with retry logic and options
+// WithTxRetryOptions executes a function within a transaction with retry logic and options.
+// Retries wait opts.BaseBackoff doubled per attempt, capped at opts.MaxBackoff; once retries
+// run out the last error is returned wrapped in ErrMaxRetriesExceeded.
 func WithTxRetryOptions(ctx context.Context, opts TxOptions, fn TxFn) error {
 	var err error
 	maxRetries := opts.MaxRetries
@@ -234,32 +360,67 @@ func WithTxRetryOptions(ctx context.Context, opts TxOptions, fn TxFn) error {
 			return err
 		}
 
-		// Wait with exponential backoff with jitter before retrying
-		baseBackoff := time.Duration(1<<uint(attempt)) * 100 * time.Millisecond
-		jitter := time.Duration(float64(baseBackoff) * 0.2 * (rand.Float64() - 0.5))
-		backoff := baseBackoff + jitter
+		// No wait after the last attempt
+		if attempt == maxRetries-1 {
+			break
+		}
 
+		// Wait with exponential backoff with jitter before retrying
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(backoff):
+		case <-time.After(retryBackoff(opts, attempt)):
 			// Continue with retry
 		}
 	}
 
 	if err != nil {
-		return fmt.Errorf("%w: %v", ErrMaxRetriesExceeded, err)
+		return fmt.Errorf("%w after %d attempts: %w", ErrMaxRetriesExceeded, maxRetries, err)
 	}
 
 	return ErrMaxRetriesExceeded
 }
 
+// retryBackoff returns the wait after a failed attempt (0-based): exponential from
+// opts.BaseBackoff with ±10% jitter, clamped to opts.MaxBackoff when set
+func retryBackoff(opts TxOptions, attempt int) time.Duration {
+	base := opts.BaseBackoff
+	if base <= 0 {
+		base = defaultBaseBackoff
+	}
+	if attempt > 30 {
+		attempt = 30 // keep the shift from overflowing
+	}
+
+	backoff := base << uint(attempt)
+	if backoff <= 0 || (opts.MaxBackoff > 0 && backoff > opts.MaxBackoff) {
+		backoff = opts.MaxBackoff
+	}
+	jitter := time.Duration(float64(backoff) * 0.2 * (rand.Float64() - 0.5))
+	backoff += jitter
+	if opts.MaxBackoff > 0 && backoff > opts.MaxBackoff {
+		backoff = opts.MaxBackoff
+	}
+	return backoff
+}
+
 // isRetryableError determines if an error can be retried
 func isRetryableError(err error) bool {
 	if err == nil {
 		return false
 	}
+	if check, ok := retryableErrorFunc.Load().(retryableErrorCheck); ok && check.fn != nil {
+		return check.fn(err)
+	}
 
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == sqlStateSerializationFailure || pgErr.Code == sqlStateDeadlockDetected
+	}
+
+	if !GetRetryableErrorStringMatch() {
+		return false
+	}
 	errMsg := strings.ToLower(err.Error())
 	for _, retryMsg := range retryableErrors {
 		if strings.Contains(errMsg, retryMsg) {
@@ -271,12 +432,61 @@ func isRetryableError(err error) bool {
 }
 
 // WithReadTx executes a function within a read-only transaction
+// Set DefaultTxOptions.ReadOnlyGuard to reject writes client-side with ErrWriteInReadOnlyTx
 func WithReadTx(ctx context.Context, fn TxFn) error {
 	opts := DefaultTxOptions
 	opts.AccessMode = pgx.ReadOnly
 	return WithTxOptions(ctx, opts, fn)
 }
 
+// snapshotCursorName is the server-side cursor used by ForEachPageInSnapshot
+const snapshotCursorName = "fsql_snapshot_cursor"
+
+// ForEachPageInSnapshot pages through every row of baseQuery inside a single
+// REPEATABLE READ read-only transaction, calling fn once per page of up to perPage rows.
+// All pages come from the same snapshot, so concurrent writes cannot cause skipped
+// or duplicated rows. Rows are fetched through a server-side cursor, so baseQuery
+// does not need a LIMIT/OFFSET or a unique sort key.
+func ForEachPageInSnapshot[T any](ctx context.Context, baseQuery, table string, perPage int, fn func([]T) error, args ...interface{}) error {
+	if _, ok := getModelInfo(table); !ok {
+		return fmt.Errorf("table name not initialized: %s", table)
+	}
+	if perPage <= 0 {
+		return fmt.Errorf("perPage must be positive, got %d", perPage)
+	}
+
+	opts := DefaultTxOptions
+	opts.IsoLevel = pgx.RepeatableRead
+	opts.AccessMode = pgx.ReadOnly
+
+	return WithTxOptions(ctx, opts, func(ctx context.Context, tx *Tx) error {
+		_, err := tx.ExecContext(ctx, "DECLARE "+snapshotCursorName+" NO SCROLL CURSOR FOR "+baseQuery, args...)
+		if err != nil {
+			return fmt.Errorf("failed to declare cursor: %w", err)
+		}
+
+		fetchQuery := fmt.Sprintf("FETCH FORWARD %d FROM %s", perPage, snapshotCursorName)
+		for {
+			var page []T
+			if err := tx.SelectContext(ctx, &page, fetchQuery); err != nil {
+				return err
+			}
+			if len(page) == 0 {
+				break
+			}
+			if err := fn(page); err != nil {
+				return err
+			}
+			if len(page) < perPage {
+				break
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, "CLOSE "+snapshotCursorName)
+		return err
+	})
+}
+
 // WithSerializableTx executes a function within a serializable isolation level transaction
 func WithSerializableTx(ctx context.Context, fn TxFn) error {
 	opts := DefaultTxOptions
@@ -315,7 +525,11 @@ func UpdateWithTx(ctx context.Context, tx *Tx, tableName string, values map[stri
 		return ErrTxDone
 	}
 
-	query, args := GetUpdateQuery(tableName, values, returning)
-	_, err := tx.tx.Exec(ctx, query, args...)
+	// Nothing is scanned, so match on the key without a RETURNING clause
+	query, args, err := GetUpdateQueryWhere(tableName, values, returning, "")
+	if err != nil {
+		return err
+	}
+	_, err = tx.tx.Exec(ctx, query, args...)
 	return err
 }
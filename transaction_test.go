@@ -4,10 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // TestTransactionCommit tests a basic transaction commit
@@ -184,7 +187,7 @@ func TestWithTxRetry(t *testing.T) {
 		attemptCount++
 		// Fail first attempt with retryable error
 		if attemptCount == 1 {
-			return errors.New("deadlock detected")
+			return &pgconn.PgError{Code: "40P01", Message: "deadlock detected"}
 		}
 
 		return nil
@@ -212,6 +215,179 @@ func TestWithTxRetry(t *testing.T) {
 	}
 }
 
+// TestRetryableErrors tests SQLSTATE-based retry classification and its overrides
+func TestRetryableErrors(t *testing.T) {
+	serialization := fmt.Errorf("insert failed: %w", &pgconn.PgError{Code: "40001", Message: "could not serialize access"})
+	uniqueViolation := &pgconn.PgError{Code: "23505", Message: "duplicate key value violates unique constraint"}
+	appErr := errors.New("booking conflict: room already taken")
+
+	if !isRetryableError(serialization) || !isRetryableError(&pgconn.PgError{Code: "40P01"}) {
+		t.Error("Expected serialization failures and deadlocks to be retryable")
+	}
+	if isRetryableError(uniqueViolation) {
+		t.Error("Expected a unique violation not to be retryable")
+	}
+	if isRetryableError(appErr) {
+		t.Error("Expected application errors not to be retried by default")
+	}
+
+	// String fallback is opt-in, and never overrides a known SQLSTATE
+	SetRetryableErrorStringMatch(true)
+	if !isRetryableError(appErr) {
+		t.Error("Expected the string fallback to match a conflict message")
+	}
+	if isRetryableError(&pgconn.PgError{Code: "23505", Message: "conflict"}) {
+		t.Error("Expected SQLSTATE to take precedence over the message text")
+	}
+	SetRetryableErrorStringMatch(false)
+
+	// A custom classifier replaces the default
+	SetRetryableErrorFunc(func(err error) bool { return errors.Is(err, appErr) })
+	defer SetRetryableErrorFunc(nil)
+	if !isRetryableError(appErr) || isRetryableError(serialization) {
+		t.Error("Expected the custom classifier to be used")
+	}
+
+	attempts := 0
+	err := WithTxRetry(context.Background(), func(ctx context.Context, tx *Tx) error {
+		attempts++
+		return appErr
+	})
+	if !errors.Is(err, ErrMaxRetriesExceeded) || attempts != DefaultTxOptions.MaxRetries {
+		t.Errorf("Expected %d attempts ending in ErrMaxRetriesExceeded, got %d: %v", DefaultTxOptions.MaxRetries, attempts, err)
+	}
+
+	SetRetryableErrorFunc(nil)
+	if isRetryableError(appErr) || !isRetryableError(serialization) {
+		t.Error("Expected nil to restore the default classification")
+	}
+}
+
+// TestRetryBackoff tests configurable exponential backoff and the final retry error
+func TestRetryBackoff(t *testing.T) {
+	// Defaults: 100ms doubling with ±10% jitter
+	for attempt, want := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		got := retryBackoff(TxOptions{}, attempt)
+		if got < want*9/10 || got > want*11/10 {
+			t.Errorf("Attempt %d: expected about %v, got %v", attempt, want, got)
+		}
+	}
+
+	opts := TxOptions{BaseBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	previous := time.Duration(0)
+	for attempt := 0; attempt < 3; attempt++ {
+		got := retryBackoff(opts, attempt)
+		if got <= previous {
+			t.Errorf("Expected backoff to grow, attempt %d got %v after %v", attempt, got, previous)
+		}
+		previous = got
+	}
+	for _, attempt := range []int{3, 10, 100} {
+		if got := retryBackoff(opts, attempt); got > opts.MaxBackoff || got < opts.MaxBackoff*9/10 {
+			t.Errorf("Attempt %d: expected backoff capped at %v, got %v", attempt, opts.MaxBackoff, got)
+		}
+	}
+
+	// Exhausted retries keep the underlying error and report the attempt count
+	deadlock := &pgconn.PgError{Code: "40P01", Message: "deadlock detected"}
+	retryOpts := DefaultTxOptions
+	retryOpts.MaxRetries = 3
+	retryOpts.BaseBackoff = time.Millisecond
+	retryOpts.MaxBackoff = 2 * time.Millisecond
+	start := time.Now()
+	err := WithTxRetryOptions(context.Background(), retryOpts, func(ctx context.Context, tx *Tx) error {
+		return deadlock
+	})
+	var pgErr *pgconn.PgError
+	if !errors.Is(err, ErrMaxRetriesExceeded) || !errors.As(err, &pgErr) || pgErr.Code != "40P01" {
+		t.Errorf("Expected ErrMaxRetriesExceeded wrapping the deadlock, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Expected the attempt count in %q", err.Error())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected short capped backoffs, took %v", elapsed)
+	}
+
+	// Cancellation between attempts stops retrying
+	ctx, cancel := context.WithCancel(context.Background())
+	retryOpts.BaseBackoff = time.Hour
+	retryOpts.MaxBackoff = 0
+	err = WithTxRetryOptions(ctx, retryOpts, func(ctx context.Context, tx *Tx) error {
+		cancel()
+		return deadlock
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// TestSavepoints tests partial rollbacks inside a transaction
+func TestSavepoints(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	err := WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+		if _, err := tx.Exec("INSERT INTO realm (uuid, name) VALUES ($1, $2)", uuid.New().String(), "Kept Realm"); err != nil {
+			return err
+		}
+
+		// Manual savepoint: a failed statement is undone without aborting the transaction
+		if err := tx.Savepoint(ctx, "before_dup"); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("INSERT INTO realm (uuid, name) VALUES ($1, $2)", "not-a-uuid", "Bad Realm"); err == nil {
+			return errors.New("expected invalid uuid error")
+		}
+		if err := tx.RollbackToSavepoint(ctx, "before_dup"); err != nil {
+			return err
+		}
+		if err := tx.ReleaseSavepoint(ctx, "before_dup"); err != nil {
+			return err
+		}
+
+		// WithSavepoint: the failing block's insert is rolled back, the outer work stays
+		spErr := WithSavepoint(ctx, tx, func(ctx context.Context, tx *Tx) error {
+			if _, err := tx.Exec("INSERT INTO realm (uuid, name) VALUES ($1, $2)", uuid.New().String(), "Discarded Realm"); err != nil {
+				return err
+			}
+			return errors.New("nested failure")
+		})
+		if spErr == nil || spErr.Error() != "nested failure" {
+			return fmt.Errorf("expected nested failure, got %v", spErr)
+		}
+
+		return WithSavepoint(ctx, tx, func(ctx context.Context, tx *Tx) error {
+			_, err := tx.Exec("INSERT INTO realm (uuid, name) VALUES ($1, $2)", uuid.New().String(), "Nested Realm")
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx with savepoints failed: %v", err)
+	}
+
+	var names []string
+	rows, err := DB.Query(ctx, "SELECT name FROM realm ORDER BY name")
+	if err != nil {
+		t.Fatalf("Failed to query realms: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("Failed to scan realm: %v", err)
+		}
+		names = append(names, name)
+	}
+	if len(names) != 2 || names[0] != "Kept Realm" || names[1] != "Nested Realm" {
+		t.Errorf("Expected [Kept Realm Nested Realm], got %v", names)
+	}
+
+	if err := (&Tx{}).Savepoint(ctx, "sp"); !errors.Is(err, ErrTxDone) {
+		t.Errorf("Expected ErrTxDone on a finished transaction, got %v", err)
+	}
+}
+
 // TestTransactionIsolationLevels tests different isolation levels
 func TestTransactionIsolationLevels(t *testing.T) {
 	cleanDatabase(t)
@@ -374,6 +550,68 @@ func TestReadOnlyTransaction(t *testing.T) {
 	}
 }
 
+// TestReadOnlyTransactionGuard tests the opt-in client-side write guard
+func TestReadOnlyTransactionGuard(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	opts := DefaultTxOptions
+	opts.AccessMode = pgx.ReadOnly
+	opts.ReadOnlyGuard = true
+
+	// Reads, session settings and cursors pass the guard
+	var count int
+	err := WithTxOptions(ctx, opts, func(ctx context.Context, tx *Tx) error {
+		if _, err := tx.Exec("SET LOCAL statement_timeout = '5s'"); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DECLARE guard_cursor CURSOR FOR SELECT uuid FROM realm"); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("CLOSE guard_cursor"); err != nil {
+			return err
+		}
+		return tx.QueryRow("SELECT COUNT(*) FROM realm").Scan(&count)
+	})
+	if err != nil {
+		t.Fatalf("Reads in guarded read-only tx failed: %v", err)
+	}
+
+	writes := []string{
+		"INSERT INTO realm (uuid, name) VALUES (uuid_generate_v4(), 'Should Fail')",
+		"UPDATE realm SET name = 'x'",
+		"WITH x AS (DELETE FROM realm RETURNING *) SELECT * FROM x",
+	}
+	for _, query := range writes {
+		err = WithTxOptions(ctx, opts, func(ctx context.Context, tx *Tx) error {
+			_, err := tx.Exec(query)
+			return err
+		})
+		if !errors.Is(err, ErrWriteInReadOnlyTx) {
+			t.Errorf("Expected ErrWriteInReadOnlyTx for %q, got %v", query, err)
+		}
+	}
+
+	// The guard also covers QueryRow
+	err = WithTxOptions(ctx, opts, func(ctx context.Context, tx *Tx) error {
+		var uuid string
+		return tx.QueryRow("INSERT INTO realm (name) VALUES ('x') RETURNING uuid").Scan(&uuid)
+	})
+	if !errors.Is(err, ErrWriteInReadOnlyTx) {
+		t.Errorf("Expected ErrWriteInReadOnlyTx from QueryRow, got %v", err)
+	}
+
+	// Without the guard the server rejects the write with its own error
+	opts.ReadOnlyGuard = false
+	err = WithTxOptions(ctx, opts, func(ctx context.Context, tx *Tx) error {
+		_, err := tx.Exec(writes[0])
+		return err
+	})
+	if err == nil || errors.Is(err, ErrWriteInReadOnlyTx) {
+		t.Errorf("Expected a server-side error without the guard, got %v", err)
+	}
+}
+
 // TestTransactionQueriesAndGetSelect tests Query, Get, Select operations in transaction
 func TestTransactionQueriesAndGetSelect(t *testing.T) {
 	cleanDatabase(t)
@@ -488,6 +726,184 @@ func TestBatchOperationsWithTx(t *testing.T) {
 	}
 }
 
+// TestBatchUpsert tests multi-row INSERT ... ON CONFLICT DO UPDATE in map and object mode
+func TestBatchUpsert(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	existing := uuid.New().String()
+	if _, err := SafeExec(`INSERT INTO realm (uuid, name) VALUES ($1, 'old-name')`, existing); err != nil {
+		t.Fatalf("Failed to insert realm: %v", err)
+	}
+
+	batch, err := NewBatchUpsert("realm", []string{"uuid", "name"}, []string{"uuid"}, []string{"name"}, 10)
+	if err != nil {
+		t.Fatalf("NewBatchUpsert failed: %v", err)
+	}
+	for _, row := range []map[string]interface{}{
+		{"uuid": existing, "name": "new-name"},
+		{"uuid": uuid.New().String(), "name": "inserted"},
+	} {
+		if err := batch.Add(row); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	query, _ := batch.buildQuery()
+	if !strings.HasSuffix(query, `ON CONFLICT ("uuid") DO UPDATE SET "name" = EXCLUDED."name"`) {
+		t.Errorf("Unexpected upsert query: %s", query)
+	}
+	if err := batch.FlushContext(ctx); err != nil {
+		t.Fatalf("Upsert flush failed: %v", err)
+	}
+
+	var names []string
+	if err := Db.Select(&names, `SELECT name FROM realm ORDER BY name`); err != nil {
+		t.Fatalf("Failed to read realms: %v", err)
+	}
+	if len(names) != 2 || names[0] != "inserted" || names[1] != "new-name" {
+		t.Errorf("Expected one updated and one inserted realm, got %v", names)
+	}
+
+	// Object mode updates the inserted dbMode "u" fields, and flushes inside a transaction.
+	// Zero CreatedAt gets its NOW() default; a set UpdatedAt is kept.
+	updatedAt := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	inserted := uuid.New().String()
+	err = WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+		objects, err := NewBatchUpsertExecutor("realm", []string{"uuid"}, nil, 10)
+		if err != nil {
+			return err
+		}
+		if err := objects.Add(&Realm{UUID: existing, Name: "object-name", UpdatedAt: updatedAt}); err != nil {
+			return err
+		}
+		if err := objects.Add(&Realm{UUID: inserted, Name: "object-inserted"}); err != nil {
+			return err
+		}
+		query, _ := objects.buildQuery()
+		if !strings.HasSuffix(query, `ON CONFLICT ("uuid") DO UPDATE SET "updated_at" = EXCLUDED."updated_at", "name" = EXCLUDED."name"`) {
+			t.Errorf("Unexpected object upsert query: %s", query)
+		}
+		return objects.FlushWithTxContext(ctx, tx)
+	})
+	if err != nil {
+		t.Fatalf("Object upsert failed: %v", err)
+	}
+	var realm Realm
+	if err := Db.Get(&realm, realmBaseQuery+` WHERE "realm".uuid = $1`, existing); err != nil {
+		t.Fatalf("Failed to read upserted realm: %v", err)
+	}
+	if realm.Name != "object-name" || !realm.UpdatedAt.Equal(updatedAt) {
+		t.Errorf("Expected object-name updated at %v, got %q at %v", updatedAt, realm.Name, realm.UpdatedAt)
+	}
+	if err := Db.Get(&realm, realmBaseQuery+` WHERE "realm".uuid = $1`, inserted); err != nil {
+		t.Fatalf("Failed to read inserted realm: %v", err)
+	}
+	if time.Since(realm.CreatedAt) > time.Minute || time.Since(realm.UpdatedAt) > time.Minute {
+		t.Errorf("Expected NOW() defaults, got created_at %v updated_at %v", realm.CreatedAt, realm.UpdatedAt)
+	}
+
+	// Update fields must be inserted, and conflict fields are required
+	if _, err := NewBatchUpsert("realm", []string{"uuid", "name"}, []string{"uuid"}, []string{"updated_at"}, 10); err == nil {
+		t.Error("Expected an error for an update field that isn't inserted")
+	}
+	if _, err := NewBatchUpsert("realm", []string{"uuid", "name"}, nil, nil, 10); err == nil {
+		t.Error("Expected an error without conflict fields")
+	}
+	if _, err := NewBatchUpsertExecutor("realm", nil, nil, 10); err == nil {
+		t.Error("Expected an error without conflict fields")
+	}
+	objects, err := NewBatchUpsertExecutor("realm", []string{"uuid"}, []string{"missing"}, 10)
+	if err != nil {
+		t.Fatalf("NewBatchUpsertExecutor failed: %v", err)
+	}
+	if err := objects.Add(&Realm{UUID: existing, Name: "rejected"}); err == nil {
+		t.Error("Expected Add to reject an update field that isn't inserted")
+	}
+
+	// No update fields means conflicting rows are left alone
+	var name string
+	ignore, err := NewBatchUpsert("realm", []string{"uuid", "name"}, []string{"uuid"}, nil, 10)
+	if err != nil {
+		t.Fatalf("NewBatchUpsert failed: %v", err)
+	}
+	if err := ignore.Add(map[string]interface{}{"uuid": existing, "name": "ignored"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := ignore.Flush(); err != nil {
+		t.Fatalf("DO NOTHING flush failed: %v", err)
+	}
+	if err := Db.Get(&name, `SELECT name FROM realm WHERE uuid = $1`, existing); err != nil || name != "object-name" {
+		t.Errorf("Expected the conflicting row untouched, got %q (%v)", name, err)
+	}
+}
+
+// TestBatchFlushReturning tests collecting RETURNING values across auto and final flushes
+func TestBatchFlushReturning(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	batch := NewBatchInsert("realm", []string{"uuid", "name"}, 2)
+	if _, err := batch.FlushReturning(ctx); err == nil {
+		t.Error("Expected an error without a returning field")
+	}
+	batch.SetReturning("uuid")
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		ids = append(ids, uuid.New().String())
+		if err := batch.Add(map[string]interface{}{"uuid": ids[i], "name": fmt.Sprintf("returning-%d", i)}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	returned, err := batch.FlushReturning(ctx)
+	if err != nil {
+		t.Fatalf("FlushReturning failed: %v", err)
+	}
+	if len(returned) != 5 {
+		t.Fatalf("Expected 5 returned values, got %v", returned)
+	}
+	for i, id := range ids {
+		if returned[i] != id {
+			t.Errorf("Expected %s at %d, got %v", id, i, returned[i])
+		}
+	}
+
+	// Values are handed out once
+	if returned, err := batch.FlushReturning(ctx); err != nil || len(returned) != 0 {
+		t.Errorf("Expected nothing left to return, got %v (%v)", returned, err)
+	}
+
+	// A plain Flush discards what auto-flushes collected
+	for i := 0; i < 3; i++ {
+		if err := batch.Add(map[string]interface{}{"uuid": uuid.New().String(), "name": fmt.Sprintf("discarded-%d", i)}); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if len(batch.returned) != 2 {
+		t.Errorf("Expected the auto-flushed batch to be collected, got %v", batch.returned)
+	}
+	if err := batch.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if returned, err := batch.FlushReturning(ctx); err != nil || len(returned) != 0 {
+		t.Errorf("Expected Flush to discard collected values, got %v (%v)", returned, err)
+	}
+
+	err = WithTx(ctx, func(ctx context.Context, tx *Tx) error {
+		if err := batch.Add(map[string]interface{}{"uuid": uuid.New().String(), "name": "returning-tx"}); err != nil {
+			return err
+		}
+		returned, err := batch.FlushReturningWithTx(ctx, tx)
+		if err == nil && len(returned) != 1 {
+			t.Errorf("Expected 1 returned value in the transaction, got %v", returned)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("FlushReturningWithTx failed: %v", err)
+	}
+}
+
 // TestBatchUpdateWithTx tests batch update within a transaction
 func TestBatchUpdateWithTx(t *testing.T) {
 	cleanDatabase(t)
@@ -536,3 +952,64 @@ func TestBatchUpdateWithTx(t *testing.T) {
 		t.Errorf("Expected name 'Updated 0', got '%s'", name)
 	}
 }
+
+// TestForEachPageInSnapshot tests that paging inside a snapshot ignores concurrent writes
+func TestForEachPageInSnapshot(t *testing.T) {
+	cleanDatabase(t)
+	ctx := context.Background()
+
+	expected := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		id := uuid.New().String()
+		expected[id] = true
+		_, err := DB.Exec(ctx, "INSERT INTO realm (uuid, name) VALUES ($1, $2)", id, fmt.Sprintf("Snapshot %d", i))
+		if err != nil {
+			t.Fatalf("Failed to insert test data: %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	pages := 0
+	err := ForEachPageInSnapshot(ctx, realmBaseQuery+` ORDER BY "realm".name`, "realm", 3, func(page []Realm) error {
+		pages++
+		if pages == 1 {
+			// Concurrent writes outside the snapshot must not affect the scan
+			if _, err := DB.Exec(ctx, "INSERT INTO realm (uuid, name) VALUES ($1, $2)", uuid.New().String(), "Snapshot AAA"); err != nil {
+				return err
+			}
+			if _, err := DB.Exec(ctx, "DELETE FROM realm WHERE uuid = $1", page[0].UUID); err != nil {
+				return err
+			}
+		}
+		for _, r := range page {
+			if seen[r.UUID] {
+				return fmt.Errorf("duplicate row %s", r.UUID)
+			}
+			seen[r.UUID] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ForEachPageInSnapshot failed: %v", err)
+	}
+
+	if pages != 4 {
+		t.Errorf("Expected 4 pages, got %d", pages)
+	}
+	if len(seen) != len(expected) {
+		t.Errorf("Expected %d rows, got %d", len(expected), len(seen))
+	}
+	for id := range expected {
+		if !seen[id] {
+			t.Errorf("Row %s missing from snapshot scan", id)
+		}
+	}
+
+	stop := errors.New("stop")
+	err = ForEachPageInSnapshot(ctx, realmBaseQuery, "realm", 2, func(page []Realm) error {
+		return stop
+	})
+	if !errors.Is(err, stop) {
+		t.Errorf("Expected callback error to propagate, got %v", err)
+	}
+}
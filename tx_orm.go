@@ -9,7 +9,7 @@ import (
 	"strings"
 )
 
-// Custom query step types for transaction query builder
+// Query step types for ORDER BY/LIMIT/OFFSET, rendered last by QueryBuilder.Build
 type orderByStep struct {
 	Clause string
 }
@@ -34,61 +34,37 @@ func InsertObjectWithTxContext(ctx context.Context, tx *Tx, object interface{},
 		return fmt.Errorf("transaction is nil")
 	}
 
-	// Get model tag cache for the object
-	objectType := reflect.TypeOf(object)
-	if objectType.Kind() == reflect.Ptr {
-		objectType = objectType.Elem()
+	query, values, err := buildInsertObjectQuery(object, tableName, "")
+	if err != nil {
+		return err
 	}
 
-	tagCache, err := getModelTagCache(objectType)
+	_, err = tx.ExecContext(ctx, query, values...)
 	if err != nil {
-		return fmt.Errorf("failed to get model tag cache: %w", err)
+		return fmt.Errorf("failed to execute insert: %w", err)
 	}
 
-	// Build query for insertion
-	var columns []string
-	var placeholders []string
-	var values []interface{}
-
-	// Process insert fields
-	for i, field := range tagCache.Fields {
-		// Only include insert fields
-		if strings.Contains(field.Mode, "i") {
-			columns = append(columns, field.DbName)
-			placeholders = append(placeholders, fmt.Sprintf("$%d", len(values)+1))
-
-			// Get field value
-			val := reflect.ValueOf(object)
-			if val.Kind() == reflect.Ptr {
-				val = val.Elem()
-			}
-			fieldVal := val.Field(i).Interface()
-
-			// Apply any value transformation
-			if field.InsertValue != "" {
-				fieldVal = field.InsertValue
-			}
-
-			values = append(values, fieldVal)
-		}
-	}
+	return nil
+}
 
-	if len(columns) == 0 {
-		return fmt.Errorf("no fields marked for insertion")
+// buildInsertObjectQuery builds the INSERT shared by every struct insert (InsertObject and
+// the *Context/*WithTx variants): the object's "i" fields from StructToInsertMap, run through
+// GetInsertQuery so defaults, quoting and casts match the map-based API. tableName must be registered.
+func buildInsertObjectQuery(object interface{}, tableName string, returning string) (string, []interface{}, error) {
+	if _, ok := getModelInfo(tableName); !ok {
+		return "", nil, fmt.Errorf("table name not initialized: %s", tableName)
 	}
 
-	// Build and execute query
-	query := fmt.Sprintf(`INSERT INTO "%s" (%s) VALUES (%s)`,
-		tableName,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "))
-
-	_, err = tx.ExecContext(ctx, query, values...)
+	values, err := StructToInsertMap(object)
 	if err != nil {
-		return fmt.Errorf("failed to execute insert: %w", err)
+		return "", nil, err
+	}
+	if len(values) == 0 {
+		return "", nil, fmt.Errorf("no fields marked for insertion")
 	}
 
-	return nil
+	query, args := GetInsertQuery(tableName, values, returning)
+	return query, args, nil
 }
 
 // UpdateObjectWithTx updates a struct object within a transaction (original fsql signature)
@@ -102,64 +78,49 @@ func UpdateObjectWithTxContext(ctx context.Context, tx *Tx, object interface{},
 		return fmt.Errorf("transaction is nil")
 	}
 
-	// Get model tag cache for the object
-	objectType := reflect.TypeOf(object)
-	if objectType.Kind() == reflect.Ptr {
-		objectType = objectType.Elem()
+	query, values, err := buildUpdateObjectQuery(object, tableName, whereClause, whereArgs...)
+	if err != nil {
+		return err
 	}
 
-	tagCache, err := getModelTagCache(objectType)
+	_, err = tx.ExecContext(ctx, query, values...)
 	if err != nil {
-		return fmt.Errorf("failed to get model tag cache: %w", err)
+		return fmt.Errorf("failed to execute update: %w", err)
 	}
 
-	// Build query for update
-	var setClause []string
-	var values []interface{}
-
-	// Process update fields
-	for i, field := range tagCache.Fields {
-		// Only include update fields
-		if strings.Contains(field.Mode, "u") {
-			setClause = append(setClause, fmt.Sprintf("%s = $%d", field.DbName, len(values)+1))
-
-			// Get field value
-			val := reflect.ValueOf(object)
-			if val.Kind() == reflect.Ptr {
-				val = val.Elem()
-			}
-			fieldVal := val.Field(i).Interface()
-
-			values = append(values, fieldVal)
-		}
+	return nil
+}
+
+// buildUpdateObjectQuery builds the UPDATE shared by every struct update (UpdateObject and
+// the *Context/*WithTx variants) from the object's "u" fields, with the same SET clauses as
+// GetUpdateQuery. Placeholders in whereClause are renumbered to follow the SET values.
+// tableName must be registered.
+func buildUpdateObjectQuery(object interface{}, tableName, whereClause string, whereArgs ...interface{}) (string, []interface{}, error) {
+	if _, ok := getModelInfo(tableName); !ok {
+		return "", nil, fmt.Errorf("table name not initialized: %s", tableName)
 	}
 
-	if len(setClause) == 0 {
-		return fmt.Errorf("no fields marked for update")
+	fields, err := structToMap(object, func(f ModelField, v reflect.Value) bool {
+		return f.HasMode("u")
+	})
+	if err != nil {
+		return "", nil, err
 	}
 
-	// Add where args to values
-	paramOffset := len(values)
-	for i, arg := range whereArgs {
-		// Replace placeholders in where clause
-		whereClause = strings.Replace(whereClause,
-			fmt.Sprintf("$%d", i+1),
-			fmt.Sprintf("$%d", paramOffset+i+1), 1)
-		values = append(values, arg)
+	setClauses, values := updateSetClauses(tableName, fields, "")
+	if len(setClauses) == 0 {
+		return "", nil, fmt.Errorf("no fields marked for update")
 	}
 
-	// Build and execute query
+	whereClause = shiftPlaceholders(whereClause, len(values))
+	values = append(values, whereArgs...)
+
 	query := fmt.Sprintf(`UPDATE "%s" SET %s WHERE %s`,
 		tableName,
-		strings.Join(setClause, ", "),
+		strings.Join(setClauses, ", "),
 		whereClause)
 
-	_, err = tx.ExecContext(ctx, query, values...)
-	if err != nil {
-		return fmt.Errorf("failed to execute update: %w", err)
-	}
-
-	return nil
+	return query, values, nil
 }
 
 // DeleteWithTxCompat deletes records within a transaction (original fsql signature without ctx)
@@ -2,6 +2,7 @@
 package fsql
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -59,3 +60,66 @@ func PlaceholdersString(start, count int) string {
 	}
 	return strings.Join(placeholders, ", ")
 }
+
+// BuildValuesClause builds a multi-row VALUES list like ($1,$2),($3,$4)
+// and returns the flattened args in placeholder order.
+// Placeholders start at $1 unless a start index is given.
+func BuildValuesClause(rows [][]interface{}, startIndex ...int) (string, []interface{}) {
+	if len(rows) == 0 {
+		return "", nil
+	}
+
+	counter := 1
+	if len(startIndex) > 0 && startIndex[0] > 0 {
+		counter = startIndex[0]
+	}
+
+	total := 0
+	for _, row := range rows {
+		total += len(row)
+	}
+
+	var sb strings.Builder
+	sb.Grow(total*4 + len(rows)*3)
+	flatArgs := make([]interface{}, 0, total)
+
+	for i, row := range rows {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteByte('(')
+		for j, val := range row {
+			if j > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteByte('$')
+			sb.WriteString(strconv.Itoa(counter))
+			counter++
+			flatArgs = append(flatArgs, val)
+		}
+		sb.WriteByte(')')
+	}
+
+	return sb.String(), flatArgs
+}
+
+// QuoteIdentifier validates and double-quotes a SQL identifier.
+// Schema-qualified names (schema.table) are quoted part by part and
+// embedded double quotes are escaped.
+func QuoteIdentifier(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("identifier is empty")
+	}
+	if strings.IndexByte(name, 0) >= 0 {
+		return "", fmt.Errorf("identifier contains NUL byte: %q", name)
+	}
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part == "" {
+			return "", fmt.Errorf("invalid identifier: %q", name)
+		}
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, "."), nil
+}
@@ -0,0 +1,64 @@
+package fsql
+
+import (
+	"testing"
+)
+
+// TestBuildValuesClause tests multi-row VALUES list generation
+func TestBuildValuesClause(t *testing.T) {
+	tests := []struct {
+		name         string
+		rows         [][]interface{}
+		start        []int
+		expected     string
+		expectedArgs []interface{}
+	}{
+		{"empty", nil, nil, "", nil},
+		{"single row", [][]interface{}{{"a", 1}}, nil, "($1,$2)", []interface{}{"a", 1}},
+		{"two rows", [][]interface{}{{"a", 1}, {"b", 2}}, nil, "($1,$2),($3,$4)", []interface{}{"a", 1, "b", 2}},
+		{"three columns", [][]interface{}{{1, 2, 3}, {4, 5, 6}}, nil, "($1,$2,$3),($4,$5,$6)", []interface{}{1, 2, 3, 4, 5, 6}},
+		{"custom start", [][]interface{}{{"x"}, {"y"}}, []int{3}, "($3),($4)", []interface{}{"x", "y"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, args := BuildValuesClause(tt.rows, tt.start...)
+			if clause != tt.expected {
+				t.Errorf("Expected clause %q, got %q", tt.expected, clause)
+			}
+			if len(args) != len(tt.expectedArgs) {
+				t.Fatalf("Expected %d args, got %d", len(tt.expectedArgs), len(args))
+			}
+			for i := range args {
+				if args[i] != tt.expectedArgs[i] {
+					t.Errorf("Arg %d: expected %v, got %v", i, tt.expectedArgs[i], args[i])
+				}
+			}
+		})
+	}
+}
+
+// TestQuoteIdentifier tests identifier validation and quoting
+func TestQuoteIdentifier(t *testing.T) {
+	valid := map[string]string{
+		"realm":        `"realm"`,
+		"public.realm": `"public"."realm"`,
+		`we"ird`:       `"we""ird"`,
+	}
+	for in, expected := range valid {
+		got, err := QuoteIdentifier(in)
+		if err != nil {
+			t.Errorf("QuoteIdentifier(%q) returned error: %v", in, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("QuoteIdentifier(%q) = %s, expected %s", in, got, expected)
+		}
+	}
+
+	for _, in := range []string{"", "public.", ".realm", "a\x00b"} {
+		if _, err := QuoteIdentifier(in); err == nil {
+			t.Errorf("Expected error for identifier %q", in)
+		}
+	}
+}